@@ -0,0 +1,298 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adjudicator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire"
+	"perun.network/go-perun/wire/perunio"
+
+	fabwire "github.com/perun-network/perun-fabric/wire"
+)
+
+type (
+	// AddressBookLedger stores the address book registrations. Registrations
+	// are stored by the AccountID of their owner and indexed by their wallet
+	// address.
+	AddressBookLedger interface {
+		GetRegistration(AccountID) (*SignedRegistration, error) //nolint:forbidigo
+		PutRegistration(*SignedRegistration) error
+		GetAddressOwner(wallet.Address) (AccountID, error) //nolint:forbidigo
+		PutAddressOwner(wallet.Address, AccountID) error
+		DeleteAddressOwner(wallet.Address) error
+	}
+
+	// Registration links the AccountID of a Fabric identity to its Perun
+	// wallet address, its wire address and the network endpoint it can be
+	// reached at. The Domain binds the registration to a single adjudicator
+	// deployment. The Sequence of a registration must be higher than that of
+	// the previous registration of its AccountID, so that old registrations
+	// cannot be replayed.
+	Registration struct {
+		Domain      Domain         `json:"domain"`
+		AccountID   AccountID      `json:"accountId"`
+		Sequence    uint64         `json:"sequence"`
+		Address     wallet.Address `json:"address"`
+		WireAddress wire.Address   `json:"wireAddress"`
+		Endpoint    string         `json:"endpoint"`
+	}
+
+	// SignedRegistration contains signatures over a Registration by the keys
+	// of its wallet address and of its wire address. The wire signature proves
+	// possession of the wire key, which is the key of the certificate peers
+	// authenticate the wire address with.
+	SignedRegistration struct {
+		Reg     Registration `json:"reg"`
+		Sig     wallet.Sig   `json:"sig"`
+		WireSig []byte       `json:"wireSig"`
+	}
+
+	// WireAccount is a wire account that can sign data, like the Account of
+	// the wire package.
+	WireAccount interface {
+		Address() wire.Address
+		SignData(data []byte) ([]byte, error)
+	}
+)
+
+// AddressBook registers the Perun addresses of Fabric identities, so that
+// counterparties can be resolved by AccountID or wallet address.
+type AddressBook struct {
+	domain Domain
+	ledger AddressBookLedger
+}
+
+// NewAddressBook returns a new AddressBook of the given domain operating on
+// the given ledger.
+func NewAddressBook(domain Domain, ledger AddressBookLedger) *AddressBook {
+	return &AddressBook{domain: domain, ledger: ledger}
+}
+
+// Register registers the signed registration for the callee. The callee must
+// be the AccountID of the registration and the registration must be signed by
+// the keys of its wallet and wire address. An earlier registration of the
+// callee is replaced, if the new registration has a higher sequence number. A
+// wallet address can only be registered by one AccountID at a time.
+func (b *AddressBook) Register(callee AccountID, sr *SignedRegistration) error {
+	if err := b.validate(callee, sr); err != nil {
+		return ValidationError{err}
+	}
+
+	addr := sr.Reg.Address
+	if owner, err := b.ledger.GetAddressOwner(addr); err == nil && owner != callee {
+		return ValidationError{fmt.Errorf("address %v already registered by another account", addr)}
+	} else if err != nil && !IsNotFoundError(err) {
+		return fmt.Errorf("querying address owner: %w", err)
+	}
+
+	prev, err := b.ledger.GetRegistration(callee)
+	if err != nil && !IsNotFoundError(err) {
+		return fmt.Errorf("querying registration: %w", err)
+	} else if err == nil {
+		if sr.Reg.Sequence <= prev.Reg.Sequence {
+			return ValidationError{fmt.Errorf("registration sequence %d not higher than registered sequence %d",
+				sr.Reg.Sequence, prev.Reg.Sequence)}
+		}
+		if !prev.Reg.Address.Equal(addr) {
+			if err := b.ledger.DeleteAddressOwner(prev.Reg.Address); err != nil {
+				return fmt.Errorf("deleting previous address owner: %w", err)
+			}
+		}
+	}
+
+	if err := b.ledger.PutRegistration(sr); err != nil {
+		return fmt.Errorf("putting registration: %w", err)
+	}
+	if err := b.ledger.PutAddressOwner(addr, callee); err != nil {
+		return fmt.Errorf("putting address owner: %w", err)
+	}
+	return nil
+}
+
+func (b *AddressBook) validate(callee AccountID, sr *SignedRegistration) error {
+	reg := sr.Reg
+	if reg.AccountID != callee {
+		return fmt.Errorf("registration of %q sent by %q", reg.AccountID, callee)
+	} else if reg.Domain != b.domain {
+		return fmt.Errorf("registration for domain %v sent to domain %v", reg.Domain, b.domain)
+	} else if reg.Address == nil || reg.WireAddress == nil {
+		return fmt.Errorf("missing address")
+	}
+	if ok, err := sr.Verify(); err != nil {
+		return fmt.Errorf("verifying registration signature: %w", err)
+	} else if !ok {
+		return fmt.Errorf("invalid registration signature")
+	}
+	return nil
+}
+
+// ByAccountID returns the registration of the given AccountID.
+func (b *AddressBook) ByAccountID(id AccountID) (*SignedRegistration, error) {
+	return b.ledger.GetRegistration(id)
+}
+
+// ByAddress returns the registration of the given wallet address.
+func (b *AddressBook) ByAddress(addr wallet.Address) (*SignedRegistration, error) {
+	owner, err := b.ledger.GetAddressOwner(addr)
+	if err != nil {
+		return nil, err
+	}
+	return b.ledger.GetRegistration(owner)
+}
+
+// SignRegistration creates a Registration in the given domain of the wallet
+// address of acc and the wire address of wireAcc for the given AccountID and
+// signs it with both accounts. The sequence must be higher than that of the
+// previous registration of the AccountID.
+func SignRegistration(acc wallet.Account, wireAcc WireAccount, domain Domain, id AccountID, seq uint64,
+	endpoint string) (*SignedRegistration, error) {
+	reg := Registration{
+		Domain:      domain,
+		AccountID:   id,
+		Sequence:    seq,
+		Address:     acc.Address(),
+		WireAddress: wireAcc.Address(),
+		Endpoint:    endpoint,
+	}
+	sig, err := reg.Sign(acc)
+	if err != nil {
+		return nil, err
+	}
+	wireSig, err := reg.SignWire(wireAcc)
+	if err != nil {
+		return nil, fmt.Errorf("signing with wire account: %w", err)
+	}
+	return &SignedRegistration{Reg: reg, Sig: sig, WireSig: wireSig}, nil
+}
+
+// Sign signs the registration with the given Account.
+func (r Registration) Sign(acc wallet.Account) (wallet.Sig, error) {
+	return r.sign(acc.SignData)
+}
+
+// SignWire signs the registration with the given wire account.
+func (r Registration) SignWire(acc WireAccount) ([]byte, error) {
+	return r.sign(acc.SignData)
+}
+
+func (r Registration) sign(signData func([]byte) ([]byte, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := r.Encode(&buf); err != nil {
+		return nil, fmt.Errorf("encoding Registration: %w", err)
+	}
+	return signData(buf.Bytes())
+}
+
+// Verify verifies that the signatures of the registration belong to its
+// wallet and wire address.
+func (sr SignedRegistration) Verify() (bool, error) {
+	var buf bytes.Buffer
+	if err := sr.Reg.Encode(&buf); err != nil {
+		return false, fmt.Errorf("encoding Registration: %w", err)
+	}
+	if ok, err := wallet.VerifySignature(buf.Bytes(), sr.Sig, sr.Reg.Address); err != nil || !ok {
+		return ok, err
+	}
+	return fabwire.VerifySignature(buf.Bytes(), sr.WireSig, sr.Reg.WireAddress)
+}
+
+// Clone returns a deep copy of the signed registration.
+func (sr *SignedRegistration) Clone() *SignedRegistration {
+	clone := *sr
+	clone.Sig = append(wallet.Sig(nil), sr.Sig...)
+	clone.WireSig = append([]byte(nil), sr.WireSig...)
+	return &clone
+}
+
+// Encode encodes a registration into an `io.Writer` or returns an `error`.
+func (r Registration) Encode(w io.Writer) error {
+	return errors.WithMessage(
+		perunio.Encode(w, r.Domain, string(r.AccountID), r.Sequence, r.Address, r.WireAddress, r.Endpoint),
+		"Registration encode")
+}
+
+// Decode decodes a registration from an `io.Reader` or returns an `error`.
+func (r *Registration) Decode(rd io.Reader) error {
+	var id string
+	r.Address = wallet.NewAddress()
+	r.WireAddress = wire.NewAddress()
+	if err := perunio.Decode(rd, &r.Domain, &id, &r.Sequence, r.Address, r.WireAddress, &r.Endpoint); err != nil {
+		return errors.WithMessage(err, "Registration decode")
+	}
+	r.AccountID = AccountID(id)
+	return nil
+}
+
+// MarshalJSON implements custom marshalling for Registration to deal with
+// custom data types. The wire address is marshalled in its binary encoding.
+func (r Registration) MarshalJSON() ([]byte, error) {
+	wireAddr, err := r.WireAddress.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("marshaling wire address: %w", err)
+	}
+	return json.Marshal(registrationJSON{
+		Domain:      r.Domain,
+		AccountID:   string(r.AccountID),
+		Sequence:    r.Sequence,
+		Address:     r.Address,
+		WireAddress: wireAddr,
+		Endpoint:    r.Endpoint,
+	})
+}
+
+// UnmarshalJSON implements custom unmarshalling for Registration to deal with
+// custom data types.
+func (r *Registration) UnmarshalJSON(data []byte) error {
+	var rj struct {
+		registrationJSON
+		Address json.RawMessage `json:"address"`
+	}
+	if err := json.Unmarshal(data, &rj); err != nil {
+		return err
+	}
+
+	addr := wallet.NewAddress()
+	addri := addr.(interface{}) //nolint:forcetypeassert
+	if err := json.Unmarshal(rj.Address, &addri); err != nil {
+		return fmt.Errorf("unmarshaling address: %w", err)
+	}
+	wireAddr := wire.NewAddress()
+	if err := wireAddr.UnmarshalBinary(rj.WireAddress); err != nil {
+		return fmt.Errorf("unmarshaling wire address: %w", err)
+	}
+
+	r.Domain = rj.Domain
+	r.AccountID = AccountID(rj.AccountID)
+	r.Sequence = rj.Sequence
+	r.Address = addr
+	r.WireAddress = wireAddr
+	r.Endpoint = rj.Endpoint
+	return nil
+}
+
+type registrationJSON struct {
+	Domain      Domain         `json:"domain"`
+	AccountID   string         `json:"accountId"`
+	Sequence    uint64         `json:"sequence"`
+	Address     wallet.Address `json:"address"`
+	WireAddress []byte         `json:"wireAddress"`
+	Endpoint    string         `json:"endpoint"`
+}
@@ -0,0 +1,138 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adjudicator_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	wtest "perun.network/go-perun/wallet/test"
+	"polycry.pt/poly-go/test"
+
+	_ "github.com/perun-network/perun-fabric" // init backend
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	fabwire "github.com/perun-network/perun-fabric/wire"
+)
+
+func TestAddressBook(t *testing.T) {
+	rng := test.Prng(t)
+	domain := adj.NewDomain("mychannel", "adjudicator")
+	const alice, bob adj.AccountID = "alice", "bob"
+
+	var seq uint64
+	signReg := func(t *testing.T, id adj.AccountID) *adj.SignedRegistration {
+		t.Helper()
+		acc := wtest.NewRandomAccount(rng)
+		seq++
+		sr, err := adj.SignRegistration(acc, fabwire.NewRandomAccount(rng), domain, id, seq, "localhost:5750")
+		require.NoError(t, err)
+		return sr
+	}
+
+	t.Run("RegisterLookup", func(t *testing.T) {
+		require := require.New(t)
+		book := adj.NewAddressBook(domain, adj.NewMemLedger())
+		sr := signReg(t, alice)
+
+		_, err := book.ByAccountID(alice)
+		require.True(adj.IsNotFoundError(err))
+		require.NoError(book.Register(alice, sr))
+
+		byID, err := book.ByAccountID(alice)
+		require.NoError(err)
+		requireRegEqual(t, sr, byID)
+		byAddr, err := book.ByAddress(sr.Reg.Address)
+		require.NoError(err)
+		requireRegEqual(t, sr, byAddr)
+
+		// Re-registering a new address frees the old one.
+		sr1 := signReg(t, alice)
+		require.NoError(book.Register(alice, sr1))
+		_, err = book.ByAddress(sr.Reg.Address)
+		require.True(adj.IsNotFoundError(err))
+		byAddr, err = book.ByAddress(sr1.Reg.Address)
+		require.NoError(err)
+		requireRegEqual(t, sr1, byAddr)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		book := adj.NewAddressBook(domain, adj.NewMemLedger())
+		sr := signReg(t, alice)
+		var err error
+		requireValidationError := func(err error) {
+			t.Helper()
+			require.Error(t, err)
+			require.True(t, adj.IsAdjudicatorError(err), "ValidationError expected, got %v", err)
+		}
+
+		requireValidationError(book.Register(bob, sr))
+		requireValidationError(adj.NewAddressBook(adj.NewDomain("mychannel", "other"), adj.NewMemLedger()).Register(alice, sr))
+
+		forged := *sr
+		forged.Reg.Endpoint = "evil:5750"
+		requireValidationError(book.Register(alice, &forged))
+
+		// The wire key must sign the registration.
+		wireAcc := fabwire.NewRandomAccount(rng)
+		other := wtest.NewRandomAccount(rng)
+		forged = *sr
+		forged.Reg.Address = other.Address()
+		forged.Reg.WireAddress = wireAcc.Address()
+		forged.Sig, err = forged.Reg.Sign(other)
+		require.NoError(t, err)
+		requireValidationError(book.Register(alice, &forged))
+		forged.WireSig = nil
+		requireValidationError(book.Register(alice, &forged))
+
+		// The address of alice cannot be taken over by bob.
+		require.NoError(t, book.Register(alice, sr))
+		acc := wtest.NewRandomAccount(rng)
+		srA, err := adj.SignRegistration(acc, wireAcc, domain, alice, sr.Reg.Sequence+1, "")
+		require.NoError(t, err)
+		srB, err := adj.SignRegistration(acc, wireAcc, domain, bob, 0, "")
+		require.NoError(t, err)
+		require.NoError(t, book.Register(alice, srA))
+		requireValidationError(book.Register(bob, srB))
+
+		// Old registrations cannot be replayed.
+		requireValidationError(book.Register(alice, sr))
+		requireValidationError(book.Register(alice, srA))
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		sr := signReg(t, alice)
+		data, err := json.Marshal(sr)
+		require.NoError(t, err)
+		var sr1 adj.SignedRegistration
+		require.NoError(t, json.Unmarshal(data, &sr1))
+		requireRegEqual(t, sr, &sr1)
+		ok, err := sr1.Verify()
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+}
+
+func requireRegEqual(t *testing.T, exp, act *adj.SignedRegistration) {
+	t.Helper()
+	require.Equal(t, exp.Reg.Domain, act.Reg.Domain)
+	require.Equal(t, exp.Reg.AccountID, act.Reg.AccountID)
+	require.Equal(t, exp.Reg.Sequence, act.Reg.Sequence)
+	require.True(t, exp.Reg.Address.Equal(act.Reg.Address))
+	require.True(t, exp.Reg.WireAddress.Equal(act.Reg.WireAddress))
+	require.Equal(t, exp.Reg.Endpoint, act.Reg.Endpoint)
+	require.Equal(t, exp.Sig, act.Sig)
+	require.Equal(t, exp.WireSig, act.WireSig)
+}
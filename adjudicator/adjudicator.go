@@ -24,15 +24,18 @@ import (
 
 // Adjudicator is an abstract implementation of the adjudicator smart contract.
 type Adjudicator struct {
+	domain     Domain       // domain separates the channels and requests of this Adjudicator from others.
 	ledger     Ledger       // ledger stores channel states.
 	holdings   *AssetHolder // holdings manages per channel holdings.
 	asset      Asset        // asset is the Asset the channels use. It can also be used independently of the channels.
 	identifier AccountID    // identifier is the chaincode id for sending and receiving funds on.
 }
 
-// NewAdjudicator generates a new Adjudicator with an identifier, holding ledger and asset ledger.
-func NewAdjudicator(id string, ledger Ledger, asset Asset) *Adjudicator {
+// NewAdjudicator generates a new Adjudicator with an identifier, domain, holding ledger and asset ledger.
+// Only channels and withdraw requests of the given domain are accepted.
+func NewAdjudicator(id string, domain Domain, ledger Ledger, asset Asset) *Adjudicator {
 	return &Adjudicator{
+		domain:     domain,
 		ledger:     ledger,
 		holdings:   NewAssetHolder(ledger),
 		asset:      asset,
@@ -41,42 +44,60 @@ func NewAdjudicator(id string, ledger Ledger, asset Asset) *Adjudicator {
 }
 
 // Register verifies the given SignedChannel, updates the holdings and saves a new StateReg.
+// The payouts of the given params are bound to the channel, see bindPayouts.
 func (a *Adjudicator) Register(ch *SignedChannel) error {
-	if err := ValidateChannel(ch); err != nil {
+	holdings, err := a.checkRegistration(ch)
+	if err != nil {
 		return err
 	}
+	payouts, err := a.bindPayouts(ch)
+	if err != nil {
+		return err
+	}
+	// Update holdings to current state so that they can be withdrawn once the
+	// channel is finalized.
+	if holdings != nil {
+		if err := a.updateHoldings(ch.State.ID, ch.Params.Parts, holdings); err != nil {
+			return err
+		}
+	}
+	return a.saveStateReg(ch, payouts)
+}
+
+// checkRegistration verifies that the given SignedChannel can be registered.
+// It returns the holdings of the participants after the registration, which
+// are nil if the holdings stay unchanged because an underfunded state of
+// version 0 is registered for funds recovery.
+func (a *Adjudicator) checkRegistration(ch *SignedChannel) ([]*big.Int, error) {
+	if err := ValidateChannel(a.domain, ch); err != nil {
+		return nil, err
+	}
 	id := ch.State.ID
 
 	// Check existing state registration for non-final channels
 	if !ch.State.IsFinal {
 		if err := a.checkExistingStateReg(ch); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// check channel funding
 	total, err := a.holdings.TotalHolding(id, ch.Params.Parts)
 	if err != nil {
-		return fmt.Errorf("querying total holding: %w", err)
+		return nil, fmt.Errorf("querying total holding: %w", err)
 	}
 	if chTotal := ch.State.Total(); total.Cmp(chTotal) == -1 {
 		// allow version 0 underfunded channels for funds recovery
 		if ch.State.Version != 0 {
-			return &UnderfundedError{
+			return nil, &UnderfundedError{
 				Version: ch.State.Version,
 				Total:   chTotal,
 				Funded:  total,
 			}
 		}
-	} else {
-		// Update holdings to current state in all other cases so that they can be
-		// withdrawn once the channel is finalized.
-		if err := a.updateHoldings(ch); err != nil {
-			return err
-		}
+		return nil, nil
 	}
-
-	return a.saveStateReg(ch)
+	return ch.State.Balances, nil
 }
 
 func (a *Adjudicator) checkExistingStateReg(ch *SignedChannel) error {
@@ -105,16 +126,38 @@ func (a *Adjudicator) checkExistingStateReg(ch *SignedChannel) error {
 	return nil
 }
 
-func (a *Adjudicator) updateHoldings(ch *SignedChannel) error {
-	for i, part := range ch.Params.Parts {
-		if err := a.holdings.SetHolding(ch.State.ID, part, ch.State.Balances[i]); err != nil {
+func (a *Adjudicator) updateHoldings(id channel.ID, parts []wallet.Address, holdings []*big.Int) error {
+	for i, part := range parts {
+		if err := a.holdings.SetHolding(id, part, holdings[i]); err != nil {
 			return fmt.Errorf("updating holding[%d]: %w", i, err)
 		}
 	}
 	return nil
 }
 
-func (a *Adjudicator) saveStateReg(ch *SignedChannel) error {
+// bindPayouts returns the payouts that are bound to the channel after the
+// registration of ch. The payouts that are already bound to the channel are
+// kept, so that a payout cannot be changed once it is bound. The payouts of
+// the given params bind the remaining participants.
+func (a *Adjudicator) bindPayouts(ch *SignedChannel) ([]SignedWithdrawReq, error) {
+	reg, err := a.stateReg(ch.State.ID)
+	if err != nil {
+		return nil, err
+	}
+	var payouts []SignedWithdrawReq
+	if reg != nil {
+		payouts = reg.Payouts
+	}
+	for i, po := range ch.Params.Payouts {
+		if po.Receiver == "" || (reg != nil && reg.payout(ch.Params.Parts[i]) != nil) {
+			continue
+		}
+		payouts = append(payouts, ch.Params.payoutReq(a.domain, ch.State.ID, i))
+	}
+	return payouts, nil
+}
+
+func (a *Adjudicator) saveStateReg(ch *SignedChannel, payouts []SignedWithdrawReq) error {
 	// determine timeout by channel finality
 	to := a.ledger.Now()
 	if !ch.State.IsFinal {
@@ -125,6 +168,7 @@ func (a *Adjudicator) saveStateReg(ch *SignedChannel) error {
 	return a.ledger.PutState(&StateReg{
 		State:   ch.State,
 		Timeout: to,
+		Payouts: payouts,
 	})
 }
 
@@ -140,13 +184,79 @@ func (a *Adjudicator) StateReg(id channel.ID) (*StateReg, error) {
 	return reg, nil
 }
 
+// StateRegs fetches the current states of the given channels from the ledger.
+// The registrations are returned in the order of the given ids. The entry of
+// a channel without a registered state is nil.
+func (a *Adjudicator) StateRegs(ids []channel.ID) ([]*StateReg, error) {
+	regs := make([]*StateReg, 0, len(ids))
+	for i, id := range ids {
+		reg, err := a.ledger.GetState(id)
+		if IsNotFoundError(err) {
+			reg = nil
+		} else if err != nil {
+			return nil, fmt.Errorf("querying ledger for channel[%d]: %w", i, err)
+		}
+		regs = append(regs, reg)
+	}
+	return regs, nil
+}
+
 // Withdraw withdraws all funds of participant Part in the finalized channel id
-// to the given Receiver. It returns the withdrawn amount.
+// to the given Receiver, or to the receiver of the participant's payout if one
+// is bound to the channel. It returns the withdrawn amount.
+// The request must be of the Adjudicator's domain and must not be expired.
 func (a *Adjudicator) Withdraw(swr SignedWithdrawReq) (*big.Int, error) {
-	if reg, err := a.StateReg(swr.Req.ID); err != nil {
+	reg, err := a.stateReg(swr.Req.ID)
+	if err != nil {
 		return nil, err
+	}
+	if err := a.checkWithdrawReq(swr, reg); err != nil {
+		return nil, err
+	}
+
+	// Withdraw from channel.
+	holding, err := a.holdings.Withdraw(swr.Req.ID, swr.Req.Part)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send funds back.
+	err = a.asset.Transfer(a.identifier, reg.Receiver(swr.Req), holding)
+	if err != nil {
+		return nil, err
+	}
+	return holding, nil
+}
+
+// stateReg returns the StateReg of channel id or nil if no state is
+// registered.
+func (a *Adjudicator) stateReg(id channel.ID) (*StateReg, error) {
+	reg, err := a.ledger.GetState(id)
+	if IsNotFoundError(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("querying ledger: %w", err)
+	}
+	return reg, nil
+}
+
+// checkWithdrawReq checks that the given request is of the Adjudicator's
+// domain, not expired, validly signed and that its channel, whose StateReg is
+// reg, is finalized. A nil reg indicates an unknown channel.
+func (a *Adjudicator) checkWithdrawReq(swr SignedWithdrawReq, reg *StateReg) error {
+	if swr.Req.Domain != a.domain {
+		return ValidationError{errors.New("withdraw request domain mismatch")}
+	} else if now := a.ledger.Now(); swr.Req.IsExpiredAt(now) {
+		return ExpiredError{
+			Expiry: *swr.Req.Expiry,
+			Now:    now,
+		}
+	}
+
+	if reg == nil {
+		return ErrUnknownChannel
 	} else if now := a.ledger.Now(); !reg.IsFinalizedAt(now) {
-		return nil, ChallengeTimeoutError{
+		return ChallengeTimeoutError{
 			Timeout: reg.Timeout,
 			Now:     now,
 		}
@@ -155,29 +265,275 @@ func (a *Adjudicator) Withdraw(swr SignedWithdrawReq) (*big.Int, error) {
 	// Verify signature.
 	sigValid, err := swr.Verify(swr.Req.Part)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if !sigValid {
-		return nil, fmt.Errorf("withdraw request signature invalid")
+		return ValidationError{errors.New("withdraw request signature invalid")}
 	}
+	return nil
+}
 
-	// Withdraw from channel.
-	holding, err := a.holdings.Withdraw(swr.Req.ID, swr.Req.Part)
-	if err != nil {
+// transferAll sends the given amounts to the respective receivers in a single
+// batch transfer. Amounts to the same receiver are added up, so that every
+// token balance is written once.
+func (a *Adjudicator) transferAll(receivers []AccountID, amounts []*big.Int) error {
+	payments := make([]Payment, 0, len(receivers))
+	index := make(map[AccountID]int, len(receivers))
+	for i, receiver := range receivers {
+		if amounts[i] == nil {
+			continue
+		}
+		if j, ok := index[receiver]; ok {
+			payments[j].Amount.Add(payments[j].Amount, amounts[i])
+			continue
+		}
+		index[receiver] = len(payments)
+		payments = append(payments, Payment{Receiver: receiver, Amount: new(big.Int).Set(amounts[i])})
+	}
+	return a.asset.TransferBatch(a.identifier, payments)
+}
+
+// WithdrawBatch withdraws the funds for each of the given signed withdraw
+// requests, which may belong to different channels. Requests that fail because
+// of an adjudicator error or an unknown channel, including repeated requests
+// for the same participant of a channel, are reported in the respective
+// WithdrawResult without aborting the others. Any other error, e.g., a ledger
+// access error, aborts the whole batch and is returned.
+// The results are in the order of the given requests. Like with Withdraw, the
+// funds of participants with a bound payout are paid out to its receiver.
+//
+// As a transaction cannot read its own writes on Fabric, the withdrawn amounts
+// are added up per receiver and every holding and token balance is written
+// once.
+func (a *Adjudicator) WithdrawBatch(reqs []SignedWithdrawReq) ([]WithdrawResult, error) {
+	results := make([]WithdrawResult, len(reqs))
+	amounts := make([]*big.Int, len(reqs))
+	receivers := make([]AccountID, len(reqs))
+	regs := make(map[channel.ID]*StateReg)
+	withdrawn := make(map[string]struct{})
+	for i, swr := range reqs {
+		amount, err := a.batchWithdrawal(swr, regs, withdrawn)
+		if err == nil {
+			results[i].Amount, amounts[i] = amount, amount
+			receivers[i] = regs[swr.Req.ID].Receiver(swr.Req)
+		} else if IsAdjudicatorError(err) || errors.Is(err, ErrUnknownChannel) {
+			results[i].Error = err.Error()
+		} else {
+			return nil, fmt.Errorf("withdrawing request[%d]: %w", i, err)
+		}
+	}
+
+	for i, swr := range reqs {
+		if amounts[i] == nil {
+			continue
+		}
+		if err := a.holdings.SetHolding(swr.Req.ID, swr.Req.Part, new(big.Int)); err != nil {
+			return nil, fmt.Errorf("zeroing holding of request[%d]: %w", i, err)
+		}
+	}
+	if err := a.transferAll(receivers, amounts); err != nil {
 		return nil, err
 	}
+	return results, nil
+}
 
-	// Send funds back.
-	err = a.asset.Transfer(a.identifier, swr.Req.Receiver, holding)
+// batchWithdrawal checks the given request of a batch withdrawal and returns
+// the holding it withdraws. The StateRegs of the channels of the batch are
+// cached in regs and the funding keys of the successful requests of the batch
+// are collected in withdrawn to reject repeated requests.
+func (a *Adjudicator) batchWithdrawal(swr SignedWithdrawReq,
+	regs map[channel.ID]*StateReg, withdrawn map[string]struct{}) (*big.Int, error) {
+	id := swr.Req.ID
+	reg, ok := regs[id]
+	if !ok {
+		var err error
+		if reg, err = a.stateReg(id); err != nil {
+			return nil, err
+		}
+		regs[id] = reg
+	}
+	if err := a.checkWithdrawReq(swr, reg); err != nil {
+		return nil, err
+	}
+
+	key := FundingKey(id, swr.Req.Part)
+	if _, ok := withdrawn[key]; ok {
+		return nil, ValidationError{errors.New("duplicate withdraw request")}
+	}
+	holding, err := a.holdings.Holding(id, swr.Req.Part)
 	if err != nil {
 		return nil, err
 	}
+	withdrawn[key] = struct{}{}
 	return holding, nil
 }
 
-// ValidateChannel checks if the given parameters in SignedChannel are in itself consistent.
-func ValidateChannel(ch *SignedChannel) error {
-	if ch.Params.ID() != ch.State.ID {
+// ConcludeFinal registers the given final channel state and withdraws the
+// funds of every participant for which a signed withdraw request is given, all
+// in one call. If the channel is already finalized on the ledger, e.g., because
+// another participant concluded it before, the registration is skipped.
+// It returns the withdrawn amounts in the order of the given requests.
+//
+// As a transaction cannot read its own writes on Fabric, the holdings after
+// the registration are computed in memory and every holding and token balance
+// is written once.
+func (a *Adjudicator) ConcludeFinal(ch *SignedChannel, reqs []SignedWithdrawReq) ([]*big.Int, error) {
+	if !ch.State.IsFinal {
+		return nil, ValidationError{errors.New("state not final")}
+	}
+	if len(reqs) == 0 {
+		return nil, ValidationError{errors.New("no withdraw requests")}
+	}
+	// The participants of the given channel determine the holdings that are
+	// paid out, so it is validated even if the channel is already finalized.
+	if err := ValidateChannel(a.domain, ch); err != nil {
+		return nil, err
+	}
+	id, parts := ch.State.ID, ch.Params.Parts
+	for i, swr := range reqs {
+		if swr.Req.ID != id {
+			return nil, ValidationError{fmt.Errorf("withdraw request[%d]: channel id mismatch", i)}
+		}
+	}
+
+	reg, err := a.stateReg(id)
+	if err != nil {
+		return nil, err
+	}
+	var holdings []*big.Int
+	register := reg == nil || !reg.IsFinalizedAt(a.ledger.Now())
+	if register {
+		if holdings, err = a.checkRegistration(ch); err != nil {
+			return nil, err
+		}
+		payouts, err := a.bindPayouts(ch)
+		if err != nil {
+			return nil, err
+		}
+		reg = &StateReg{State: ch.State, Timeout: a.ledger.Now(), Payouts: payouts}
+	} else if reg.ID != id || reg.Version != ch.State.Version {
+		return nil, ValidationError{fmt.Errorf("state version %d does not match finalized version %d",
+			ch.State.Version, reg.Version)}
+	}
+	if holdings == nil {
+		if holdings, err = a.currentHoldings(id, parts); err != nil {
+			return nil, err
+		}
+	} else {
+		holdings = cloneAmounts(holdings)
+	}
+
+	amounts := make([]*big.Int, len(reqs))
+	receivers := make([]AccountID, len(reqs))
+	for i, swr := range reqs {
+		if err := a.checkWithdrawReq(swr, reg); err != nil {
+			return nil, fmt.Errorf("withdrawing request[%d]: %w", i, err)
+		}
+		idx := partIndex(parts, swr.Req.Part)
+		if idx < 0 {
+			return nil, ValidationError{fmt.Errorf("withdraw request[%d]: not a participant", i)}
+		} else if holdings[idx] == nil {
+			return nil, ValidationError{fmt.Errorf("withdraw request[%d]: duplicate participant", i)}
+		}
+		amounts[i], holdings[idx] = holdings[idx], nil
+		receivers[i] = reg.Receiver(swr.Req)
+	}
+
+	if register {
+		if err := a.saveStateReg(ch, reg.Payouts); err != nil {
+			return nil, err
+		}
+	}
+	for i, part := range parts {
+		holding := holdings[i]
+		if holding == nil {
+			holding = new(big.Int)
+		}
+		if err := a.holdings.SetHolding(id, part, holding); err != nil {
+			return nil, fmt.Errorf("updating holding[%d]: %w", i, err)
+		}
+	}
+	if err := a.transferAll(receivers, amounts); err != nil {
+		return nil, err
+	}
+	return amounts, nil
+}
+
+// Payout withdraws the funds of every participant of the finalized channel id
+// whose payout is bound to the channel to the receiver of the payout. As the
+// payouts are signed by the participants, Payout can be called by anyone,
+// e.g., a watchtower or the counterparty.
+// It returns the withdrawn amounts in the order of the bound payouts, see
+// StateReg.Payouts.
+func (a *Adjudicator) Payout(id channel.ID) ([]*big.Int, error) {
+	reg, err := a.stateReg(id)
+	if err != nil {
+		return nil, err
+	} else if reg == nil {
+		return nil, ErrUnknownChannel
+	} else if len(reg.Payouts) == 0 {
+		return nil, ValidationError{errors.New("no payouts bound")}
+	}
+
+	amounts := make([]*big.Int, len(reg.Payouts))
+	receivers := make([]AccountID, len(reg.Payouts))
+	for i, swr := range reg.Payouts {
+		if err := a.checkWithdrawReq(swr, reg); err != nil {
+			return nil, fmt.Errorf("paying out payout[%d]: %w", i, err)
+		}
+		holding, err := a.holdings.Holding(id, swr.Req.Part)
+		if err != nil {
+			return nil, err
+		}
+		amounts[i], receivers[i] = holding, swr.Req.Receiver
+	}
+
+	for i, swr := range reg.Payouts {
+		if err := a.holdings.SetHolding(id, swr.Req.Part, new(big.Int)); err != nil {
+			return nil, fmt.Errorf("zeroing holding of payout[%d]: %w", i, err)
+		}
+	}
+	if err := a.transferAll(receivers, amounts); err != nil {
+		return nil, err
+	}
+	return amounts, nil
+}
+
+// currentHoldings returns the holdings of the given participants in channel id.
+func (a *Adjudicator) currentHoldings(id channel.ID, parts []wallet.Address) ([]*big.Int, error) {
+	holdings := make([]*big.Int, len(parts))
+	for i, part := range parts {
+		holding, err := a.holdings.Holding(id, part)
+		if err != nil {
+			return nil, err
+		}
+		holdings[i] = holding
+	}
+	return holdings, nil
+}
+
+// partIndex returns the index of part in parts or -1 if it is not contained.
+func partIndex(parts []wallet.Address, part wallet.Address) int {
+	for i, p := range parts {
+		if p.Equal(part) {
+			return i
+		}
+	}
+	return -1
+}
+
+func cloneAmounts(amounts []*big.Int) []*big.Int {
+	clone := make([]*big.Int, len(amounts))
+	for i, amount := range amounts {
+		clone[i] = new(big.Int).Set(amount)
+	}
+	return clone
+}
+
+// ValidateChannel checks if the given parameters in SignedChannel are in itself consistent
+// and that the channel id is calculated in the given domain.
+func ValidateChannel(domain Domain, ch *SignedChannel) error {
+	if domain.CalcID(ch.Params.CoreParams()) != ch.State.ID {
 		return ValidationError{errors.New("channel id mismatch")}
 	}
 
@@ -197,6 +553,30 @@ func ValidateChannel(ch *SignedChannel) error {
 		}
 	}
 
+	return validatePayouts(domain, ch)
+}
+
+// validatePayouts checks that the params of the given channel contain either
+// no payouts or one payout per participant and that every payout with a
+// receiver is signed by its participant.
+func validatePayouts(domain Domain, ch *SignedChannel) error {
+	if len(ch.Params.Payouts) == 0 {
+		return nil
+	} else if len(ch.Params.Payouts) != len(ch.Params.Parts) {
+		return ValidationError{errors.New("payouts dimension mismatch")}
+	}
+
+	for i, po := range ch.Params.Payouts {
+		if po.Receiver == "" {
+			continue
+		}
+		swr := ch.Params.payoutReq(domain, ch.State.ID, i)
+		if ok, err := swr.Verify(swr.Req.Part); err != nil {
+			return ValidationError{fmt.Errorf("validating payout[%d]: %w", i, err)}
+		} else if !ok {
+			return ValidationError{fmt.Errorf("payout[%d] invalid", i)}
+		}
+	}
 	return nil
 }
 
@@ -233,6 +613,11 @@ func (a *Adjudicator) Burn(callee AccountID, amount *big.Int) error {
 	return a.asset.Burn(callee, amount)
 }
 
+// Domain returns the domain of the Adjudicator.
+func (a *Adjudicator) Domain() Domain {
+	return a.domain
+}
+
 // Transfer sends the given amount of asset tokens from the sender to the receiver.
 func (a *Adjudicator) Transfer(sender AccountID, receiver AccountID, amount *big.Int) error {
 	return a.asset.Transfer(sender, receiver, amount)
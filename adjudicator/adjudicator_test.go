@@ -19,15 +19,17 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
 	"polycry.pt/poly-go/test"
 
 	adj "github.com/perun-network/perun-fabric/adjudicator"
 	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	chtest "github.com/perun-network/perun-fabric/channel/test"
 )
 
 func TestValidateChannel(t *testing.T) {
 	s := adjtest.NewSetup(test.Prng(t))
-	require.NoError(t, adj.ValidateChannel(s.SignedChannel()))
+	require.NoError(t, adj.ValidateChannel(s.Domain, s.SignedChannel()))
 }
 
 func TestAdjudicator(t *testing.T) { //nolint:maintidx
@@ -114,6 +116,24 @@ func TestAdjudicator(t *testing.T) { //nolint:maintidx
 		require.True(sr.Equal(*adjsr))
 	})
 
+	t.Run("StateRegs", func(t *testing.T) {
+		require := require.New(t)
+		s := adjtest.NewSetup(test.Prng(t))
+		unknown := channel.ID{0x42}
+
+		regs, err := s.Adj.StateRegs([]channel.ID{s.State.ID, unknown})
+		require.NoError(err)
+		require.Equal([]*adj.StateReg{nil, nil}, regs)
+
+		sr := s.StateReg()
+		require.NoError(s.Adj.Register(s.SignedChannel()))
+		regs, err = s.Adj.StateRegs([]channel.ID{unknown, s.State.ID})
+		require.NoError(err)
+		require.Len(regs, 2)
+		require.Nil(regs[0])
+		require.True(sr.Equal(*regs[1]))
+	})
+
 	t.Run("Register-idempotence", func(t *testing.T) {
 		require := require.New(t)
 		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithVersion(2))
@@ -193,7 +213,7 @@ func TestAdjudicator(t *testing.T) { //nolint:maintidx
 		s.Ledger.AdvanceNow(s.Params.ChallengeDuration + 1)
 
 		for i := 0; i < 2; i++ {
-			req, err := adj.SignWithdrawRequest(s.Accs[i], adjsr.ID, s.IDs[i])
+			req, err := adj.SignWithdrawRequest(s.Accs[i], s.Domain, adjsr.ID, s.IDs[i])
 			require.NoError(err)
 			_, err = s.Adj.Withdraw(*req)
 			require.NoError(err)
@@ -228,14 +248,14 @@ func TestAdjudicator(t *testing.T) { //nolint:maintidx
 		s.Ledger.AdvanceNow(s.Params.ChallengeDuration + 1)
 
 		// Party 0 tries to withdraw party 1's funds.
-		req, err := adj.SignWithdrawRequest(s.Accs[0], adjsr.ID, s.IDs[0])
+		req, err := adj.SignWithdrawRequest(s.Accs[0], s.Domain, adjsr.ID, s.IDs[0])
 		require.NoError(err)
 		req.Req.Part = s.Accs[1].Address()
 		_, err = s.Adj.Withdraw(*req)
 		require.Error(err)
 
 		// Party 1 tries to withdraw party 0's funds.
-		req, err = adj.SignWithdrawRequest(s.Accs[1], adjsr.ID, s.IDs[1])
+		req, err = adj.SignWithdrawRequest(s.Accs[1], s.Domain, adjsr.ID, s.IDs[1])
 		require.NoError(err)
 		req.Req.Part = s.Accs[0].Address()
 		_, err = s.Adj.Withdraw(*req)
@@ -243,7 +263,7 @@ func TestAdjudicator(t *testing.T) { //nolint:maintidx
 
 		// Check if valid withdraw still possible.
 		for i := 0; i < 2; i++ {
-			req, err := adj.SignWithdrawRequest(s.Accs[i], adjsr.ID, s.IDs[i])
+			req, err := adj.SignWithdrawRequest(s.Accs[i], s.Domain, adjsr.ID, s.IDs[i])
 			require.NoError(err)
 			_, err = s.Adj.Withdraw(*req)
 			require.NoError(err)
@@ -256,4 +276,313 @@ func TestAdjudicator(t *testing.T) { //nolint:maintidx
 			require.NoError(err)
 		}
 	})
+
+	t.Run("ConcludeFinal", func(t *testing.T) {
+		require := require.New(t)
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithFinalState, adjtest.WithVersion(3))
+
+		reqs := make([]adj.SignedWithdrawReq, 0, 2)
+		for i := 0; i < 2; i++ {
+			req, err := adj.SignWithdrawRequest(s.Accs[i], s.Domain, s.State.ID, s.IDs[i])
+			require.NoError(err)
+			reqs = append(reqs, *req)
+		}
+
+		withdrawn, err := s.Adj.ConcludeFinal(s.SignedChannel(), reqs)
+		require.NoError(err)
+		require.Equal(s.State.Balances, withdrawn)
+
+		adjsr, err := s.Adj.StateReg(s.State.ID)
+		require.NoError(err)
+		require.True(adjsr.IsFinal)
+
+		// Token balance for parts must be the original value.
+		for i := 0; i < 2; i++ {
+			bal, err := s.Adj.BalanceOfID(s.IDs[i])
+			require.Equal(s.State.Balances[i], bal)
+			require.NoError(err)
+		}
+	})
+
+	t.Run("ConcludeFinal-separately", func(t *testing.T) {
+		require := require.New(t)
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithFinalState)
+
+		// Each participant concludes on its own, the second one skips registration.
+		for i := 0; i < 2; i++ {
+			req, err := adj.SignWithdrawRequest(s.Accs[i], s.Domain, s.State.ID, s.IDs[i])
+			require.NoError(err)
+			withdrawn, err := s.Adj.ConcludeFinal(s.SignedChannel(), []adj.SignedWithdrawReq{*req})
+			require.NoError(err)
+			require.Equal([]*big.Int{s.State.Balances[i]}, withdrawn)
+		}
+
+		for i := 0; i < 2; i++ {
+			bal, err := s.Adj.BalanceOfID(s.IDs[i])
+			require.Equal(s.State.Balances[i], bal)
+			require.NoError(err)
+		}
+	})
+
+	t.Run("ConcludeFinal-concluded", func(t *testing.T) {
+		require := require.New(t)
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithFinalState)
+		_, err := s.Adj.ConcludeFinal(s.SignedChannel(), []adj.SignedWithdrawReq{*s.SignedWithdrawReq(0)})
+		require.NoError(err)
+		reqs := []adj.SignedWithdrawReq{*s.SignedWithdrawReq(1)}
+
+		// The parameters of a concluded channel are still validated.
+		ch := s.SignedChannel()
+		ch.Params.Parts[0], ch.Params.Parts[1] = ch.Params.Parts[1], ch.Params.Parts[0]
+		_, err = s.Adj.ConcludeFinal(ch, reqs)
+		require.True(adj.IsAdjudicatorError(err))
+
+		// Another final state than the concluded one is rejected.
+		s.State.Version++
+		_, err = s.Adj.ConcludeFinal(s.SignedChannel(), reqs)
+		require.True(adj.IsAdjudicatorError(err))
+
+		holding, err := s.Adj.Holding(s.State.ID, s.Parts[1])
+		require.NoError(err)
+		require.Equal(s.State.Balances[1], holding)
+	})
+
+	t.Run("ConcludeFinal-invalid", func(t *testing.T) {
+		require := require.New(t)
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded)
+
+		req, err := adj.SignWithdrawRequest(s.Accs[0], s.Domain, s.State.ID, s.IDs[0])
+		require.NoError(err)
+		reqs := []adj.SignedWithdrawReq{*req}
+
+		// Non-final state.
+		_, err = s.Adj.ConcludeFinal(s.SignedChannel(), reqs)
+		require.True(adj.IsAdjudicatorError(err))
+
+		s.State.IsFinal = true
+		// No withdraw requests.
+		_, err = s.Adj.ConcludeFinal(s.SignedChannel(), nil)
+		require.True(adj.IsAdjudicatorError(err))
+
+		// Two withdraw requests for the same participant.
+		_, err = s.Adj.ConcludeFinal(s.SignedChannel(), []adj.SignedWithdrawReq{*req, *req})
+		require.True(adj.IsAdjudicatorError(err))
+
+		// Withdraw request for another channel.
+		reqs[0].Req.ID[0]++
+		_, err = s.Adj.ConcludeFinal(s.SignedChannel(), reqs)
+		require.True(adj.IsAdjudicatorError(err))
+
+		_, err = s.Adj.StateReg(s.State.ID)
+		require.ErrorIs(err, adj.ErrUnknownChannel)
+	})
+
+	t.Run("WithdrawBatch", func(t *testing.T) {
+		require := require.New(t)
+		rng := test.Prng(t)
+		s := adjtest.NewSetup(rng, adjtest.Funded, adjtest.WithFinalState)
+		require.NoError(s.Adj.Register(s.SignedChannel()))
+		id0, bals0 := s.State.ID, s.State.Balances
+
+		// Open, fund and finalize a second channel between the same participants.
+		s.Params.Nonce = new(big.Int).SetUint64(rng.Uint64())
+		s.State.ID = s.Domain.CalcID(s.Params.CoreParams())
+		s.State.Balances = []*big.Int{big.NewInt(10), big.NewInt(20)}
+		for i, part := range s.Parts {
+			require.NoError(s.Adj.Mint(s.IDs[i], s.State.Balances[i]))
+			require.NoError(s.Adj.Deposit(s.IDs[i], s.State.ID, part, s.State.Balances[i]))
+		}
+		require.NoError(s.Adj.Register(s.SignedChannel()))
+		id1, bals1 := s.State.ID, s.State.Balances
+
+		var reqs []adj.SignedWithdrawReq
+		for _, id := range []channel.ID{id0, id1} {
+			for i := 0; i < 2; i++ {
+				req, err := adj.SignWithdrawRequest(s.Accs[i], s.Domain, id, s.IDs[i])
+				require.NoError(err)
+				reqs = append(reqs, *req)
+			}
+		}
+		// Party 0 tries to withdraw party 1's funds.
+		invalid := reqs[0]
+		invalid.Req.Part = s.Accs[1].Address()
+		// Withdrawal from an unknown channel.
+		unknown, err := adj.SignWithdrawRequest(s.Accs[0], s.Domain, channel.ID{1}, s.IDs[0])
+		require.NoError(err)
+		reqs = append([]adj.SignedWithdrawReq{invalid, *unknown}, reqs...)
+		// Repeated request.
+		reqs = append(reqs, reqs[2])
+
+		results, err := s.Adj.WithdrawBatch(reqs)
+		require.NoError(err)
+		require.Len(results, len(reqs))
+		for _, i := range []int{0, 1, len(reqs) - 1} {
+			require.Nil(results[i].Amount)
+			require.NotEmpty(results[i].Error)
+		}
+		for i, bal := range append(bals0, bals1...) {
+			require.Empty(results[i+2].Error)
+			require.Equal(bal, results[i+2].Amount)
+		}
+
+		for i := 0; i < 2; i++ {
+			bal, err := s.Adj.BalanceOfID(s.IDs[i])
+			require.NoError(err)
+			require.Equal(new(big.Int).Add(bals0[i], bals1[i]), bal)
+		}
+	})
+
+	t.Run("Withdraw-domain", func(t *testing.T) {
+		require := require.New(t)
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithFinalState)
+		require.NoError(s.Adj.Register(s.SignedChannel()))
+
+		// Request signed for another deployment of the adjudicator.
+		other := adj.NewDomain(s.Domain.Channel, "otherAdjudicator")
+		req, err := adj.SignWithdrawRequest(s.Accs[0], other, s.State.ID, s.IDs[0])
+		require.NoError(err)
+		_, err = s.Adj.Withdraw(*req)
+		require.True(adj.IsAdjudicatorError(err))
+
+		// Tampering with the domain invalidates the signature.
+		req.Req.Domain = s.Domain
+		_, err = s.Adj.Withdraw(*req)
+		require.True(adj.IsAdjudicatorError(err))
+
+		_, err = s.Adj.Withdraw(*s.SignedWithdrawReq(0))
+		require.NoError(err)
+	})
+
+	t.Run("Withdraw-expiry", func(t *testing.T) {
+		require := require.New(t)
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithFinalState)
+		require.NoError(s.Adj.Register(s.SignedChannel()))
+
+		expiry := s.Ledger.Now().Add(5)
+		req0 := s.SignedWithdrawReq(0, adj.WithExpiry(expiry))
+		req1 := s.SignedWithdrawReq(1, adj.WithExpiry(expiry))
+		_, err := s.Adj.Withdraw(*req0)
+		require.NoError(err)
+
+		s.Ledger.AdvanceNow(6)
+		_, err = s.Adj.Withdraw(*req1)
+		require.ErrorAs(err, new(adj.ExpiredError))
+
+		// A request without expiry is still valid.
+		_, err = s.Adj.Withdraw(*s.SignedWithdrawReq(1))
+		require.NoError(err)
+	})
+
+	t.Run("Payout", func(t *testing.T) {
+		require := require.New(t)
+		receivers := []adj.AccountID{"payout0", "payout1"}
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithPayouts(receivers...))
+		require.NoError(s.Adj.Register(s.SignedChannel()))
+
+		// Channel not finalized yet.
+		_, err := s.Adj.Payout(s.State.ID)
+		require.ErrorAs(err, new(adj.ChallengeTimeoutError))
+
+		// Anyone can trigger the payout after finalization.
+		s.Ledger.AdvanceNow(s.Params.ChallengeDuration + 1)
+		withdrawn, err := s.Adj.Payout(s.State.ID)
+		require.NoError(err)
+		require.Equal([]*big.Int(s.State.Balances), withdrawn)
+		for i, receiver := range receivers {
+			requireBalance(t, s, receiver, s.State.Balances[i])
+			requireBalance(t, s, s.IDs[i], new(big.Int))
+		}
+		requireBalance(t, s, chtest.AdjudicatorName, new(big.Int))
+	})
+
+	t.Run("Payout-bound", func(t *testing.T) {
+		require := require.New(t)
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithPayouts("payout0", "payout1"))
+
+		// Only participant 0 binds its payout first.
+		ch := s.SignedChannel()
+		ch.Params.Payouts[1] = adj.Payout{}
+		require.NoError(s.Adj.Register(ch))
+		_, err := s.Adj.Payout(s.State.ID)
+		require.ErrorAs(err, new(adj.ChallengeTimeoutError))
+
+		// A bound payout cannot be changed, the others can be bound later.
+		s.State.Version++
+		ch = s.SignedChannel()
+		po, err := adj.SignPayout(s.Accs[0], s.Domain, s.State.ID, "other")
+		require.NoError(err)
+		ch.Params.Payouts[0] = *po
+		require.NoError(s.Adj.Register(ch))
+		reg, err := s.Adj.StateReg(s.State.ID)
+		require.NoError(err)
+		require.Len(reg.Payouts, 2)
+		require.Equal(adj.AccountID("payout0"), reg.Receiver(s.SignedWithdrawReq(0).Req))
+		require.Equal(adj.AccountID("payout1"), reg.Receiver(s.SignedWithdrawReq(1).Req))
+
+		// Withdrawals pay to the bound receivers.
+		s.Ledger.AdvanceNow(s.Params.ChallengeDuration + 1)
+		_, err = s.Adj.Withdraw(*s.SignedWithdrawReq(0))
+		require.NoError(err)
+		results, err := s.Adj.WithdrawBatch([]adj.SignedWithdrawReq{*s.SignedWithdrawReq(1)})
+		require.NoError(err)
+		require.Empty(results[0].Error)
+		for i, receiver := range []adj.AccountID{"payout0", "payout1"} {
+			requireBalance(t, s, receiver, s.State.Balances[i])
+			requireBalance(t, s, s.IDs[i], new(big.Int))
+		}
+
+		// Nothing left to pay out.
+		withdrawn, err := s.Adj.Payout(s.State.ID)
+		require.NoError(err)
+		require.Zero(withdrawn[0].Sign())
+		require.Zero(withdrawn[1].Sign())
+	})
+
+	t.Run("Payout-ConcludeFinal", func(t *testing.T) {
+		require := require.New(t)
+		receivers := []adj.AccountID{"payout0", "payout1"}
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithFinalState, adjtest.WithPayouts(receivers...))
+
+		_, err := s.Adj.ConcludeFinal(s.SignedChannel(), []adj.SignedWithdrawReq{*s.SignedWithdrawReq(0)})
+		require.NoError(err)
+		requireBalance(t, s, receivers[0], s.State.Balances[0])
+		withdrawn, err := s.Adj.Payout(s.State.ID)
+		require.NoError(err)
+		require.Zero(withdrawn[0].Sign())
+		require.Equal(s.State.Balances[1], withdrawn[1])
+		requireBalance(t, s, receivers[1], s.State.Balances[1])
+	})
+
+	t.Run("Payout-invalid", func(t *testing.T) {
+		require := require.New(t)
+		s := adjtest.NewSetup(test.Prng(t), adjtest.Funded, adjtest.WithFinalState, adjtest.WithPayouts("payout0", "payout1"))
+
+		// No payouts bound.
+		_, err := s.Adj.Payout(s.State.ID)
+		require.ErrorIs(err, adj.ErrUnknownChannel)
+
+		// Redirect participant 0's funds to participant 1.
+		ch := s.SignedChannel()
+		ch.Params.Payouts[0].Receiver = s.IDs[1]
+		require.True(adj.IsAdjudicatorError(s.Adj.Register(ch)))
+
+		// Missing payouts.
+		ch = s.SignedChannel()
+		ch.Params.Payouts = ch.Params.Payouts[1:]
+		require.True(adj.IsAdjudicatorError(s.Adj.Register(ch)))
+
+		// Registration without payouts.
+		ch = s.SignedChannel()
+		ch.Params.Payouts = nil
+		require.NoError(s.Adj.Register(ch))
+		_, err = s.Adj.Payout(s.State.ID)
+		require.True(adj.IsAdjudicatorError(err))
+	})
+}
+
+func requireBalance(t *testing.T, s *adjtest.Setup, id adj.AccountID, expected *big.Int) {
+	t.Helper()
+	bal, err := s.Adj.BalanceOfID(id)
+	require.NoError(t, err)
+	require.Zero(t, bal.Cmp(expected), "token balance of %s: expected %v, got %v", id, expected, bal)
 }
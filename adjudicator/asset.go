@@ -15,6 +15,7 @@
 package adjudicator
 
 import (
+	"fmt"
 	"math/big"
 )
 
@@ -23,6 +24,30 @@ import (
 // Ensure it is unique for every client interacting with Asset and no impersonation is possible.
 type AccountID string
 
+// Payment is an amount of tokens sent to a receiver.
+type Payment struct {
+	Receiver AccountID
+	Amount   *big.Int
+}
+
+// CheckPayments checks that the payments have distinct receivers and no
+// negative amounts and returns their total amount.
+func CheckPayments(payments []Payment) (*big.Int, error) {
+	total := new(big.Int)
+	receivers := make(map[AccountID]struct{}, len(payments))
+	for i, p := range payments {
+		if p.Amount.Sign() < 0 {
+			return nil, fmt.Errorf("payment[%d]: cannot transfer negative amount", i)
+		}
+		if _, ok := receivers[p.Receiver]; ok {
+			return nil, fmt.Errorf("payment[%d]: duplicate receiver", i)
+		}
+		receivers[p.Receiver] = struct{}{}
+		total.Add(total, p.Amount)
+	}
+	return total, nil
+}
+
 // Asset is a basic interface for creating tokens with.
 type Asset interface {
 	// Mint creates the desired amount of token for the given id.
@@ -37,6 +62,12 @@ type Asset interface {
 	// Note that sender must be authenticated first.
 	Transfer(sender AccountID, receiver AccountID, amount *big.Int) error
 
+	// TransferBatch sends the given payments from sender to their receivers.
+	// Each receiver must only occur once, so that every balance is only read
+	// and written once, as a transaction cannot read its own writes on Fabric.
+	// Note that sender must be authenticated first.
+	TransferBatch(sender AccountID, payments []Payment) error
+
 	// BalanceOf returns the amount of tokens the given id holds.
 	BalanceOf(id AccountID) (*big.Int, error)
 }
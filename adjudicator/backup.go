@@ -0,0 +1,120 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adjudicator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// BackupFormat identifies channel backups.
+	BackupFormat = "perun-fabric/channel-backup"
+	// BackupVersion is the current version of the channel backup format.
+	BackupVersion uint64 = 1
+)
+
+// ErrUnsupportedBackup indicates that data is not a channel backup or that
+// its version is not supported.
+var ErrUnsupportedBackup = errors.New("unsupported channel backup")
+
+// Backup is a self-describing snapshot of the latest fully signed state of a
+// channel, together with its params and the adjudicator domain it belongs
+// to. It is all that is needed to register the state and withdraw from the
+// channel without the client that created it.
+type Backup struct {
+	Format  string        `json:"format"`  // Format is always BackupFormat.
+	Version uint64        `json:"version"` // Version is the version of the backup format.
+	Created time.Time     `json:"created"` // Created is the creation time of the backup.
+	Domain  Domain        `json:"domain"`  // Domain is the domain of the adjudicator the channel is opened on.
+	Channel SignedChannel `json:"channel"` // Channel is the signed channel state.
+}
+
+// NewBackup creates a Backup of the signed channel in the given domain, in
+// the current backup format.
+func NewBackup(domain Domain, ch *SignedChannel) *Backup {
+	return &Backup{
+		Format:  BackupFormat,
+		Version: BackupVersion,
+		Created: time.Now().UTC(),
+		Domain:  domain,
+		Channel: *ch.Clone(),
+	}
+}
+
+// ReadBackup reads a Backup from r. It returns an error wrapping
+// ErrUnsupportedBackup if the data is not a backup of a supported version.
+func ReadBackup(r io.Reader) (*Backup, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading backup: %w", err)
+	}
+	return ParseBackup(data)
+}
+
+// ParseBackup parses a JSON encoded Backup. It returns an error wrapping
+// ErrUnsupportedBackup if the data is not a backup of a supported version.
+func ParseBackup(data []byte) (*Backup, error) {
+	// The header is checked first, so that later formats are not decoded as
+	// the current one.
+	var header struct {
+		Format  string `json:"format"`
+		Version uint64 `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("decoding backup header: %w", err)
+	} else if err := checkBackupHeader(header.Format, header.Version); err != nil {
+		return nil, err
+	}
+
+	b := new(Backup)
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("decoding backup: %w", err)
+	}
+	return b, nil
+}
+
+// WriteTo writes the Backup as indented JSON to w.
+func (b *Backup) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("encoding backup: %w", err)
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
+
+// Verify checks that the backup is of a supported format and version and that
+// its signed channel is valid in its domain, see ValidateChannel.
+func (b *Backup) Verify() error {
+	if err := checkBackupHeader(b.Format, b.Version); err != nil {
+		return err
+	}
+	return ValidateChannel(b.Domain, &b.Channel)
+}
+
+// checkBackupHeader returns an error wrapping ErrUnsupportedBackup if the
+// given format and version are not supported.
+func checkBackupHeader(format string, version uint64) error {
+	if format != BackupFormat {
+		return fmt.Errorf("%w: format %q", ErrUnsupportedBackup, format)
+	} else if version == 0 || version > BackupVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedBackup, version)
+	}
+	return nil
+}
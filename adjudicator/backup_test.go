@@ -0,0 +1,85 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adjudicator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/stretchr/testify/require"
+	"polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+)
+
+func TestBackup(t *testing.T) {
+	rng := test.Prng(t)
+	setup := adjtest.NewSetup(rng)
+	b := adj.NewBackup(setup.Domain, setup.SignedChannel())
+	require.Equal(t, adj.BackupFormat, b.Format)
+	require.Equal(t, adj.BackupVersion, b.Version)
+	require.NoError(t, b.Verify())
+
+	var buf bytes.Buffer
+	_, err := b.WriteTo(&buf)
+	require.NoError(t, err)
+	b1, err := adj.ReadBackup(&buf)
+	require.NoError(t, err)
+	require.Zero(t, deep.Equal(b, b1))
+	require.NoError(t, b1.Verify())
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, modify := range map[string]func(*adj.Backup){
+			"wrong domain":      func(b *adj.Backup) { b.Domain = testDomain },
+			"missing signature": func(b *adj.Backup) { b.Channel.Sigs = b.Channel.Sigs[:1] },
+			"modified state":    func(b *adj.Backup) { b.Channel.State.Balances[0].Add(b.Channel.State.Balances[0], big.NewInt(1)) },
+			"missing balance":   func(b *adj.Backup) { b.Channel.State.Balances = b.Channel.State.Balances[:1] },
+			"missing payout":    func(b *adj.Backup) { b.Channel.Params.Payouts = []adj.Payout{{}} },
+		} {
+			b := adj.NewBackup(setup.Domain, setup.SignedChannel())
+			modify(b)
+			err := b.Verify()
+			require.True(t, errors.As(err, new(adj.ValidationError)), "%s: ValidationError expected, got %v", name, err)
+		}
+
+		for name, modify := range map[string]func(*adj.Backup){
+			"other format":  func(b *adj.Backup) { b.Format = "other" },
+			"newer version": func(b *adj.Backup) { b.Version = adj.BackupVersion + 1 },
+		} {
+			b := adj.NewBackup(setup.Domain, setup.SignedChannel())
+			modify(b)
+			require.True(t, errors.Is(b.Verify(), adj.ErrUnsupportedBackup), name)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		for name, header := range map[string]map[string]interface{}{
+			"raw signed channel": {"params": b.Channel.Params},
+			"other format":       {"format": "other", "version": adj.BackupVersion},
+			"newer version":      {"format": adj.BackupFormat, "version": adj.BackupVersion + 1},
+			"zero version":       {"format": adj.BackupFormat, "version": 0},
+		} {
+			data, err := json.Marshal(header)
+			require.NoError(t, err)
+			_, err = adj.ParseBackup(data)
+			require.True(t, errors.Is(err, adj.ErrUnsupportedBackup), name)
+		}
+	})
+}
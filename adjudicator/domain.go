@@ -0,0 +1,70 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adjudicator
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"github.com/pkg/errors"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire/perunio"
+)
+
+// DomainVersion is the current version of the signed adjudicator payloads.
+// It is part of every Domain created by NewDomain.
+const DomainVersion uint64 = 1
+
+// Domain separates the channels and withdraw requests of different adjudicator
+// deployments. It is part of every channel id and signed withdraw request, so
+// that signed states and withdrawals cannot be replayed on another deployment.
+type Domain struct {
+	Channel   string `json:"channel"`   // Channel is the Fabric channel name.
+	Chaincode string `json:"chaincode"` // Chaincode is the adjudicator chaincode name.
+	Version   uint64 `json:"version"`   // Version is the version of the signed payloads.
+}
+
+// NewDomain returns the Domain of the adjudicator chaincode deployed under the
+// given name on the given Fabric channel, using the current DomainVersion.
+func NewDomain(fabricChannel, chaincode string) Domain {
+	return Domain{
+		Channel:   fabricChannel,
+		Chaincode: chaincode,
+		Version:   DomainVersion,
+	}
+}
+
+// CalcID calculates the id of the channel with the given params in Domain d.
+func (d Domain) CalcID(params *channel.Params) channel.ID {
+	hash := sha256.New()
+	if err := perunio.Encode(hash, d, params); err != nil {
+		panic("error encoding Domain and Params: " + err.Error())
+	}
+	id := channel.ID{}
+	copy(id[:], hash.Sum(nil))
+	return id
+}
+
+// Encode encodes the Domain into an `io.Writer` or returns an `error`.
+func (d Domain) Encode(w io.Writer) error {
+	return errors.WithMessage(
+		perunio.Encode(w, d.Channel, d.Chaincode, d.Version), "Domain encode")
+}
+
+// Decode decodes a Domain from an `io.Reader` or returns an `error`.
+func (d *Domain) Decode(r io.Reader) error {
+	return errors.WithMessage(
+		perunio.Decode(r, &d.Channel, &d.Chaincode, &d.Version), "Domain decode")
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adjudicator_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+)
+
+func TestDomainCalcID(t *testing.T) {
+	rng := test.Prng(t)
+	params := adjtest.RandomParams(rng).CoreParams()
+	d := adj.NewDomain("someChannel", "someAdjudicator")
+	require.Equal(t, d.CalcID(params), d.CalcID(params))
+
+	for _, other := range []adj.Domain{
+		adj.NewDomain("otherChannel", d.Chaincode),
+		adj.NewDomain(d.Channel, "otherAdjudicator"),
+		{Channel: d.Channel, Chaincode: d.Chaincode, Version: d.Version + 1},
+	} {
+		require.NotEqual(t, d.CalcID(params), other.CalcID(params))
+	}
+}
+
+func TestDomainEncoding(t *testing.T) {
+	d := adj.NewDomain("someChannel", "someAdjudicator")
+	var buf bytes.Buffer
+	require.NoError(t, d.Encode(&buf))
+	var d1 adj.Domain
+	require.NoError(t, d1.Decode(&buf))
+	require.Equal(t, d, d1)
+}
@@ -33,6 +33,12 @@ type (
 		Now     Timestamp
 	}
 
+	// ExpiredError indicates that a signed request expired.
+	ExpiredError struct {
+		Expiry Timestamp
+		Now    Timestamp
+	}
+
 	// VersionError indicates that the chaincode holds a newer version of the proposed channel state.
 	VersionError struct {
 		Registered uint64
@@ -55,6 +61,10 @@ func (te ChallengeTimeoutError) Error() string {
 	return fmt.Sprintf("challenge period ended (timeout: %v, now: %v)", te.Timeout, te.Now)
 }
 
+func (ee ExpiredError) Error() string {
+	return fmt.Sprintf("request expired (expiry: %v, now: %v)", ee.Expiry, ee.Now)
+}
+
 func (ve VersionError) Error() string {
 	return fmt.Sprintf("version too low (registered: %d, tried: %d)", ve.Registered, ve.Tried)
 }
@@ -64,7 +74,8 @@ func (ue UnderfundedError) Error() string {
 }
 
 // IsAdjudicatorError returns true if the given error is one of the following:
-// ValidationError, ChallengeTimeoutError, VersionError, UnderfundedError.
+// ValidationError, ChallengeTimeoutError, ExpiredError, VersionError,
+// UnderfundedError.
 func IsAdjudicatorError(err error) bool {
 	if err == nil {
 		return false
@@ -73,6 +84,7 @@ func IsAdjudicatorError(err error) bool {
 	adjErrors := []interface{}{
 		new(ValidationError),
 		new(ChallengeTimeoutError),
+		new(ExpiredError),
 		new(VersionError),
 		new(UnderfundedError),
 	}
@@ -32,6 +32,7 @@ func TestIsAdjudicatorError(t *testing.T) {
 	adjErrors := []error{
 		adj.ValidationError{},
 		adj.ChallengeTimeoutError{},
+		adj.ExpiredError{},
 		adj.VersionError{},
 		adj.UnderfundedError{},
 	}
@@ -0,0 +1,117 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adjudicator
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"perun.network/go-perun/wallet"
+)
+
+// IdemixAuthKey is the key of the IdemixAuth in the transient data of the
+// transactions of Idemix identities.
+const IdemixAuthKey = "perun-idemix-auth"
+
+// idemixAuthPrefix separates the signatures of IdemixAuths from other
+// signatures of the account.
+const idemixAuthPrefix = "perun-fabric idemix auth:"
+
+type (
+	// IdemixAuth authorizes an Idemix identity to act on behalf of the
+	// AccountID of a Perun wallet address. Idemix identities present a fresh
+	// pseudonym with every transaction and carry no attribute that identifies
+	// them stably. Therefore, their AccountID is derived from an account key
+	// of their choice, see IdemixAccountID, which they prove to control by
+	// signing the serialized identity that creates the transaction.
+	//
+	// The signature binds the IdemixAuth to the pseudonym of the creator.
+	// Only the holder of the Idemix credential can create transactions with
+	// that pseudonym, so the IdemixAuth cannot be used by others.
+	IdemixAuth struct {
+		Address wallet.Address // Address is the address of the account key.
+		Sig     wallet.Sig     // Sig is the signature of the creator by the account key.
+	}
+
+	idemixAuthJSON struct {
+		Address []byte     `json:"address"`
+		Sig     wallet.Sig `json:"sig"`
+	}
+)
+
+// IdemixAccountID returns the AccountID of the Idemix identities of the given
+// MSP that are authorized by the account key of the given address. It is
+// derived analogous to the X.509 AccountIDs of the form
+// base64("x509::<subject>::<issuer>").
+func IdemixAccountID(mspID string, addr wallet.Address) AccountID {
+	id := fmt.Sprintf("idemix::%s::%s", mspID, addr)
+	return AccountID(base64.StdEncoding.EncodeToString([]byte(id)))
+}
+
+// SignIdemixAuth returns the IdemixAuth of the given account for the given
+// creator, which is the serialized Idemix identity of the transactions.
+func SignIdemixAuth(acc wallet.Account, creator []byte) (*IdemixAuth, error) {
+	sig, err := acc.SignData(idemixAuthData(creator))
+	if err != nil {
+		return nil, fmt.Errorf("signing idemix auth: %w", err)
+	}
+	return &IdemixAuth{Address: acc.Address(), Sig: sig}, nil
+}
+
+// Verify verifies that the IdemixAuth is signed for the given creator.
+func (a *IdemixAuth) Verify(creator []byte) error {
+	if a.Address == nil {
+		return errors.New("idemix auth without address")
+	}
+	ok, err := wallet.VerifySignature(idemixAuthData(creator), a.Sig, a.Address)
+	if err != nil {
+		return fmt.Errorf("verifying idemix auth: %w", err)
+	} else if !ok {
+		return errors.New("invalid idemix auth signature")
+	}
+	return nil
+}
+
+func idemixAuthData(creator []byte) []byte {
+	h := sha256.Sum256(creator)
+	return append([]byte(idemixAuthPrefix), h[:]...)
+}
+
+// MarshalJSON marshals the IdemixAuth with the address in its binary
+// encoding.
+func (a IdemixAuth) MarshalJSON() ([]byte, error) {
+	addr, err := a.Address.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("marshaling address: %w", err)
+	}
+	return json.Marshal(idemixAuthJSON{Address: addr, Sig: a.Sig})
+}
+
+// UnmarshalJSON unmarshals an IdemixAuth marshaled by MarshalJSON.
+func (a *IdemixAuth) UnmarshalJSON(data []byte) error {
+	var aj idemixAuthJSON
+	if err := json.Unmarshal(data, &aj); err != nil {
+		return err
+	}
+	addr := wallet.NewAddress()
+	if err := addr.UnmarshalBinary(aj.Address); err != nil {
+		return fmt.Errorf("unmarshaling address: %w", err)
+	}
+	a.Address, a.Sig = addr, aj.Sig
+	return nil
+}
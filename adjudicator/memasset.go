@@ -91,6 +91,29 @@ func (m MemAsset) Transfer(sender AccountID, receiver AccountID, amount *big.Int
 	return nil
 }
 
+// TransferBatch checks if the proposed payments are valid and transfers
+// them from the sender to their receivers.
+func (m MemAsset) TransferBatch(sender AccountID, payments []Payment) error {
+	total, err := CheckPayments(payments)
+	if err != nil {
+		return err
+	}
+
+	// Check balance of sender.
+	senderBal, _ := m.BalanceOf(sender) // No error expected.
+	if senderBal.Cmp(total) < 0 {
+		return fmt.Errorf("not enought funds to transfer the requested amount")
+	}
+
+	// Store new balances.
+	m.holdings[string(sender)] = senderBal.Sub(senderBal, total)
+	for _, p := range payments {
+		receiverBal, _ := m.BalanceOf(p.Receiver) // No error expected.
+		m.holdings[string(p.Receiver)] = receiverBal.Add(receiverBal, p.Amount)
+	}
+	return nil
+}
+
 // BalanceOf returns the amount of tokens the given id holds.
 // If the id is unknown, zero is returned.
 func (m MemAsset) BalanceOf(id AccountID) (*big.Int, error) {
@@ -26,8 +26,10 @@ import (
 // MemLedger is a simple in-memory ledger, using Go maps.
 // time.Time is used as Timestamps.
 type MemLedger struct {
-	states   map[channel.ID]*StateReg
-	holdings map[string]*big.Int
+	states        map[channel.ID]*StateReg
+	holdings      map[string]*big.Int
+	registrations map[AccountID]*SignedRegistration
+	owners        map[string]AccountID
 }
 
 // IDKey creates the key used for storing the channel state in the states map.
@@ -40,11 +42,18 @@ func FundingKey(id channel.ID, addr wallet.Address) string {
 	return fmt.Sprintf("%x:%s", id, addr)
 }
 
+// AddressKey creates the key used for storing the owner of an address.
+func AddressKey(addr wallet.Address) string {
+	return addr.String()
+}
+
 // NewMemLedger generates a new local in-memory ledger for testing purposes.
 func NewMemLedger() *MemLedger {
 	return &MemLedger{
-		states:   make(map[channel.ID]*StateReg),
-		holdings: make(map[string]*big.Int),
+		states:        make(map[channel.ID]*StateReg),
+		holdings:      make(map[string]*big.Int),
+		registrations: make(map[AccountID]*SignedRegistration),
+		owners:        make(map[string]AccountID),
 	}
 }
 
@@ -79,6 +88,43 @@ func (m *MemLedger) PutHolding(id channel.ID, addr wallet.Address, holding *big.
 	return nil
 }
 
+// GetRegistration retrieves the address book registration of the given id.
+func (m *MemLedger) GetRegistration(id AccountID) (*SignedRegistration, error) { //nolint:forbidigo
+	r, ok := m.registrations[id]
+	if !ok {
+		return nil, &NotFoundError{Key: string(id), Type: "SignedRegistration"}
+	}
+	return r.Clone(), nil
+}
+
+// PutRegistration overwrites the address book registration of its AccountID.
+func (m *MemLedger) PutRegistration(r *SignedRegistration) error {
+	m.registrations[r.Reg.AccountID] = r.Clone()
+	return nil
+}
+
+// GetAddressOwner retrieves the AccountID that registered the given address.
+func (m *MemLedger) GetAddressOwner(addr wallet.Address) (AccountID, error) { //nolint:forbidigo
+	key := AddressKey(addr)
+	id, ok := m.owners[key]
+	if !ok {
+		return "", &NotFoundError{Key: key, Type: "AccountID"}
+	}
+	return id, nil
+}
+
+// PutAddressOwner sets the AccountID that registered the given address.
+func (m *MemLedger) PutAddressOwner(addr wallet.Address, id AccountID) error {
+	m.owners[AddressKey(addr)] = id
+	return nil
+}
+
+// DeleteAddressOwner deletes the owner of the given address.
+func (m *MemLedger) DeleteAddressOwner(addr wallet.Address) error {
+	delete(m.owners, AddressKey(addr))
+	return nil
+}
+
 // Now returns time.Now() as a Timestamp.
 func (m *MemLedger) Now() Timestamp {
 	return StdNow()
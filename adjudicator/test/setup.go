@@ -36,6 +36,7 @@ const (
 type (
 	// Setup provides necessary elements for testing.
 	Setup struct {
+		Domain  adj.Domain
 		IDs     []adj.AccountID
 		Parts   []wallet.Address
 		Accs    []wallet.Account
@@ -92,23 +93,25 @@ func NewSetup(rng *rand.Rand, opts ...SetupOption) *Setup {
 		Parts:             parts,
 		Nonce:             new(big.Int).SetUint64(rng.Uint64()),
 	}
+	domain := adj.NewDomain(chtest.ChannelName, chtest.AdjudicatorName)
 	ledger := NewTestLedger()
 	asset := adj.NewMemAsset()
 	ids := []adj.AccountID{adj.AccountID(parts[0].String()), adj.AccountID(parts[1].String())}
 
 	s := &Setup{
+		Domain: domain,
 		IDs:    ids,
 		Parts:  parts,
 		Accs:   accs,
 		Params: params,
 		State: &adj.State{
-			ID:       params.ID(),
+			ID:       domain.CalcID(params.CoreParams()),
 			Version:  0,
 			Balances: test.NewRandomBals(rng, numParts),
 			IsFinal:  false,
 		},
 		Ledger:  ledger,
-		Adj:     adj.NewAdjudicator(chtest.AdjudicatorName, ledger, asset),
+		Adj:     adj.NewAdjudicator(chtest.AdjudicatorName, domain, ledger, asset),
 		Timeout: ledger.Now().Add(params.ChallengeDuration),
 	}
 
@@ -131,6 +134,16 @@ func (s *Setup) SignedChannel() *adj.SignedChannel {
 	return ch.Clone()
 }
 
+// SignedWithdrawReq returns a withdraw request of participant idx for the current channel, signed in the Setup's
+// domain. The funds are withdrawn to the participant's id.
+func (s *Setup) SignedWithdrawReq(idx int, opts ...adj.WithdrawReqOpt) *adj.SignedWithdrawReq {
+	req, err := adj.SignWithdrawRequest(s.Accs[idx], s.Domain, s.State.ID, s.IDs[idx], opts...)
+	if err != nil {
+		panic(fmt.Sprintf("Setup: error signing withdraw request: %v", err))
+	}
+	return req
+}
+
 // StateReg returns the StateReg according to the current state and ledger's now.
 func (s *Setup) StateReg() *adj.StateReg {
 	return &adj.StateReg{
@@ -190,6 +203,26 @@ var Funded = setupModifier(func(s *Setup) {
 	}
 })
 
+// WithPayouts adds to the params a payout for every participant to the
+// respective receiver, signed in the Setup's domain.
+func WithPayouts(receivers ...adj.AccountID) SetupOption {
+	return setupModifier(func(s *Setup) {
+		if n := len(s.Params.Parts); len(receivers) != n {
+			panic(fmt.Sprintf(
+				"Setup: receivers mismatch number of participants (%d != %d)",
+				len(receivers), n))
+		}
+		s.Params.Payouts = make([]adj.Payout, 0, len(s.Accs))
+		for i, acc := range s.Accs {
+			po, err := adj.SignPayout(acc, s.Domain, s.State.ID, receivers[i])
+			if err != nil {
+				panic(fmt.Sprintf("Setup: error signing payout[%d]: %v", i, err))
+			}
+			s.Params.Payouts = append(s.Params.Payouts, *po)
+		}
+	})
+}
+
 // WithAccounts allows setting own Accounts instead of using random ones.
 func WithAccounts(accs ...wallet.Account) SetupOption {
 	return withAccsOption{accs: accs}
@@ -21,6 +21,8 @@ import (
 	"github.com/pkg/errors"
 	"io"
 	"math/big"
+	"time"
+
 	"perun.network/go-perun/wire/perunio"
 
 	"perun.network/go-perun/channel"
@@ -29,10 +31,22 @@ import (
 
 type (
 	// Params are the parameters of a state channel.
+	// Payouts are optional. If set, they hold one Payout per participant, which
+	// is bound to the channel on registration, see Adjudicator.Register.
 	Params struct {
 		ChallengeDuration uint64           `json:"challengeDuration"`
 		Parts             []wallet.Address `json:"parts"`
 		Nonce             channel.Nonce    `json:"nonce"`
+		Payouts           []Payout         `json:"payouts,omitempty"`
+	}
+
+	// Payout binds the funds of a channel participant to the AccountID they are
+	// paid out to. Sig is the participant's signature on the WithdrawReq for the
+	// channel and Receiver without expiry, see SignPayout. A Payout without
+	// Receiver binds nothing.
+	Payout struct {
+		Receiver AccountID  `json:"receiver,omitempty"`
+		Sig      wallet.Sig `json:"sig,omitempty"`
 	}
 
 	// State is a state of a state channel.
@@ -51,32 +65,74 @@ type (
 	}
 
 	// WithdrawReq are parameters needed to withdraw funds from a state channel.
+	// The Domain binds the request to a single adjudicator deployment. If
+	// Expiry is set, the request is only valid until then.
 	WithdrawReq struct {
+		Domain   Domain         `json:"domain"`
 		ID       channel.ID     `json:"id"`
 		Part     wallet.Address `json:"part"`
 		Receiver AccountID      `json:"receiver"`
+		Expiry   *Timestamp     `json:"expiry,omitempty"`
 	}
 
+	// WithdrawReqOpt extends the construction of a WithdrawReq in
+	// SignWithdrawRequest.
+	WithdrawReqOpt func(*WithdrawReq)
+
 	// SignedWithdrawReq contains a signature over a WithdrawReq to check its validity.
 	SignedWithdrawReq struct {
 		Req WithdrawReq `json:"req"`
 		Sig wallet.Sig  `json:"sig"`
 	}
 
+	// WithdrawResult is the outcome of a single request of a batch withdrawal.
+	// Either Amount is set to the withdrawn amount or Error describes why the
+	// request failed.
+	WithdrawResult struct {
+		Amount *big.Int `json:"amount,omitempty"`
+		Error  string   `json:"error,omitempty"`
+	}
+
 	// StateReg adds a Timeout to the State to indicate the states challenge timeout.
+	// Payouts are the signed payouts of the participants that are bound to the
+	// channel. Their funds are always paid out to the respective Receiver.
 	StateReg struct {
 		State   `json:"state"`
-		Timeout Timestamp `json:"timeout"`
+		Timeout Timestamp           `json:"timeout"`
+		Payouts []SignedWithdrawReq `json:"payouts,omitempty"`
 	}
 )
 
 // Clone duplicates the params.
 func (p Params) Clone() Params {
 	p.Parts = wallet.CloneAddresses(p.Parts)
+	if p.Payouts != nil {
+		payouts := make([]Payout, len(p.Payouts))
+		for i, po := range p.Payouts {
+			payouts[i] = Payout{Receiver: po.Receiver, Sig: append(wallet.Sig(nil), po.Sig...)}
+		}
+		p.Payouts = payouts
+	}
 	return p
 }
 
+// payoutReq returns the signed withdraw request of the payout of participant
+// idx in channel id of the given domain.
+func (p Params) payoutReq(domain Domain, id channel.ID, idx int) SignedWithdrawReq {
+	return SignedWithdrawReq{
+		Req: WithdrawReq{
+			Domain:   domain,
+			ID:       id,
+			Part:     p.Parts[idx],
+			Receiver: p.Payouts[idx].Receiver,
+		},
+		Sig: p.Payouts[idx].Sig,
+	}
+}
+
 // ID return the params channel id.
+// It is calculated by the channel backend, i.e., in the Domain set there.
+// Use Domain.CalcID to calculate the id in a specific Domain.
 func (p Params) ID() channel.ID {
 	return channel.CalcID(p.CoreParams())
 }
@@ -102,6 +158,7 @@ func (p *Params) UnmarshalJSON(data []byte) error {
 		ChallengeDuration uint64            `json:"challengeDuration"`
 		Parts             []json.RawMessage `json:"parts"`
 		Nonce             channel.Nonce     `json:"nonce"`
+		Payouts           []Payout          `json:"payouts,omitempty"`
 	}
 	if err := json.Unmarshal(data, &pj); err != nil {
 		return err
@@ -109,6 +166,7 @@ func (p *Params) UnmarshalJSON(data []byte) error {
 
 	p.ChallengeDuration = pj.ChallengeDuration
 	p.Nonce = pj.Nonce
+	p.Payouts = pj.Payouts
 	p.Parts = make([]wallet.Address, 0, len(pj.Parts))
 	for i, rawp := range pj.Parts {
 		part := wallet.NewAddress()
@@ -176,9 +234,17 @@ func VerifySig(signer wallet.Address, state State, sig wallet.Sig) (bool, error)
 
 // Clone duplicates the StateReg.
 func (s *StateReg) Clone() *StateReg {
+	var payouts []SignedWithdrawReq
+	if s.Payouts != nil {
+		payouts = make([]SignedWithdrawReq, len(s.Payouts))
+		for i, po := range s.Payouts {
+			payouts[i] = SignedWithdrawReq{Req: po.Req, Sig: append(wallet.Sig(nil), po.Sig...)}
+		}
+	}
 	return &StateReg{
 		State:   s.State.Clone(),
 		Timeout: s.Timeout.Clone(),
+		Payouts: payouts,
 	}
 }
 
@@ -188,6 +254,27 @@ func (s *StateReg) Equal(sr StateReg) bool {
 	return err == nil && s.Timeout.Equal(sr.Timeout)
 }
 
+// Receiver returns the AccountID the funds of the participant of the given
+// withdraw request are paid out to. This is the receiver of the participant's
+// bound payout, if any, and the receiver of the request otherwise.
+func (s *StateReg) Receiver(req WithdrawReq) AccountID {
+	if po := s.payout(req.Part); po != nil {
+		return po.Req.Receiver
+	}
+	return req.Receiver
+}
+
+// payout returns the bound payout of the given participant or nil if there is
+// none.
+func (s *StateReg) payout(part wallet.Address) *SignedWithdrawReq {
+	for i, po := range s.Payouts {
+		if po.Req.Part.Equal(part) {
+			return &s.Payouts[i]
+		}
+	}
+	return nil
+}
+
 // IsFinalizedAt checks if the registered state is final.
 // This is the case if either the isFinal flag is true or the timeout passed.
 func (s *StateReg) IsFinalizedAt(ts Timestamp) bool {
@@ -249,13 +336,40 @@ func ConvertToSignedChannel(req channel.AdjudicatorReq) (*SignedChannel, error)
 	}, nil
 }
 
-// SignWithdrawRequest generates a WithdrawReq and signs it with the given account to return a SignedWithdrawReq.
-func SignWithdrawRequest(acc wallet.Account, channel channel.ID, receiver AccountID) (*SignedWithdrawReq, error) {
+// SignPayout signs the payout of the funds of the given account in the given
+// channel of the given domain to receiver.
+func SignPayout(acc wallet.Account, domain Domain, channel channel.ID, receiver AccountID) (*Payout, error) {
+	swr, err := SignWithdrawRequest(acc, domain, channel, receiver)
+	if err != nil {
+		return nil, err
+	}
+	return &Payout{
+		Receiver: receiver,
+		Sig:      swr.Sig,
+	}, nil
+}
+
+// WithExpiry sets the expiry of a WithdrawReq. The request is rejected after
+// the given time.
+func WithExpiry(expiry Timestamp) WithdrawReqOpt {
+	return func(wr *WithdrawReq) {
+		wr.Expiry = &expiry
+	}
+}
+
+// SignWithdrawRequest generates a WithdrawReq in the given domain and signs it with the given account to return a
+// SignedWithdrawReq.
+func SignWithdrawRequest(acc wallet.Account, domain Domain, channel channel.ID, receiver AccountID,
+	opts ...WithdrawReqOpt) (*SignedWithdrawReq, error) {
 	req := WithdrawReq{
+		Domain:   domain,
 		ID:       channel,
 		Part:     acc.Address(),
 		Receiver: receiver,
 	}
+	for _, opt := range opts {
+		opt(&req)
+	}
 
 	sig, err := req.Sign(acc)
 	if err != nil {
@@ -268,32 +382,45 @@ func SignWithdrawRequest(acc wallet.Account, channel channel.ID, receiver Accoun
 	}, nil
 }
 
+// IsExpiredAt checks if the withdraw request has an expiry that passed at ts.
+func (wr WithdrawReq) IsExpiredAt(ts Timestamp) bool {
+	return wr.Expiry != nil && ts.After(*wr.Expiry)
+}
+
 // MarshalJSON implements custom marshalling for WithdrawReq to deal with custom data types.
 func (wr *WithdrawReq) MarshalJSON() ([]byte, error) {
 	var wrj struct {
+		Domain   Domain         `json:"domain"`
 		ID       channel.ID     `json:"id"`
 		Part     wallet.Address `json:"part"`
 		Receiver string         `json:"receiver"`
+		Expiry   *Timestamp     `json:"expiry,omitempty"`
 	}
+	wrj.Domain = wr.Domain
 	wrj.ID = wr.ID
 	wrj.Part = wr.Part
 	wrj.Receiver = string(wr.Receiver)
+	wrj.Expiry = wr.Expiry
 	return json.Marshal(wrj)
 }
 
 // UnmarshalJSON implements custom unmarshalling for WithdrawReq to deal with custom data types.
 func (wr *WithdrawReq) UnmarshalJSON(data []byte) error {
 	var wrj struct {
+		Domain   Domain          `json:"domain"`
 		ID       channel.ID      `json:"id"`
 		Part     json.RawMessage `json:"part"`
 		Receiver string          `json:"receiver"`
+		Expiry   *Timestamp      `json:"expiry,omitempty"`
 	}
 	if err := json.Unmarshal(data, &wrj); err != nil {
 		return err
 	}
 
+	wr.Domain = wrj.Domain
 	wr.ID = wrj.ID
 	wr.Receiver = AccountID(wrj.Receiver)
+	wr.Expiry = wrj.Expiry
 
 	part := wallet.NewAddress()
 	parti := part.(interface{}) //nolint:forcetypeassert
@@ -325,13 +452,31 @@ func (swr SignedWithdrawReq) Verify(addr wallet.Address) (bool, error) {
 }
 
 // Encode encodes a withdraw request into an `io.Writer` or returns an `error`.
+// A missing expiry is encoded as zero.
 func (wr WithdrawReq) Encode(w io.Writer) error {
+	var expiry int64
+	if wr.Expiry != nil {
+		expiry = wr.Expiry.Time().UnixNano()
+	}
 	return errors.WithMessage(
-		perunio.Encode(w, wr.ID, wr.Part, string(wr.Receiver)), "WithdrawReq encode")
+		perunio.Encode(w, wr.Domain, wr.ID, wr.Part, string(wr.Receiver), expiry), "WithdrawReq encode")
 }
 
 // Decode decodes a withdraw request from an `io.Reader` or returns an `error`.
-func (wr WithdrawReq) Decode(r io.Reader) error {
-	return errors.WithMessage(
-		perunio.Decode(r, &wr.ID, &wr.Part, &wr.Receiver), "WithdrawReq decode")
+func (wr *WithdrawReq) Decode(r io.Reader) error {
+	var (
+		receiver string
+		expiry   int64
+	)
+	wr.Part = wallet.NewAddress()
+	if err := perunio.Decode(r, &wr.Domain, &wr.ID, wr.Part, &receiver, &expiry); err != nil {
+		return errors.WithMessage(err, "WithdrawReq decode")
+	}
+	wr.Receiver = AccountID(receiver)
+	wr.Expiry = nil
+	if expiry != 0 {
+		ts := Timestamp(time.Unix(0, expiry))
+		wr.Expiry = &ts
+	}
+	return nil
 }
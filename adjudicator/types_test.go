@@ -15,10 +15,12 @@
 package adjudicator_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"github.com/perun-network/perun-fabric/wallet"
 	"perun.network/go-perun/channel"
 	"testing"
+	"time"
 
 	"github.com/go-test/deep"
 	"github.com/stretchr/testify/require"
@@ -29,6 +31,8 @@ import (
 	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
 )
 
+var testDomain = adj.NewDomain("someChannel", "someAdjudicator")
+
 func TestStateRegJSONMarshaling(t *testing.T) {
 	rng := test.Prng(t)
 	sr := adjtest.RandomStateReg(rng)
@@ -39,6 +43,25 @@ func TestStateRegJSONMarshaling(t *testing.T) {
 	require.Zero(t, deep.Equal(sr, sr1))
 }
 
+func TestPayoutsJSONMarshaling(t *testing.T) {
+	s := adjtest.NewSetup(test.Prng(t), adjtest.WithPayouts("payout0", "payout1"))
+	data, err := json.Marshal(s.Params)
+	require.NoError(t, err)
+	params := new(adj.Params)
+	require.NoError(t, json.Unmarshal(data, params))
+	require.Zero(t, deep.Equal(s.Params, params))
+	require.Zero(t, deep.Equal(*s.Params, params.Clone()))
+
+	sr := s.StateReg()
+	sr.Payouts = []adj.SignedWithdrawReq{*s.SignedWithdrawReq(0), *s.SignedWithdrawReq(1)}
+	data, err = json.Marshal(sr)
+	require.NoError(t, err)
+	sr1 := new(adj.StateReg)
+	require.NoError(t, json.Unmarshal(data, sr1))
+	require.Zero(t, deep.Equal(sr, sr1))
+	require.Zero(t, deep.Equal(sr, sr.Clone()))
+}
+
 func TestStateSigning(t *testing.T) {
 	rng := test.Prng(t)
 	state := adjtest.RandomState(rng)
@@ -53,7 +76,7 @@ func TestStateSigning(t *testing.T) {
 func TestSignedWithdrawRequestJSONMarshaling(t *testing.T) {
 	rng := test.Prng(t)
 	acc := wallet.NewRandomAccount(rng)
-	req, err := adj.SignWithdrawRequest(acc, channel.ID{1}, "someReceiverID")
+	req, err := adj.SignWithdrawRequest(acc, testDomain, channel.ID{1}, "someReceiverID")
 	require.NoError(t, err)
 	data, err := json.Marshal(req)
 	require.NoError(t, err)
@@ -65,7 +88,7 @@ func TestSignedWithdrawRequestJSONMarshaling(t *testing.T) {
 func TestWithdrawRequestSigning(t *testing.T) {
 	rng := test.Prng(t)
 	acc := wallet.NewRandomAccount(rng)
-	req, err := adj.SignWithdrawRequest(acc, channel.ID{1}, "someReceiverID")
+	req, err := adj.SignWithdrawRequest(acc, testDomain, channel.ID{1}, "someReceiverID")
 	require.NoError(t, err)
 	verify, err := req.Verify(acc.Address())
 	require.NoError(t, err)
@@ -75,10 +98,60 @@ func TestWithdrawRequestSigning(t *testing.T) {
 func TestWithdrawRequestSigningInvalid(t *testing.T) {
 	rng := test.Prng(t)
 	acc := wallet.NewRandomAccount(rng)
-	req, err := adj.SignWithdrawRequest(acc, channel.ID{1}, "someReceiverID")
+	req, err := adj.SignWithdrawRequest(acc, testDomain, channel.ID{1}, "someReceiverID")
 	require.NoError(t, err)
 	acc1 := wallet.NewRandomAccount(rng)
 	verify, err := req.Verify(acc1.Address())
 	require.NoError(t, err)
 	require.False(t, verify)
 }
+
+func TestWithdrawRequestExpiryJSONMarshaling(t *testing.T) {
+	rng := test.Prng(t)
+	acc := wallet.NewRandomAccount(rng)
+	expiry := adj.Timestamp(time.Unix(0, rng.Int63()).UTC())
+	req, err := adj.SignWithdrawRequest(acc, testDomain, channel.ID{1}, "someReceiverID", adj.WithExpiry(expiry))
+	require.NoError(t, err)
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	req1 := new(adj.SignedWithdrawReq)
+	require.NoError(t, json.Unmarshal(data, req1))
+	require.Zero(t, deep.Equal(req, req1))
+	verify, err := req1.Verify(acc.Address())
+	require.NoError(t, err)
+	require.True(t, verify)
+}
+
+func TestWithdrawRequestEncoding(t *testing.T) {
+	rng := test.Prng(t)
+	acc := wallet.NewRandomAccount(rng)
+	expiry := adj.Timestamp(time.Unix(0, rng.Int63()))
+	for _, opts := range [][]adj.WithdrawReqOpt{nil, {adj.WithExpiry(expiry)}} {
+		req, err := adj.SignWithdrawRequest(acc, testDomain, channel.ID{1}, "someReceiverID", opts...)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, req.Req.Encode(&buf))
+		var req1 adj.WithdrawReq
+		require.NoError(t, req1.Decode(&buf))
+		require.Equal(t, req.Req.Domain, req1.Domain)
+		require.Equal(t, req.Req.ID, req1.ID)
+		require.True(t, req.Req.Part.Equal(req1.Part))
+		require.Equal(t, req.Req.Receiver, req1.Receiver)
+		if req.Req.Expiry == nil {
+			require.Nil(t, req1.Expiry)
+		} else {
+			require.True(t, req.Req.Expiry.Equal(*req1.Expiry))
+		}
+	}
+}
+
+func TestWithdrawRequestSigningDomain(t *testing.T) {
+	rng := test.Prng(t)
+	acc := wallet.NewRandomAccount(rng)
+	req, err := adj.SignWithdrawRequest(acc, testDomain, channel.ID{1}, "someReceiverID")
+	require.NoError(t, err)
+	req.Req.Domain.Chaincode = "otherAdjudicator"
+	verify, err := req.Verify(acc.Address())
+	require.NoError(t, err)
+	require.False(t, verify)
+}
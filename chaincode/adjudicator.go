@@ -17,10 +17,15 @@ package chaincode
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/hyperledger/fabric-contract-api-go/contractapi"
-	adj "github.com/perun-network/perun-fabric/adjudicator"
 	"math/big"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
 	"perun.network/go-perun/channel"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
 )
 
 // Adjudicator is the chaincode that implements the adjudicator.
@@ -28,14 +33,55 @@ type Adjudicator struct {
 	contractapi.Contract
 }
 
-func (Adjudicator) contract(ctx contractapi.TransactionContextInterface) *adj.Adjudicator {
-	return adj.NewAdjudicator(ctx.GetStub().GetChannelID(), NewStubLedger(ctx), NewStubAsset(ctx))
+// contract returns the adjudicator in the domain of the transaction.
+func (Adjudicator) contract(ctx contractapi.TransactionContextInterface) (*adj.Adjudicator, error) {
+	stub := ctx.GetStub()
+	domain, err := StubDomain(stub)
+	if err != nil {
+		return nil, fmt.Errorf("determining adjudicator domain: %w", err)
+	}
+	return adj.NewAdjudicator(stub.GetChannelID(), domain, NewStubLedger(ctx), NewStubAsset(ctx)), nil
+}
+
+// addressBook returns the address book in the domain of the transaction.
+func (Adjudicator) addressBook(ctx contractapi.TransactionContextInterface) (*adj.AddressBook, error) {
+	domain, err := StubDomain(ctx.GetStub())
+	if err != nil {
+		return nil, fmt.Errorf("determining adjudicator domain: %w", err)
+	}
+	return adj.NewAddressBook(domain, NewStubLedger(ctx)), nil
+}
+
+// StubDomain returns the adjudicator domain of the transaction, consisting of
+// the Fabric channel and the name of the invoked chaincode.
+func StubDomain(stub shim.ChaincodeStubInterface) (adj.Domain, error) {
+	sp, err := stub.GetSignedProposal()
+	if err != nil {
+		return adj.Domain{}, fmt.Errorf("stub.GetSignedProposal: %w", err)
+	}
+	var prop peer.Proposal
+	if err := proto.Unmarshal(sp.GetProposalBytes(), &prop); err != nil {
+		return adj.Domain{}, fmt.Errorf("unmarshaling proposal: %w", err)
+	}
+	var payload peer.ChaincodeProposalPayload
+	if err := proto.Unmarshal(prop.GetPayload(), &payload); err != nil {
+		return adj.Domain{}, fmt.Errorf("unmarshaling proposal payload: %w", err)
+	}
+	var spec peer.ChaincodeInvocationSpec
+	if err := proto.Unmarshal(payload.GetInput(), &spec); err != nil {
+		return adj.Domain{}, fmt.Errorf("unmarshaling invocation spec: %w", err)
+	}
+	name := spec.GetChaincodeSpec().GetChaincodeId().GetName()
+	if name == "" {
+		return adj.Domain{}, fmt.Errorf("missing chaincode name")
+	}
+	return adj.NewDomain(stub.GetChannelID(), name), nil
 }
 
 // Deposit unmarshalls the given arguments to forward the deposit request.
 func (a *Adjudicator) Deposit(ctx contractapi.TransactionContextInterface,
 	chID channel.ID, partStr string, amountStr string) error {
-	calleeID, err := ctx.GetClientIdentity().GetID()
+	calleeID, err := CallerID(ctx)
 	if err != nil {
 		return err
 	}
@@ -50,7 +96,11 @@ func (a *Adjudicator) Deposit(ctx contractapi.TransactionContextInterface,
 		return err
 	}
 
-	return a.contract(ctx).Deposit(adj.AccountID(calleeID), chID, part, amount)
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return err
+	}
+	return contract.Deposit(calleeID, chID, part, amount)
 }
 
 // Holding unmarshalls the given arguments to forward the holding request.
@@ -61,7 +111,11 @@ func (a *Adjudicator) Holding(ctx contractapi.TransactionContextInterface,
 	if err != nil {
 		return "", err
 	}
-	return stringWithErr(a.contract(ctx).Holding(id, part))
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return "", err
+	}
+	return stringWithErr(contract.Holding(id, part))
 }
 
 // TotalHolding unmarshalls the given arguments to forward the total holding request.
@@ -72,7 +126,11 @@ func (a *Adjudicator) TotalHolding(ctx contractapi.TransactionContextInterface,
 	if err != nil {
 		return "", err
 	}
-	return stringWithErr(a.contract(ctx).TotalHolding(id, parts))
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return "", err
+	}
+	return stringWithErr(contract.TotalHolding(id, parts))
 }
 
 // Register unmarshalls the given argument to forward the register request.
@@ -82,14 +140,22 @@ func (a *Adjudicator) Register(ctx contractapi.TransactionContextInterface,
 	if err := json.Unmarshal([]byte(chStr), &ch); err != nil {
 		return err
 	}
-	return a.contract(ctx).Register(&ch)
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return err
+	}
+	return contract.Register(&ch)
 }
 
 // StateReg unmarshalls the given argument to forward the state reg request.
 // It returns the retrieved state reg marshalled as string.
 func (a *Adjudicator) StateReg(ctx contractapi.TransactionContextInterface,
 	id channel.ID) (string, error) {
-	reg, err := a.contract(ctx).StateReg(id)
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return "", err
+	}
+	reg, err := contract.StateReg(id)
 	if err != nil {
 		return "", err
 	}
@@ -97,6 +163,27 @@ func (a *Adjudicator) StateReg(ctx contractapi.TransactionContextInterface,
 	return string(regJSON), err
 }
 
+// StateRegs unmarshalls the given argument to forward the multi-channel state
+// reg request. It returns the retrieved state regs, in the order of the given
+// channel ids, marshalled as string. Unknown channels are null.
+func (a *Adjudicator) StateRegs(ctx contractapi.TransactionContextInterface,
+	idsStr string) (string, error) {
+	var ids []channel.ID
+	if err := json.Unmarshal([]byte(idsStr), &ids); err != nil {
+		return "", err
+	}
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return "", err
+	}
+	regs, err := contract.StateRegs(ids)
+	if err != nil {
+		return "", err
+	}
+	regsJSON, err := json.Marshal(regs)
+	return string(regsJSON), err
+}
+
 // Withdraw unmarshalls the given argument to forward the withdrawal request.
 // It returns the withdrawal amount as a marshalled (string) *big.Int.
 func (a *Adjudicator) Withdraw(ctx contractapi.TransactionContextInterface,
@@ -105,14 +192,78 @@ func (a *Adjudicator) Withdraw(ctx contractapi.TransactionContextInterface,
 	if err := json.Unmarshal([]byte(reqStr), &req); err != nil {
 		return "", err
 	}
-	return stringWithErr(a.contract(ctx).Withdraw(req))
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return "", err
+	}
+	return stringWithErr(contract.Withdraw(req))
+}
+
+// Payout forwards the payout request of the given channel.
+// It returns the withdrawn amounts as a marshalled (string) []*big.Int.
+func (a *Adjudicator) Payout(ctx contractapi.TransactionContextInterface,
+	id channel.ID) (string, error) {
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return "", err
+	}
+	amounts, err := contract.Payout(id)
+	if err != nil {
+		return "", err
+	}
+	amountsJSON, err := json.Marshal(amounts)
+	return string(amountsJSON), err
+}
+
+// WithdrawBatch unmarshalls the given argument to forward the batch withdrawal request.
+// It returns the withdrawal results as a marshalled (string) []adj.WithdrawResult.
+func (a *Adjudicator) WithdrawBatch(ctx contractapi.TransactionContextInterface,
+	reqsStr string) (string, error) {
+	var reqs []adj.SignedWithdrawReq
+	if err := json.Unmarshal([]byte(reqsStr), &reqs); err != nil {
+		return "", err
+	}
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return "", err
+	}
+	results, err := contract.WithdrawBatch(reqs)
+	if err != nil {
+		return "", err
+	}
+	resultsJSON, err := json.Marshal(results)
+	return string(resultsJSON), err
+}
+
+// ConcludeFinal unmarshalls the given arguments to forward the conclude final request.
+// It returns the withdrawn amounts as a marshalled (string) []*big.Int.
+func (a *Adjudicator) ConcludeFinal(ctx contractapi.TransactionContextInterface,
+	chStr string, reqsStr string) (string, error) {
+	var ch adj.SignedChannel
+	if err := json.Unmarshal([]byte(chStr), &ch); err != nil {
+		return "", err
+	}
+	var reqs []adj.SignedWithdrawReq
+	if err := json.Unmarshal([]byte(reqsStr), &reqs); err != nil {
+		return "", err
+	}
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return "", err
+	}
+	amounts, err := contract.ConcludeFinal(&ch, reqs)
+	if err != nil {
+		return "", err
+	}
+	amountsJSON, err := json.Marshal(amounts)
+	return string(amountsJSON), err
 }
 
 // MintToken unmarshalls the given argument to forward the minting request.
 // The callee is derived from the transaction context.
 func (a *Adjudicator) MintToken(ctx contractapi.TransactionContextInterface,
 	amountStr string) error {
-	calleeID, err := ctx.GetClientIdentity().GetID()
+	calleeID, err := CallerID(ctx)
 	if err != nil {
 		return err
 	}
@@ -122,18 +273,18 @@ func (a *Adjudicator) MintToken(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("parsing big.Int string %q failed", amountStr)
 	}
 
-	err = a.contract(ctx).Mint(adj.AccountID(calleeID), amount)
+	contract, err := a.contract(ctx)
 	if err != nil {
 		return err
 	}
-	return nil
+	return contract.Mint(calleeID, amount)
 }
 
 // BurnToken unmarshalls the given argument to forward the burning request.
 // The callee is derived from the transaction context.
 func (a *Adjudicator) BurnToken(ctx contractapi.TransactionContextInterface,
 	amountStr string) error {
-	calleeID, err := ctx.GetClientIdentity().GetID()
+	calleeID, err := CallerID(ctx)
 	if err != nil {
 		return err
 	}
@@ -143,18 +294,18 @@ func (a *Adjudicator) BurnToken(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("parsing big.Int string %q failed", amountStr)
 	}
 
-	err = a.contract(ctx).Burn(adj.AccountID(calleeID), amount)
+	contract, err := a.contract(ctx)
 	if err != nil {
 		return err
 	}
-	return nil
+	return contract.Burn(calleeID, amount)
 }
 
 // TransferToken unmarshalls the given arguments to forward the token transfer request.
 // The sender of the tokens is derived from the transaction context.
 func (a *Adjudicator) TransferToken(ctx contractapi.TransactionContextInterface,
 	receiverStr string, amountStr string) error {
-	calleeID, err := ctx.GetClientIdentity().GetID()
+	calleeID, err := CallerID(ctx)
 	if err != nil {
 		return err
 	}
@@ -169,11 +320,11 @@ func (a *Adjudicator) TransferToken(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("parsing big.Int string %q failed", amountStr)
 	}
 
-	err = a.contract(ctx).Transfer(adj.AccountID(calleeID), receiverID, amount)
+	contract, err := a.contract(ctx)
 	if err != nil {
 		return err
 	}
-	return nil
+	return contract.Transfer(calleeID, receiverID, amount)
 }
 
 // TokenBalance unmarshalls the given argument to forward the token balance request.
@@ -184,5 +335,60 @@ func (a *Adjudicator) TokenBalance(ctx contractapi.TransactionContextInterface,
 	if err != nil {
 		return "", err
 	}
-	return stringWithErr(a.contract(ctx).BalanceOfID(idToCheck))
+	contract, err := a.contract(ctx)
+	if err != nil {
+		return "", err
+	}
+	return stringWithErr(contract.BalanceOfID(idToCheck))
+}
+
+// RegisterAddress unmarshalls the given argument to forward the address book
+// registration. The registering identity is derived from the transaction
+// context.
+func (a *Adjudicator) RegisterAddress(ctx contractapi.TransactionContextInterface,
+	regStr string) error {
+	calleeID, err := CallerID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reg adj.SignedRegistration
+	if err := json.Unmarshal([]byte(regStr), &reg); err != nil {
+		return err
+	}
+	book, err := a.addressBook(ctx)
+	if err != nil {
+		return err
+	}
+	return book.Register(calleeID, &reg)
+}
+
+// RegistrationByAccountID unmarshalls the given argument to forward the address
+// book lookup. It returns the registration marshalled as string.
+func (a *Adjudicator) RegistrationByAccountID(ctx contractapi.TransactionContextInterface,
+	id string) (string, error) {
+	idToCheck, err := UnmarshalID(id)
+	if err != nil {
+		return "", err
+	}
+	book, err := a.addressBook(ctx)
+	if err != nil {
+		return "", err
+	}
+	return jsonWithErr(book.ByAccountID(idToCheck))
+}
+
+// RegistrationByAddress unmarshalls the given argument to forward the address
+// book lookup. It returns the registration marshalled as string.
+func (a *Adjudicator) RegistrationByAddress(ctx contractapi.TransactionContextInterface,
+	addrStr string) (string, error) {
+	addr, err := UnmarshalAddress(addrStr)
+	if err != nil {
+		return "", err
+	}
+	book, err := a.addressBook(ctx)
+	if err != nil {
+		return "", err
+	}
+	return jsonWithErr(book.ByAddress(addr))
 }
@@ -0,0 +1,220 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chaincode_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/stretchr/testify/require"
+	pchannel "perun.network/go-perun/channel"
+	"polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/chaincode"
+	chtest "github.com/perun-network/perun-fabric/channel/test"
+)
+
+// txStub is a MockStub that, like a Fabric peer, does not let a transaction
+// read its own writes. The writes of a transaction are applied on commit.
+type txStub struct {
+	*shimtest.MockStub
+	writes    map[string][]byte
+	rewritten []string
+}
+
+func newTxStub() *txStub {
+	return &txStub{MockStub: shimtest.NewMockStub("adjudicator", nil)}
+}
+
+// PutState buffers the write until the transaction is committed.
+func (s *txStub) PutState(key string, value []byte) error {
+	if _, ok := s.writes[key]; ok {
+		s.rewritten = append(s.rewritten, key)
+	}
+	s.writes[key] = value
+	return nil
+}
+
+// transact runs f in a transaction and commits its writes. It fails the test
+// if f fails or if a key is written more than once.
+func (s *txStub) transact(t *testing.T, f func() error) {
+	t.Helper()
+	s.writes, s.rewritten = make(map[string][]byte), nil
+	s.MockTransactionStart("tx")
+	defer s.MockTransactionEnd("tx")
+
+	require.NoError(t, f())
+	require.Empty(t, s.rewritten, "keys written more than once")
+	for key, value := range s.writes {
+		require.NoError(t, s.MockStub.PutState(key, value))
+	}
+}
+
+// newStubAdjudicator returns an Adjudicator of the setup's domain on the given
+// stub, on which the setup's channel is funded.
+func newStubAdjudicator(t *testing.T, s *adjtest.Setup, stub *txStub) *adj.Adjudicator {
+	t.Helper()
+	a := adj.NewAdjudicator(chtest.AdjudicatorName, s.Domain, &chaincode.StubLedger{Stub: stub},
+		chaincode.StubAsset{Stub: stub})
+	fund(t, a, s, stub)
+	return a
+}
+
+// fund funds the setup's channel. The deposits of the participants are made
+// in separate transactions and are swapped with respect to the setup's
+// balances.
+func fund(t *testing.T, a *adj.Adjudicator, s *adjtest.Setup, stub *txStub) {
+	t.Helper()
+	for i, part := range s.Parts {
+		deposit := s.State.Balances[len(s.Parts)-1-i]
+		stub.State[string(s.IDs[i])] = deposit.Bytes()
+		stub.transact(t, func() error { return a.Deposit(s.IDs[i], s.State.ID, part, deposit) })
+	}
+}
+
+func requireTokenBalance(t *testing.T, a *adj.Adjudicator, id adj.AccountID, expected *big.Int) {
+	t.Helper()
+	bal, err := a.BalanceOfID(id)
+	require.NoError(t, err)
+	require.Zero(t, bal.Cmp(expected), "token balance of %s: expected %v, got %v", id, expected, bal)
+}
+
+func TestAdjudicator_ConcludeFinal(t *testing.T) {
+	rng := test.Prng(t)
+
+	t.Run("all", func(t *testing.T) {
+		s := adjtest.NewSetup(rng, adjtest.WithFinalState)
+		stub := newTxStub()
+		a := newStubAdjudicator(t, s, stub)
+
+		reqs := []adj.SignedWithdrawReq{*s.SignedWithdrawReq(0), *s.SignedWithdrawReq(1)}
+		stub.transact(t, func() error {
+			withdrawn, err := a.ConcludeFinal(s.SignedChannel(), reqs)
+			require.Equal(t, []*big.Int(s.State.Balances), withdrawn)
+			return err
+		})
+
+		for i, id := range s.IDs {
+			requireTokenBalance(t, a, id, s.State.Balances[i])
+		}
+		requireTokenBalance(t, a, chtest.AdjudicatorName, new(big.Int))
+		reg, err := a.StateReg(s.State.ID)
+		require.NoError(t, err)
+		require.True(t, reg.IsFinal)
+	})
+
+	t.Run("separately", func(t *testing.T) {
+		s := adjtest.NewSetup(rng, adjtest.WithFinalState)
+		stub := newTxStub()
+		a := newStubAdjudicator(t, s, stub)
+
+		for i := range s.Parts {
+			reqs := []adj.SignedWithdrawReq{*s.SignedWithdrawReq(i)}
+			stub.transact(t, func() error {
+				withdrawn, err := a.ConcludeFinal(s.SignedChannel(), reqs)
+				require.Equal(t, []*big.Int{s.State.Balances[i]}, withdrawn)
+				return err
+			})
+			requireTokenBalance(t, a, s.IDs[i], s.State.Balances[i])
+		}
+		requireTokenBalance(t, a, chtest.AdjudicatorName, new(big.Int))
+	})
+}
+
+func TestAdjudicator_WithdrawBatch(t *testing.T) {
+	rng := test.Prng(t)
+	s := adjtest.NewSetup(rng, adjtest.WithFinalState)
+	stub := newTxStub()
+	a := newStubAdjudicator(t, s, stub)
+	stub.transact(t, func() error { return a.Register(s.SignedChannel()) })
+	id0, bals0 := s.State.ID, s.State.Balances
+
+	// Open, fund and finalize a second channel between the same participants.
+	s.Params.Nonce = new(big.Int).SetUint64(rng.Uint64())
+	s.State.ID = s.Domain.CalcID(s.Params.CoreParams())
+	s.State.Balances = []*big.Int{big.NewInt(10), big.NewInt(20)}
+	fund(t, a, s, stub)
+	stub.transact(t, func() error { return a.Register(s.SignedChannel()) })
+	id1, bals1 := s.State.ID, s.State.Balances
+
+	// All funds are withdrawn to the same receiver, e.g., an operator.
+	receiver := adj.AccountID("operator")
+	var reqs []adj.SignedWithdrawReq
+	for _, id := range []pchannel.ID{id0, id1} {
+		for i := range s.Parts {
+			req, err := adj.SignWithdrawRequest(s.Accs[i], s.Domain, id, receiver)
+			require.NoError(t, err)
+			reqs = append(reqs, *req)
+		}
+	}
+	// Repeated request.
+	reqs = append(reqs, reqs[0])
+
+	stub.transact(t, func() error {
+		results, err := a.WithdrawBatch(reqs)
+		require.NoError(t, err)
+		for i, bal := range append(bals0, bals1...) {
+			require.Empty(t, results[i].Error)
+			require.Equal(t, bal, results[i].Amount)
+		}
+		require.Nil(t, results[len(reqs)-1].Amount)
+		require.NotEmpty(t, results[len(reqs)-1].Error)
+		return err
+	})
+
+	total := new(big.Int)
+	for _, bal := range append(bals0, bals1...) {
+		total.Add(total, bal)
+	}
+	requireTokenBalance(t, a, receiver, total)
+	requireTokenBalance(t, a, chtest.AdjudicatorName, new(big.Int))
+}
+
+func TestAdjudicator_Payout(t *testing.T) {
+	receivers := []adj.AccountID{"payout0", "payout1"}
+	s := adjtest.NewSetup(test.Prng(t), adjtest.WithFinalState, adjtest.WithPayouts(receivers...))
+	stub := newTxStub()
+	a := newStubAdjudicator(t, s, stub)
+	stub.transact(t, func() error { return a.Register(s.SignedChannel()) })
+
+	stub.transact(t, func() error {
+		withdrawn, err := a.Payout(s.State.ID)
+		require.Equal(t, []*big.Int(s.State.Balances), withdrawn)
+		return err
+	})
+
+	for i, receiver := range receivers {
+		requireTokenBalance(t, a, receiver, s.State.Balances[i])
+	}
+	requireTokenBalance(t, a, chtest.AdjudicatorName, new(big.Int))
+}
+
+func TestAdjudicator_UnknownDomain(t *testing.T) {
+	// The mock stub has no signed proposal, so the domain cannot be determined.
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(shimtest.NewMockStub("adjudicator", nil))
+	var cc chaincode.Adjudicator
+
+	require.NotPanics(t, func() {
+		_, err := cc.StateReg(ctx, pchannel.ID{})
+		require.Error(t, err)
+		_, err = cc.StateRegs(ctx, "[]")
+		require.Error(t, err)
+	})
+}
@@ -126,6 +126,54 @@ func (s StubAsset) Transfer(sender adj.AccountID, receiver adj.AccountID, amount
 	return nil
 }
 
+// TransferBatch checks if the proposed payments are valid and transfers them
+// from the sender to their receivers. Every balance is read and written at
+// most once, as the stub does not return the writes of the own transaction.
+// The sender must be the callee of the transaction invoking TransferBatch.
+func (s StubAsset) TransferBatch(sender adj.AccountID, payments []adj.Payment) error {
+	total, err := adj.CheckPayments(payments)
+	if err != nil {
+		return err
+	}
+
+	// Check balance of sender.
+	senderBal, err := s.BalanceOf(sender)
+	if err != nil {
+		return err
+	}
+	if senderBal.Cmp(total) < 0 {
+		return fmt.Errorf("not enought funds to transfer the requested amount")
+	}
+	senderBal.Sub(senderBal, total)
+
+	// Calc new balances. A payment to the sender only adds to its balance.
+	receiverBals := make([]*big.Int, len(payments))
+	for i, p := range payments {
+		if p.Receiver == sender {
+			senderBal.Add(senderBal, p.Amount)
+			continue
+		}
+		if receiverBals[i], err = s.BalanceOf(p.Receiver); err != nil {
+			return err
+		}
+		receiverBals[i].Add(receiverBals[i], p.Amount)
+	}
+
+	// Store new balances.
+	if err := s.Stub.PutState(string(sender), senderBal.Bytes()); err != nil {
+		return fmt.Errorf("stub.PutState: %w", err)
+	}
+	for i, p := range payments {
+		if receiverBals[i] == nil {
+			continue
+		}
+		if err := s.Stub.PutState(string(p.Receiver), receiverBals[i].Bytes()); err != nil {
+			return fmt.Errorf("stub.PutState: %w", err)
+		}
+	}
+	return nil
+}
+
 // BalanceOf returns the amount of tokens the given id holds.
 // If the id is unknown, zero is returned.
 func (s StubAsset) BalanceOf(id adj.AccountID) (*big.Int, error) {
@@ -0,0 +1,76 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chaincode
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/msp"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+// CallerID returns the AccountID of the identity that invoked the
+// transaction. For X.509 identities it is the id of the client identity. For
+// Idemix identities it is the adj.IdemixAccountID of the account key of the
+// adj.IdemixAuth in the transient data of the transaction.
+func CallerID(ctx contractapi.TransactionContextInterface) (adj.AccountID, error) {
+	creator, err := ctx.GetStub().GetCreator()
+	if err != nil {
+		return "", fmt.Errorf("stub.GetCreator: %w", err)
+	}
+	var sid msp.SerializedIdentity
+	if err := proto.Unmarshal(creator, &sid); err != nil {
+		return "", fmt.Errorf("unmarshaling creator: %w", err)
+	}
+
+	if block, _ := pem.Decode(sid.IdBytes); block != nil {
+		id, err := ctx.GetClientIdentity().GetID()
+		return adj.AccountID(id), err
+	}
+	return idemixAccountID(ctx, &sid, creator)
+}
+
+func idemixAccountID(ctx contractapi.TransactionContextInterface, sid *msp.SerializedIdentity,
+	creator []byte) (adj.AccountID, error) {
+	var idemix msp.SerializedIdemixIdentity
+	if err := proto.Unmarshal(sid.IdBytes, &idemix); err != nil {
+		return "", fmt.Errorf("unmarshaling idemix identity: %w", err)
+	} else if len(idemix.NymX) == 0 || len(idemix.NymY) == 0 {
+		return "", errors.New("idemix identity without pseudonym")
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("stub.GetTransient: %w", err)
+	}
+	authData, ok := transient[adj.IdemixAuthKey]
+	if !ok {
+		return "", errors.New("idemix identity without account authorization")
+	}
+	var auth adj.IdemixAuth
+	if err := json.Unmarshal(authData, &auth); err != nil {
+		return "", fmt.Errorf("unmarshaling idemix auth: %w", err)
+	}
+	if err := auth.Verify(creator); err != nil {
+		return "", err
+	}
+	return adj.IdemixAccountID(sid.Mspid, auth.Address), nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/stretchr/testify/require"
+	"polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/chaincode"
+	"github.com/perun-network/perun-fabric/client"
+	ctest "github.com/perun-network/perun-fabric/client/test"
+	"github.com/perun-network/perun-fabric/wallet"
+	"github.com/perun-network/perun-fabric/wire"
+)
+
+const testMSPID = "Org1MSP"
+
+func TestCallerID(t *testing.T) {
+	rng := test.Prng(t)
+
+	t.Run("X509", func(t *testing.T) {
+		cert, err := wire.SelfSignedCertificate(rng, wallet.NewRandomAccount(rng), "alice")
+		require.NoError(t, err)
+		id, err := identity.NewX509Identity(testMSPID, cert)
+		require.NoError(t, err)
+		creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: testMSPID, IdBytes: id.Credentials()})
+		require.NoError(t, err)
+		clientID, err := client.AccountIDFromIdentity(id)
+		require.NoError(t, err)
+		callerID, err := chaincode.CallerID(newIdentityContext(t, creator, nil))
+		require.NoError(t, err)
+		require.Equal(t, clientID, callerID)
+	})
+
+	t.Run("Idemix", func(t *testing.T) {
+		require := require.New(t)
+		acc := wallet.NewRandomAccount(rng)
+		issuer, err := ctest.NewIdemixIssuer(rng, testMSPID)
+		require.NoError(err)
+		user, err := issuer.NewUser(rng)
+		require.NoError(err)
+		// Each identity of the user presents another pseudonym.
+		var accountID adj.AccountID
+		var transients []map[string][]byte
+		var creators [][]byte
+		for i := 0; i < 2; i++ {
+			id, err := user.NewIdentity(rng)
+			require.NoError(err)
+			creator, err := id.Creator()
+			require.NoError(err)
+			clientID, transient, err := client.IdemixAccount(id, acc)
+			require.NoError(err)
+			callerID, err := chaincode.CallerID(newIdentityContext(t, creator, transient))
+			require.NoError(err)
+			require.Equal(clientID, callerID)
+			if i > 0 {
+				require.Equal(accountID, callerID, "stable across pseudonyms")
+			}
+			accountID = callerID
+			transients, creators = append(transients, transient), append(creators, creator)
+		}
+
+		_, err = chaincode.CallerID(newIdentityContext(t, creators[0], nil))
+		require.Error(err, "missing authorization")
+		_, err = chaincode.CallerID(newIdentityContext(t, creators[0], transients[1]))
+		require.Error(err, "authorization of other pseudonym")
+
+		id, err := user.NewIdentity(rng)
+		require.NoError(err)
+		_, err = client.AccountIDFromIdentity(id)
+		require.Error(err, "no X.509 identity")
+	})
+}
+
+// newIdentityContext returns a transaction context of the given creator with
+// the given transient data.
+func newIdentityContext(t *testing.T, creator []byte, transient map[string][]byte) *contractapi.TransactionContext {
+	t.Helper()
+	stub := shimtest.NewMockStub("adjudicator", nil)
+	stub.Creator = creator
+	stub.TransientMap = transient
+	ci, err := cid.New(stub)
+	require.NoError(t, err)
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(ci)
+	return ctx
+}
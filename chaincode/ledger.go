@@ -88,6 +88,60 @@ func (l *StubLedger) PutHolding(id channel.ID, addr wallet.Address, holding *big
 	return nil
 }
 
+// GetRegistration retrieves the address book registration of the given id.
+func (l *StubLedger) GetRegistration(id adj.AccountID) (*adj.SignedRegistration, error) { //nolint:forbidigo
+	key := RegistrationKey(id)
+	rb, err := l.Stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("stub.GetState: %w", err)
+	} else if rb == nil {
+		return nil, &adj.NotFoundError{Key: key, Type: "SignedRegistration"}
+	}
+
+	var r adj.SignedRegistration
+	return &r, json.Unmarshal(rb, &r)
+}
+
+// PutRegistration overwrites the address book registration of its AccountID.
+func (l *StubLedger) PutRegistration(r *adj.SignedRegistration) error {
+	rb, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+	if err := l.Stub.PutState(RegistrationKey(r.Reg.AccountID), rb); err != nil {
+		return fmt.Errorf("stub.PutState: %w", err)
+	}
+	return nil
+}
+
+// GetAddressOwner retrieves the AccountID that registered the given address.
+func (l *StubLedger) GetAddressOwner(addr wallet.Address) (adj.AccountID, error) { //nolint:forbidigo
+	key := AddressOwnerKey(addr)
+	id, err := l.Stub.GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("stub.GetState: %w", err)
+	} else if id == nil {
+		return "", &adj.NotFoundError{Key: key, Type: "AccountID"}
+	}
+	return adj.AccountID(id), nil
+}
+
+// PutAddressOwner sets the AccountID that registered the given address.
+func (l *StubLedger) PutAddressOwner(addr wallet.Address, id adj.AccountID) error {
+	if err := l.Stub.PutState(AddressOwnerKey(addr), []byte(id)); err != nil {
+		return fmt.Errorf("stub.PutState: %w", err)
+	}
+	return nil
+}
+
+// DeleteAddressOwner deletes the owner of the given address.
+func (l *StubLedger) DeleteAddressOwner(addr wallet.Address) error {
+	if err := l.Stub.DelState(AddressOwnerKey(addr)); err != nil {
+		return fmt.Errorf("stub.DelState: %w", err)
+	}
+	return nil
+}
+
 // maxNowDiff is the maximum allowed difference of a transaction's timestamp to
 // be considered the current block time.
 const maxNowDiff = 3 * time.Second
@@ -124,3 +178,15 @@ func StateRegKey(id channel.ID) string {
 func ChannelHoldingKey(id channel.ID, addr wallet.Address) string {
 	return orgPrefix + "ChannelHolding:" + adj.FundingKey(id, addr)
 }
+
+// RegistrationKey generates the key for storing address book registrations on
+// the stub.
+func RegistrationKey(id adj.AccountID) string {
+	return orgPrefix + "Registration:" + string(id)
+}
+
+// AddressOwnerKey generates the key for storing the owner of a registered
+// address on the stub.
+func AddressOwnerKey(addr wallet.Address) string {
+	return orgPrefix + "AddressOwner:" + adj.AddressKey(addr)
+}
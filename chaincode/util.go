@@ -31,6 +31,14 @@ func stringWithErr(s fmt.Stringer, err error) (string, error) {
 	return s.String(), nil
 }
 
+func jsonWithErr(v interface{}, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
 // UnmarshalID unmarshalls a fabric ID.
 func UnmarshalID(idStr string) (adj.AccountID, error) {
 	id := ""
@@ -20,20 +20,27 @@ import (
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 	adj "github.com/perun-network/perun-fabric/adjudicator"
 	"github.com/perun-network/perun-fabric/channel/binding"
-	fabclient "github.com/perun-network/perun-fabric/client"
 	"perun.network/go-perun/channel"
 	"time"
 )
 
 const (
 	defaultAdjPollingInterval = 1 * time.Second
+	defaultSubBatchSize       = 100
+	defaultSubQueryFailures   = 10
 )
 
 // Adjudicator provides methods for dispute resolution on the ledger.
 type Adjudicator struct {
-	binding  *binding.Adjudicator // binding gives access to the Adjudicator contract.
-	polling  time.Duration        // The polling interval for event subscription.
-	receiver adj.AccountID        // The fabric id of the receiver of the funds for withdrawal.
+	binding    Contract             // binding gives access to the Adjudicator contract.
+	domain     adj.Domain           // domain is the domain of the Adjudicator contract.
+	polling    time.Duration        // The polling interval for event subscription.
+	maxPolling time.Duration        // The maximal polling interval if no subscribed channel is disputed.
+	batchSize  int                  // The number of channels whose states are queried at once.
+	maxFails   int                  // The number of consecutive failed queries of a channel that end its subscriptions.
+	receiver   adj.AccountID        // The fabric id of the receiver of the funds for withdrawal.
+	clock      Clock                // The clock for timeouts and polling.
+	subs       *subscriptionManager // subs polls the states of all subscribed channels.
 }
 
 // AdjudicatorOpt allows to extend the Adjudicator constructor.
@@ -46,33 +53,111 @@ func WithSubPollingInterval(d time.Duration) AdjudicatorOpt {
 	}
 }
 
+// WithSubMaxPollingInterval lets the event subscriptions back off up to the
+// given polling interval while none of the subscribed channels is disputed.
+// By default, the polling interval stays constant.
+func WithSubMaxPollingInterval(d time.Duration) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.maxPolling = d
+	}
+}
+
+// WithSubBatchSize overwrites the number of channels whose states are queried
+// at once for the event subscriptions.
+func WithSubBatchSize(n int) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.batchSize = n
+	}
+}
+
+// WithSubMaxQueryFailures overwrites the number of consecutive failed state
+// queries of a channel after which its event subscriptions end with the
+// error. Failed queries are retried with backoff until then.
+func WithSubMaxQueryFailures(n int) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.maxFails = n
+	}
+}
+
+// WithAdjudicatorClock overwrites the clock of the Adjudicator, which
+// defaults to SystemClock. It is used for challenge timeouts and the polling of
+// the event subscriptions.
+func WithAdjudicatorClock(c Clock) AdjudicatorOpt {
+	return func(a *Adjudicator) {
+		a.clock = c
+	}
+}
+
 // NewAdjudicator generates an Adjudicator and requires to preset the fabric ID used for withdrawal.
+// Withdraw requests are signed in the domain of the given network and chaincode. Note that channel ids
+// are calculated in the domain set with SetDomain, which must therefore be the same. Otherwise,
+// Register and Withdraw fail, see CheckDomain.
 func NewAdjudicator(network *client.Network, chaincode string, withdrawTo adj.AccountID, opts ...AdjudicatorOpt) *Adjudicator {
+	return NewAdjudicatorWithContract(binding.NewAdjudicatorBinding(network, chaincode),
+		adj.NewDomain(network.Name(), chaincode), withdrawTo, opts...)
+}
+
+// NewAdjudicatorWithContract generates an Adjudicator on the given contract,
+// whose withdraw requests are signed in the given domain.
+func NewAdjudicatorWithContract(c Contract, domain adj.Domain, withdrawTo adj.AccountID, opts ...AdjudicatorOpt) *Adjudicator {
 	a := &Adjudicator{
-		binding:  binding.NewAdjudicatorBinding(network, chaincode),
-		polling:  defaultAdjPollingInterval,
-		receiver: withdrawTo,
+		binding:   c,
+		domain:    domain,
+		polling:   defaultAdjPollingInterval,
+		batchSize: defaultSubBatchSize,
+		maxFails:  defaultSubQueryFailures,
+		receiver:  withdrawTo,
+		clock:     SystemClock{},
 	}
 	for _, opt := range opts {
 		opt(a)
 	}
+	if a.maxPolling < a.polling {
+		a.maxPolling = a.polling
+	}
+	a.subs = newSubscriptionManager(a)
 	return a
 }
 
 // Register registers the given ledger channel state on-chain.
 // If the channel has locked funds into sub-channels, the corresponding
 // signed sub-channel states must be provided.
+// The payout of the requesting participant to the receiver of the Adjudicator
+// is bound to the channel, see signedChannel.
 func (a *Adjudicator) Register(ctx context.Context, req channel.AdjudicatorReq, subChannels []channel.SignedState) error {
 	if len(subChannels) > 0 {
 		return fmt.Errorf("subchannels not supported")
 	}
-	sigCh, err := adj.ConvertToSignedChannel(req)
+	if err := CheckDomain(a.domain); err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	sigCh, err := a.signedChannel(req)
 	if err != nil {
 		return fmt.Errorf("register: %w", err)
 	}
 	return a.binding.Register(sigCh)
 }
 
+// signedChannel converts the given request to a SignedChannel that binds the
+// payout of the requesting participant to the receiver of the Adjudicator. The
+// funds of the participant can then be paid out by anyone once the channel is
+// finalized, see adj.Adjudicator.Payout. A payout that is already bound to the
+// channel is not changed. Requests without account, e.g., refutations of the
+// watcher, bind no payout.
+func (a *Adjudicator) signedChannel(req channel.AdjudicatorReq) (*adj.SignedChannel, error) {
+	sigCh, err := adj.ConvertToSignedChannel(req)
+	if err != nil || req.Acc == nil {
+		return sigCh, err
+	}
+	payout, err := adj.SignPayout(req.Acc, a.domain, req.Tx.ID, a.receiver)
+	if err != nil {
+		return nil, fmt.Errorf("signing payout: %w", err)
+	}
+	sigCh.Params.Payouts = make([]adj.Payout, len(sigCh.Params.Parts))
+	sigCh.Params.Payouts[req.Idx] = *payout
+	return sigCh, nil
+}
+
 // Withdraw concludes and withdraws the registered state, so that the
 // final outcome is set on the asset holders and funds are withdrawn.
 // If the channel has locked funds in sub-channels, the states of the
@@ -81,36 +166,34 @@ func (a *Adjudicator) Withdraw(ctx context.Context, req channel.AdjudicatorReq,
 	if len(subStates) > 0 {
 		return fmt.Errorf("subchannels not supported")
 	}
+	if err := CheckDomain(a.domain); err != nil {
+		return fmt.Errorf("withdraw: %w", err)
+	}
 	channelID := req.Tx.ID
 
-	// For withdrawing there must be at least one registered state.
-	if req.Tx.IsFinal { //nolint:nestif
-		// Ensure there is a registered state.
-		err := a.ensureRegistered(ctx, req, nil)
-		if err != nil {
-			return err
-		}
-	} else {
-		// Dispute case: There must be a registered state.
-		reg, err := a.binding.StateReg(channelID)
-		if err != nil {
-			return err
-		}
-
-		if reg.Version != req.Tx.Version {
-			return fmt.Errorf("invalid adjudicator request")
-		}
-
-		timeout := MakeTimeout(reg.Timeout.Time(), a.polling)
-		err = timeout.Wait(ctx)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Concluded (or waited for challenge duration in case of dispute)
-	// Withdraw funds.
-	withdrawReq, err := adj.SignWithdrawRequest(req.Acc, channelID, a.receiver)
+	// A final state is registered and withdrawn in a single transaction.
+	if req.Tx.IsFinal {
+		return a.concludeFinal(req)
+	}
+
+	// Dispute case: There must be a registered state.
+	reg, err := a.binding.StateReg(channelID)
+	if err != nil {
+		return err
+	}
+
+	if reg.Version != req.Tx.Version {
+		return fmt.Errorf("invalid adjudicator request")
+	}
+
+	timeout := a.makeTimeout(reg.Timeout)
+	err = timeout.Wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Waited for challenge duration. Withdraw funds.
+	withdrawReq, err := adj.SignWithdrawRequest(req.Acc, a.domain, channelID, a.receiver)
 	if err != nil {
 		return err
 	}
@@ -121,6 +204,29 @@ func (a *Adjudicator) Withdraw(ctx context.Context, req channel.AdjudicatorReq,
 	return nil
 }
 
+// makeTimeout returns the timeout at the given ledger time, evaluated with the
+// clock of the Adjudicator.
+func (a *Adjudicator) makeTimeout(t adj.Timestamp) *Timeout {
+	return MakeTimeoutWithClock(t.Time(), a.polling, a.clock)
+}
+
+// concludeFinal registers the final state given in AdjudicatorReq and
+// withdraws the funds of the requesting participant in one transaction.
+// If the channel was already concluded by another participant, only the funds
+// are withdrawn.
+func (a *Adjudicator) concludeFinal(req channel.AdjudicatorReq) error {
+	sigCh, err := a.signedChannel(req)
+	if err != nil {
+		return fmt.Errorf("conclude final: %w", err)
+	}
+	withdrawReq, err := adj.SignWithdrawRequest(req.Acc, a.domain, req.Tx.ID, a.receiver)
+	if err != nil {
+		return err
+	}
+	_, err = a.binding.ConcludeFinal(sigCh, []adj.SignedWithdrawReq{*withdrawReq})
+	return err
+}
+
 // Progress progresses the state of a previously registered channel on-chain.
 // The signatures for the old state can be nil as the state is already
 // registered on the adjudicator.
@@ -132,6 +238,9 @@ func (a *Adjudicator) Progress(ctx context.Context, req channel.ProgressReq) err
 // The context should only be used to establish the subscription. The
 // framework will call Close on the subscription once the respective channel
 // controller shuts down.
+//
+// The states of all subscribed channels are queried together in batches, see
+// WithSubBatchSize.
 func (a *Adjudicator) Subscribe(ctx context.Context, ch channel.ID) (channel.AdjudicatorSubscription, error) {
 	sub, err := NewEventSubscription(a, ch)
 	if err != nil {
@@ -139,19 +248,3 @@ func (a *Adjudicator) Subscribe(ctx context.Context, ch channel.ID) (channel.Adj
 	}
 	return sub, nil
 }
-
-// ensureRegistered tries to register the state given in AdjudicatorReq.
-// If the channel is already registered or the registration is successful ensureRegisters returns nil.
-// Otherwise, an error is returned.
-func (a *Adjudicator) ensureRegistered(ctx context.Context, req channel.AdjudicatorReq, subChannels []channel.SignedState) error {
-	err := a.Register(ctx, req, subChannels)
-
-	// If an underfunded error is returned:
-	// Either the clients AdjudicatorReq contains a faulty balance which we do not expect at this point.
-	// Or the other party successfully called withdraw which lowered the total holdings in the channel.
-	// Hence, the other party must have had registered before.
-	if fabclient.IsUnderfundedErr(err) {
-		return nil
-	}
-	return err
-}
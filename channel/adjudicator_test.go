@@ -17,7 +17,9 @@ package channel_test
 import (
 	"context"
 	"fmt"
+	adj "github.com/perun-network/perun-fabric/adjudicator"
 	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/channel"
 	"github.com/perun-network/perun-fabric/channel/test"
 	requ "github.com/stretchr/testify/require"
 	"math/big"
@@ -289,3 +291,67 @@ func makeStateMapFromSignedStates(channels ...pchannel.SignedState) pchannel.Sta
 	}
 	return m
 }
+
+func TestAdjudicator_DomainMismatch(t *testing.T) {
+	require := requ.New(t)
+	setup := adjtest.NewSetup(ptest.Prng(t), adjtest.Funded)
+	setDomain(t, adj.NewDomain(test.ChannelName, "otherAdjudicator"))
+	contracts := test.NewMemContracts(setup.Adj)
+	contract := contracts.Contract(setup.IDs[0])
+	a := channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0])
+
+	req := pchannel.AdjudicatorReq{
+		Params: setup.Params.CoreParams(),
+		Acc:    setup.Accs[0],
+		Tx: pchannel.Transaction{
+			State: setup.State.CoreState(),
+			Sigs:  setup.SignedChannel().Sigs,
+		},
+	}
+	ctx := context.Background()
+	require.Error(a.Register(ctx, req, nil))
+	require.Error(a.Withdraw(ctx, req, nil))
+	_, err := contract.StateReg(setup.State.ID)
+	require.ErrorIs(err, adj.ErrUnknownChannel)
+
+	// The Funder must not deposit for a channel id of another domain.
+	require.NoError(contracts.Mint(setup.IDs[0], setup.State.Balances[0]))
+	holding, err := contract.TotalHolding(setup.State.ID, setup.Parts)
+	require.NoError(err)
+	f := channel.NewFunderWithContract(contract, setup.Domain)
+	require.Error(f.Fund(ctx, pchannel.FundingReq{
+		Params:    req.Params,
+		State:     req.Tx.State,
+		Idx:       0,
+		Agreement: req.Tx.State.Balances,
+	}))
+	after, err := contract.TotalHolding(setup.State.ID, setup.Parts)
+	require.NoError(err)
+	require.Zero(holding.Cmp(after))
+}
+
+func TestAdjudicator_BindsPayout(t *testing.T) {
+	require := requ.New(t)
+	setup := adjtest.NewSetup(ptest.Prng(t), adjtest.Funded)
+	setDomain(t, setup.Domain)
+	contract := test.NewMemContracts(setup.Adj).Contract(setup.IDs[0])
+	receiver := adj.AccountID("payout0")
+	a := channel.NewAdjudicatorWithContract(contract, setup.Domain, receiver)
+
+	req := pchannel.AdjudicatorReq{
+		Params: setup.Params.CoreParams(),
+		Acc:    setup.Accs[0],
+		Idx:    0,
+		Tx: pchannel.Transaction{
+			State: setup.State.CoreState(),
+			Sigs:  setup.SignedChannel().Sigs,
+		},
+	}
+	require.NoError(a.Register(context.Background(), req, nil))
+
+	reg, err := contract.StateReg(setup.State.ID)
+	require.NoError(err)
+	require.Len(reg.Payouts, 1)
+	require.Equal(receiver, reg.Receiver(setup.SignedWithdrawReq(0).Req))
+	require.Equal(setup.IDs[1], reg.Receiver(setup.SignedWithdrawReq(1).Req))
+}
@@ -16,30 +16,59 @@ package channel
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"fmt"
 	"math/rand"
+	"sync"
 
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/wallet"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
 )
 
 // Backend provides basic functionalities for fabric.
 type Backend struct{}
 
-// CalcID calculates the channel id of a channel from its parameters. Usually,
-// this should be a hash digest of some or all fields of the parameters.
+var (
+	domainMtx sync.RWMutex
+	domain    adj.Domain // domain is the adjudicator domain in which channel ids are calculated.
+)
+
+// SetDomain sets the adjudicator domain in which the Backend calculates
+// channel ids. It must be set to the domain of the adjudicator chaincode the
+// channels are opened on, e.g., adj.NewDomain(network.Name(), chaincode),
+// before any channel is created.
+func SetDomain(d adj.Domain) {
+	domainMtx.Lock()
+	defer domainMtx.Unlock()
+	domain = d
+}
+
+// Domain returns the adjudicator domain in which the Backend calculates
+// channel ids.
+func Domain() adj.Domain {
+	domainMtx.RLock()
+	defer domainMtx.RUnlock()
+	return domain
+}
+
+// CheckDomain returns an error if the Backend does not calculate channel ids
+// in the given adjudicator domain, e.g., because SetDomain was not called.
+func CheckDomain(d adj.Domain) error {
+	if bd := Domain(); bd != d {
+		return fmt.Errorf("backend domain %+v does not match adjudicator domain %+v, see SetDomain", bd, d)
+	}
+	return nil
+}
+
+// CalcID calculates the channel id of a channel from its parameters and the
+// domain set with SetDomain. The domain prevents signed states from being
+// replayed on other adjudicator deployments.
 // In order to guarantee non-malleability of States, any parameters omitted
 // from the CalcID digest need to be signed together with the State in
 // Sign().
 func (Backend) CalcID(params *channel.Params) channel.ID {
-	hash := sha256.New()
-	if err := params.Encode(hash); err != nil {
-		panic("error encoding Params: " + err.Error())
-	}
-	id := channel.ID{}
-	copy(id[:], hash.Sum(nil))
-	return id
+	return Domain().CalcID(params)
 }
 
 // Sign signs a channel's State with the given Account.
@@ -17,12 +17,16 @@ package channel_test
 import (
 	"testing"
 
+	"github.com/stretchr/testify/require"
+	pchannel "perun.network/go-perun/channel"
 	chtest "perun.network/go-perun/channel/test"
 	"perun.network/go-perun/wallet"
 	wtest "perun.network/go-perun/wallet/test"
 	"polycry.pt/poly-go/test"
 
 	_ "github.com/perun-network/perun-fabric" // init backend
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/channel"
 )
 
 func TestBackend(t *testing.T) {
@@ -41,3 +45,25 @@ func TestBackend(t *testing.T) {
 
 	chtest.GenericBackendTest(t, setup, chtest.IgnoreApp, chtest.IgnoreAssets)
 }
+
+func TestBackendDomain(t *testing.T) {
+	rng := test.Prng(t)
+	params := chtest.NewRandomParams(rng)
+	d := adj.NewDomain("someChannel", "someAdjudicator")
+
+	prev := channel.Domain()
+	defer channel.SetDomain(prev)
+	channel.SetDomain(d)
+	require.Equal(t, d, channel.Domain())
+	require.Equal(t, d.CalcID(params), pchannel.CalcID(params))
+	require.NoError(t, channel.CheckDomain(d))
+	require.Error(t, channel.CheckDomain(adj.NewDomain("someChannel", "otherAdjudicator")))
+}
+
+// setDomain sets the backend domain to d until the end of the test.
+func setDomain(t *testing.T, d adj.Domain) {
+	t.Helper()
+	prev := channel.Domain()
+	t.Cleanup(func() { channel.SetDomain(prev) })
+	channel.SetDomain(d)
+}
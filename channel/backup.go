@@ -0,0 +1,193 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/watcher"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+// StateRecorder is a Watcher that records the latest fully signed state of
+// every watched ledger channel, so that it can be exported as backup. It
+// passes all calls on to the wrapped Watcher.
+type StateRecorder struct {
+	watcher.Watcher
+	domain adj.Domain
+
+	mutex  sync.RWMutex
+	states map[channel.ID]*adj.SignedChannel
+}
+
+// recordingStatesPub records the published states before passing them on.
+type recordingStatesPub struct {
+	watcher.StatesPub
+	recorder *StateRecorder
+	params   *channel.Params
+}
+
+// NewStateRecorder returns a StateRecorder wrapping the given Watcher. Its
+// backups are created for the adjudicator of the given domain.
+func NewStateRecorder(w watcher.Watcher, domain adj.Domain) *StateRecorder {
+	return &StateRecorder{
+		Watcher: w,
+		domain:  domain,
+		states:  make(map[channel.ID]*adj.SignedChannel),
+	}
+}
+
+// StartWatchingLedgerChannel starts watching the channel with the wrapped
+// Watcher and records its initial state and all states published later. If
+// the state cannot be recorded, the wrapped Watcher stops watching the channel
+// again.
+func (r *StateRecorder) StartWatchingLedgerChannel(ctx context.Context, s channel.SignedState) (
+	watcher.StatesPub, watcher.AdjudicatorSub, error) {
+	pub, sub, err := r.Watcher.StartWatchingLedgerChannel(ctx, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.record(s.Params, channel.Transaction{State: s.State, Sigs: s.Sigs}); err != nil {
+		if serr := r.Watcher.StopWatching(ctx, s.State.ID); serr != nil {
+			return nil, nil, fmt.Errorf("%v, stopping watcher: %w", err, serr)
+		}
+		return nil, nil, err
+	}
+	return &recordingStatesPub{StatesPub: pub, recorder: r, params: s.Params}, sub, nil
+}
+
+// StopWatching stops watching the channel with the wrapped Watcher and drops
+// its recorded state.
+func (r *StateRecorder) StopWatching(ctx context.Context, id channel.ID) error {
+	r.mutex.Lock()
+	delete(r.states, id)
+	r.mutex.Unlock()
+	return r.Watcher.StopWatching(ctx, id)
+}
+
+// SignedChannel returns the latest recorded signed state of the channel.
+func (r *StateRecorder) SignedChannel(id channel.ID) (*adj.SignedChannel, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	ch, ok := r.states[id]
+	if !ok {
+		return nil, fmt.Errorf("no recorded state for channel %x", id)
+	}
+	return ch.Clone(), nil
+}
+
+// Backup returns a backup of the latest recorded signed state of the channel
+// in the domain of the StateRecorder.
+func (r *StateRecorder) Backup(id channel.ID) (*adj.Backup, error) {
+	ch, err := r.SignedChannel(id)
+	if err != nil {
+		return nil, err
+	}
+	return adj.NewBackup(r.domain, ch), nil
+}
+
+func (r *StateRecorder) record(params *channel.Params, tx channel.Transaction) error {
+	ch, err := adj.ConvertToSignedChannel(channel.AdjudicatorReq{Params: params, Tx: tx})
+	if err != nil {
+		return fmt.Errorf("recording state: %w", err)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states[ch.State.ID] = ch
+	return nil
+}
+
+// Publish publishes the state on the wrapped StatesPub and records it.
+func (p *recordingStatesPub) Publish(ctx context.Context, tx channel.Transaction) error {
+	if err := p.StatesPub.Publish(ctx, tx); err != nil {
+		return err
+	}
+	return p.recorder.record(p.params, tx)
+}
+
+// RegisterBackup registers the signed state of the given backup.
+func (a *Adjudicator) RegisterBackup(b *adj.Backup) error {
+	if err := a.checkBackup(b); err != nil {
+		return err
+	}
+	return a.binding.Register(&b.Channel)
+}
+
+// WithdrawBackup withdraws the funds of the given participant account from
+// the channel of the backup to the receiver of the Adjudicator. It returns
+// the withdrawn amount.
+//
+// A final state is concluded and withdrawn from in a single transaction.
+// Otherwise, the backup's state or a newer one must have been registered, see
+// RegisterBackup, and WithdrawBackup waits for the end of the challenge
+// duration before withdrawing.
+func (a *Adjudicator) WithdrawBackup(ctx context.Context, b *adj.Backup, acc wallet.Account) (*big.Int, error) {
+	if err := a.checkBackup(b); err != nil {
+		return nil, err
+	}
+	ch := &b.Channel
+	if !isPart(ch.Params.Parts, acc.Address()) {
+		return nil, fmt.Errorf("account %v is not a participant of channel %x", acc.Address(), ch.State.ID)
+	}
+	withdrawReq, err := adj.SignWithdrawRequest(acc, a.domain, ch.State.ID, a.receiver)
+	if err != nil {
+		return nil, err
+	}
+
+	if ch.State.IsFinal {
+		amounts, err := a.binding.ConcludeFinal(ch, []adj.SignedWithdrawReq{*withdrawReq})
+		if err != nil {
+			return nil, err
+		} else if len(amounts) != 1 {
+			return nil, fmt.Errorf("expected one withdrawn amount, got %d", len(amounts))
+		}
+		return amounts[0], nil
+	}
+
+	reg, err := a.binding.StateReg(ch.State.ID)
+	if err != nil {
+		return nil, err
+	} else if reg.Version < ch.State.Version {
+		return nil, fmt.Errorf("registered version %d older than backup version %d", reg.Version, ch.State.Version)
+	}
+	if err := a.makeTimeout(reg.Timeout).Wait(ctx); err != nil {
+		return nil, err
+	}
+	return a.binding.Withdraw(*withdrawReq)
+}
+
+// checkBackup checks that the backup belongs to the domain of the
+// Adjudicator and is fully signed.
+func (a *Adjudicator) checkBackup(b *adj.Backup) error {
+	if b.Domain != a.domain {
+		return fmt.Errorf("backup of domain %v, expected %v", b.Domain, a.domain)
+	}
+	return b.Verify()
+}
+
+func isPart(parts []wallet.Address, addr wallet.Address) bool {
+	for _, p := range parts {
+		if p.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
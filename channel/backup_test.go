@@ -0,0 +1,104 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pchannel "perun.network/go-perun/channel"
+	"perun.network/go-perun/watcher"
+	"polycry.pt/poly-go/test"
+
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/channel"
+)
+
+// nopWatcher is a Watcher that does not watch anything.
+type nopWatcher struct {
+	watcher.Watcher
+	published []pchannel.Transaction
+	stopped   []pchannel.ID
+}
+
+func (w *nopWatcher) StartWatchingLedgerChannel(context.Context, pchannel.SignedState) (
+	watcher.StatesPub, watcher.AdjudicatorSub, error) {
+	return w, nil, nil
+}
+
+func (w *nopWatcher) StopWatching(_ context.Context, id pchannel.ID) error {
+	w.stopped = append(w.stopped, id)
+	return nil
+}
+
+func (w *nopWatcher) Publish(_ context.Context, tx pchannel.Transaction) error {
+	w.published = append(w.published, tx)
+	return nil
+}
+
+func TestStateRecorder(t *testing.T) {
+	rng := test.Prng(t)
+	setup := adjtest.NewSetup(rng)
+	ch := setup.SignedChannel()
+	id := ch.State.ID
+	ctx := context.Background()
+
+	w := &nopWatcher{}
+	r := channel.NewStateRecorder(w, setup.Domain)
+	_, err := r.Backup(id)
+	require.Error(t, err, "unknown channel")
+
+	pub, _, err := r.StartWatchingLedgerChannel(ctx, pchannel.SignedState{
+		Params: ch.Params.CoreParams(),
+		State:  ch.State.CoreState(),
+		Sigs:   ch.Sigs,
+	})
+	require.NoError(t, err)
+	b, err := r.Backup(id)
+	require.NoError(t, err)
+	require.NoError(t, b.Verify())
+	require.Equal(t, uint64(0), b.Channel.State.Version)
+
+	// Published states are passed on to the watcher and recorded.
+	setup.State.Version = 1
+	ch = setup.SignedChannel()
+	tx := pchannel.Transaction{State: ch.State.CoreState(), Sigs: ch.Sigs}
+	require.NoError(t, pub.Publish(ctx, tx))
+	require.Len(t, w.published, 1)
+	b, err = r.Backup(id)
+	require.NoError(t, err)
+	require.NoError(t, b.Verify())
+	require.Equal(t, uint64(1), b.Channel.State.Version)
+	require.Equal(t, setup.Domain, b.Domain)
+
+	require.NoError(t, r.StopWatching(ctx, id))
+	_, err = r.SignedChannel(id)
+	require.Error(t, err, "stopped channel")
+	require.Equal(t, []pchannel.ID{id}, w.stopped)
+
+	// The watcher stops watching channels whose state cannot be recorded.
+	state := ch.State.CoreState()
+	state.Balances = append(state.Balances, state.Balances[0])
+	_, _, err = r.StartWatchingLedgerChannel(ctx, pchannel.SignedState{
+		Params: ch.Params.CoreParams(),
+		State:  state,
+		Sigs:   ch.Sigs,
+	})
+	require.Error(t, err, "multiple assets")
+	require.Equal(t, []pchannel.ID{id, id}, w.stopped)
+	_, err = r.SignedChannel(id)
+	require.Error(t, err, "unrecorded channel")
+}
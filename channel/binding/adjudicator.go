@@ -20,6 +20,8 @@ import (
 	"github.com/hyperledger/fabric-protos-go/peer"
 	adj "github.com/perun-network/perun-fabric/adjudicator"
 	pkgjson "github.com/perun-network/perun-fabric/pkg/json"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"math/big"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/wallet"
@@ -32,17 +34,28 @@ const (
 	txTotalHolding      = "TotalHolding"
 	txRegister          = "Register"
 	txStateReg          = "StateReg"
+	txStateRegs         = "StateRegs"
 	txWithdraw          = "Withdraw"
+	txWithdrawBatch     = "WithdrawBatch"
+	txPayout            = "Payout"
+	txConcludeFinal     = "ConcludeFinal"
 	txMintT             = "MintToken"
 	txBurnT             = "BurnToken"
 	txTToAddr           = "TransferToken"
 	txTBal              = "TokenBalance"
+	txRegisterAddr      = "RegisterAddress"
+	txRegByID           = "RegistrationByAccountID"
+	txRegByAddr         = "RegistrationByAddress"
 	submitRetryDuration = 3 * time.Second
 )
 
 // Adjudicator wraps a fabric client.Contract to connect to the Adjudicator chaincode.
 type Adjudicator struct {
 	Contract *client.Contract
+	// Transient is sent as transient data with every transaction. Idemix
+	// identities set it to the authorization of their account, see
+	// client.IdemixAccount.
+	Transient map[string][]byte
 }
 
 // NewAdjudicatorBinding creates the bindings for the on-chain Adjudicator.
@@ -106,6 +119,23 @@ func (a *Adjudicator) StateReg(id channel.ID) (*adj.StateReg, error) {
 	return &reg, json.Unmarshal(regJSON, &reg)
 }
 
+// StateRegs marshals the given channel ids and evaluates a multi-channel state reg query on the Adjudicator
+// chaincode. The response contains the current registered states of the channels in the order of the ids.
+// The entry of a channel without a registered state is nil. The query is not ordered, so it is cheap enough
+// for polling.
+func (a *Adjudicator) StateRegs(ids []channel.ID) ([]*adj.StateReg, error) {
+	arg, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	regsJSON, err := a.evaluateTransaction(txStateRegs, string(arg))
+	if err != nil {
+		return nil, err
+	}
+	var regs []*adj.StateReg
+	return regs, json.Unmarshal(regsJSON, &regs)
+}
+
 // Withdraw marshals the given withdraw request and sends it to the Adjudicator chaincode.
 // The response contains the amount of funds withdrawn form the channel.
 func (a *Adjudicator) Withdraw(req adj.SignedWithdrawReq) (*big.Int, error) {
@@ -116,6 +146,54 @@ func (a *Adjudicator) Withdraw(req adj.SignedWithdrawReq) (*big.Int, error) {
 	return bigIntWithError(a.submitTransactionWithRetry(txWithdraw, string(arg)))
 }
 
+// Payout sends a payout request for the given channel to the Adjudicator chaincode. The funds of all
+// participants whose payout is bound to the channel are withdrawn to the receivers of their payouts.
+// The response contains the withdrawn amounts in the order of the bound payouts.
+func (a *Adjudicator) Payout(id channel.ID) ([]*big.Int, error) {
+	arg, err := json.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+	amountsJSON, err := a.submitTransactionWithRetry(txPayout, string(arg))
+	if err != nil {
+		return nil, err
+	}
+	var amounts []*big.Int
+	return amounts, json.Unmarshal(amountsJSON, &amounts)
+}
+
+// WithdrawBatch marshals the given withdraw requests and sends them to the Adjudicator chaincode.
+// The requests may belong to different channels. The response contains a result per request, in the
+// order of the requests, holding either the amount withdrawn or the reason the request failed.
+func (a *Adjudicator) WithdrawBatch(reqs []adj.SignedWithdrawReq) ([]adj.WithdrawResult, error) {
+	arg, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+	resultsJSON, err := a.submitTransactionWithRetry(txWithdrawBatch, string(arg))
+	if err != nil {
+		return nil, err
+	}
+	var results []adj.WithdrawResult
+	return results, json.Unmarshal(resultsJSON, &results)
+}
+
+// ConcludeFinal marshals the given final channel state and withdraw requests and sends them to the
+// Adjudicator chaincode. The state is registered and all given requests are withdrawn in one transaction.
+// The response contains the amounts withdrawn from the channel in the order of the requests.
+func (a *Adjudicator) ConcludeFinal(ch *adj.SignedChannel, reqs []adj.SignedWithdrawReq) ([]*big.Int, error) {
+	args, err := pkgjson.MultiMarshal(ch, reqs)
+	if err != nil {
+		return nil, err
+	}
+	amountsJSON, err := a.submitTransactionWithRetry(txConcludeFinal, args...)
+	if err != nil {
+		return nil, err
+	}
+	var amounts []*big.Int
+	return amounts, json.Unmarshal(amountsJSON, &amounts)
+}
+
 // MintToken marshals the given amount and sends a request to the Adjudicator chaincode to mint the amount of tokens.
 func (a *Adjudicator) MintToken(amount *big.Int) error {
 	arg, err := json.Marshal(amount)
@@ -156,13 +234,46 @@ func (a *Adjudicator) TokenBalance(owner adj.AccountID) (*big.Int, error) {
 	return bigIntWithError(a.submitTransactionWithRetry(txTBal, string(arg)))
 }
 
+// RegisterAddress marshals the signed registration and sends an address book registration request to the
+// Adjudicator chaincode. The registration must belong to the client identity of the binding's gateway.
+func (a *Adjudicator) RegisterAddress(reg *adj.SignedRegistration) error {
+	arg, err := json.Marshal(reg)
+	if err != nil {
+		return err
+	}
+	_, err = a.submitTransactionWithRetry(txRegisterAddr, string(arg))
+	return err
+}
+
+// RegistrationByAccountID marshals the given id and sends an address book lookup request to the Adjudicator
+// chaincode. The lookup is evaluated without ordering. The response contains the registration of the given id.
+func (a *Adjudicator) RegistrationByAccountID(id adj.AccountID) (*adj.SignedRegistration, error) {
+	arg, err := json.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+	return registrationWithError(a.evaluateTransaction(txRegByID, string(arg)))
+}
+
+// RegistrationByAddress marshals the given address and sends an address book lookup request to the
+// Adjudicator chaincode. The lookup is evaluated without ordering. The response contains the registration of the
+// given wallet address.
+func (a *Adjudicator) RegistrationByAddress(addr wallet.Address) (*adj.SignedRegistration, error) {
+	arg, err := json.Marshal(addr)
+	if err != nil {
+		return nil, err
+	}
+	return registrationWithError(a.evaluateTransaction(txRegByAddr, string(arg)))
+}
+
 // submitTransactionWithRetry ensures that in case of a missed lock on the contract there is
 // another attempt on submitting the transaction.
 func (a *Adjudicator) submitTransactionWithRetry(txType string, args ...string) ([]byte, error) {
-	tx, err := a.Contract.SubmitTransaction(txType, args...)
-	if e, ok := err.(*client.CommitError); ok && e.Code == peer.TxValidationCode_MVCC_READ_CONFLICT { //nolint:nosnakecase
+	opts := a.proposalOpts(args)
+	tx, err := a.Contract.Submit(txType, opts...)
+	if retryable(err) {
 		time.Sleep(submitRetryDuration)
-		tx, err = a.Contract.SubmitTransaction(txType, args...)
+		tx, err = a.Contract.Submit(txType, opts...)
 	}
 	if err != nil {
 		return nil, err
@@ -170,6 +281,37 @@ func (a *Adjudicator) submitTransactionWithRetry(txType string, args ...string)
 	return tx, nil
 }
 
+// evaluateTransaction evaluates a read-only transaction on a peer without
+// submitting it for ordering.
+func (a *Adjudicator) evaluateTransaction(txType string, args ...string) ([]byte, error) {
+	return a.Contract.Evaluate(txType, a.proposalOpts(args)...)
+}
+
+// proposalOpts returns the options of a transaction proposal with the given
+// arguments.
+func (a *Adjudicator) proposalOpts(args []string) []client.ProposalOption {
+	opts := []client.ProposalOption{client.WithArguments(args...)}
+	if a.Transient != nil {
+		opts = append(opts, client.WithTransient(a.Transient))
+	}
+	return opts
+}
+
+// retryable returns whether a transaction that failed with the given error
+// can be submitted again. This is the case for MVCC read conflicts and for
+// endorsements that failed because the gateway peer was unavailable. In the
+// latter case, the transaction was not submitted and the connection fails
+// over to another peer.
+func retryable(err error) bool {
+	if e, ok := err.(*client.CommitError); ok {
+		return e.Code == peer.TxValidationCode_MVCC_READ_CONFLICT //nolint:nosnakecase
+	}
+	if e, ok := err.(*client.EndorseError); ok {
+		return status.Code(e) == codes.Unavailable
+	}
+	return false
+}
+
 func bigIntWithError(b []byte, err error) (*big.Int, error) {
 	if err != nil {
 		return nil, err
@@ -178,3 +320,12 @@ func bigIntWithError(b []byte, err error) (*big.Int, error) {
 	bi := new(big.Int)
 	return bi, json.Unmarshal(b, bi)
 }
+
+func registrationWithError(b []byte, err error) (*adj.SignedRegistration, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	var reg adj.SignedRegistration
+	return &reg, json.Unmarshal(b, &reg)
+}
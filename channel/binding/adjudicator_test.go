@@ -26,6 +26,7 @@ import (
 
 	_ "github.com/perun-network/perun-fabric" // init backend
 	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/wire"
 )
 
 func TestAdjudicatorBinding(t *testing.T) {
@@ -64,8 +65,14 @@ func TestAdjudicatorBinding(t *testing.T) {
 	test.FatalClientErr("querying state", err)
 	require.Equal(true, regfinal.CoreState().Equal(regfinal0.CoreState()) == nil, "final StateReg")
 
+	regs, err := adjs[1].Binding.StateRegs([]channel.ID{id, {}})
+	test.FatalClientErr("querying states", err)
+	require.Len(regs, 2)
+	require.Equal(true, regfinal.CoreState().Equal(regs[0].CoreState()) == nil, "final StateRegs")
+	require.Nil(regs[1], "unknown channel StateRegs")
+
 	for i := range setup.Parts {
-		req, _ := adj.SignWithdrawRequest(adjs[i].Account, setup.Params.ID(), adjs[i].ClientFabricID)
+		req, _ := adj.SignWithdrawRequest(adjs[i].Account, setup.Domain, id, adjs[i].ClientFabricID)
 		withdrawn, err := adjs[i].Binding.Withdraw(*req)
 		test.FatalClientErr("withdrawing", err)
 		require.Equal(0, setup.State.Balances[i].Cmp(withdrawn), "Withdraw")
@@ -75,3 +82,28 @@ func TestAdjudicatorBinding(t *testing.T) {
 	test.FatalClientErr("querying total holding", err)
 	require.Equal(0, totalfinal.Cmp(new(big.Int)), "final zero holding")
 }
+
+func TestAddressBookBinding(t *testing.T) {
+	require := requ.New(t)
+	as, err := test.NewTestSession(test.Org1, test.AdjudicatorName)
+	test.FatalErr("creating adjudicator session", err)
+	defer as.Close()
+
+	rng := ptest.Prng(ptest.NameStr("FabricAddressBook"))
+	domain := adj.NewDomain(test.ChannelName, test.AdjudicatorName)
+	var seq uint64
+	if prev, err := as.Binding.RegistrationByAccountID(as.ClientFabricID); err == nil {
+		seq = prev.Reg.Sequence + 1
+	}
+	reg, err := adj.SignRegistration(as.Account, wire.NewRandomAccount(rng), domain, as.ClientFabricID, seq, "localhost:5750")
+	require.NoError(err)
+	test.FatalClientErr("registering address", as.Binding.RegisterAddress(reg))
+
+	byID, err := as.Binding.RegistrationByAccountID(as.ClientFabricID)
+	test.FatalClientErr("querying registration by id", err)
+	require.True(byID.Reg.Address.Equal(as.Account.Address()))
+	byAddr, err := as.Binding.RegistrationByAddress(as.Account.Address())
+	test.FatalClientErr("querying registration by address", err)
+	require.Equal(as.ClientFabricID, byAddr.Reg.AccountID)
+	require.True(byAddr.Reg.WireAddress.Equal(reg.Reg.WireAddress))
+}
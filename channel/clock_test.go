@@ -0,0 +1,120 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pchannel "perun.network/go-perun/channel"
+	"polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/channel"
+	chtest "github.com/perun-network/perun-fabric/channel/test"
+)
+
+const clockTestTimeout = 10 * time.Second
+
+// newClockSetup returns a test setup whose in-memory contract runs on the
+// returned fake clock.
+func newClockSetup(t *testing.T) (*adjtest.Setup, *chtest.MemContracts, *chtest.FakeClock) {
+	t.Helper()
+	setup := adjtest.NewSetup(test.Prng(t))
+	setup.State.ID = setup.Domain.CalcID(setup.Params.CoreParams())
+	setDomain(t, setup.Domain)
+
+	clock := chtest.NewFakeClock(setup.Ledger.Now().Time())
+	ledger := chtest.NewClockLedger(clock)
+	contracts := chtest.NewMemContracts(adj.NewAdjudicator(chtest.AdjudicatorName, setup.Domain, ledger, adj.NewMemAsset()))
+	return setup, contracts, clock
+}
+
+func TestClock_Dispute(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), clockTestTimeout)
+	defer cancel()
+	setup, contracts, clock := newClockSetup(t)
+	id := setup.State.ID
+
+	contract := contracts.Contract(setup.IDs[0])
+	for i, part := range setup.Parts {
+		require.NoError(t, contracts.Mint(setup.IDs[0], setup.State.Balances[i]))
+		require.NoError(t, contract.Deposit(id, part, setup.State.Balances[i]))
+	}
+	a := channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0], channel.WithAdjudicatorClock(clock))
+
+	req := pchannel.AdjudicatorReq{
+		Params: setup.Params.CoreParams(),
+		Acc:    setup.Accs[0],
+		Idx:    0,
+		Tx: pchannel.Transaction{
+			State: setup.State.CoreState(),
+			Sigs:  setup.SignedChannel().Sigs,
+		},
+	}
+	require.NoError(t, a.Register(ctx, req, nil))
+
+	sub, err := a.Subscribe(ctx, id)
+	require.NoError(t, err)
+	defer sub.Close()
+	_, ok := sub.Next().(*pchannel.RegisteredEvent)
+	require.True(t, ok, "expected RegisteredEvent")
+
+	withdrawn := make(chan error, 1)
+	go func() { withdrawn <- a.Withdraw(ctx, req, nil) }()
+
+	// Wait until the subscription and the withdrawal wait for the clock,
+	// then let the challenge period elapse.
+	clock.BlockUntil(2) //nolint:gomnd
+	clock.Advance(time.Duration(setup.Params.ChallengeDuration)*time.Second + time.Second)
+
+	e, ok := sub.Next().(*pchannel.ConcludedEvent)
+	require.True(t, ok, "expected ConcludedEvent")
+	require.Equal(t, id, e.ID())
+	require.NoError(t, <-withdrawn)
+
+	balance, err := contracts.TokenBalance(setup.IDs[0])
+	require.NoError(t, err)
+	require.Zero(t, balance.Cmp(setup.State.Balances[0]))
+}
+
+func TestClock_FundingTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), clockTestTimeout)
+	defer cancel()
+	setup, contracts, clock := newClockSetup(t)
+
+	// Only the first participant funds the channel.
+	require.NoError(t, contracts.Mint(setup.IDs[0], setup.State.Balances[0]))
+	f := channel.NewFunderWithContract(contracts.Contract(setup.IDs[0]), setup.Domain, channel.WithFunderClock(clock))
+	state := setup.State.CoreState()
+	req := pchannel.FundingReq{
+		Params:    setup.Params.CoreParams(),
+		State:     state,
+		Idx:       0,
+		Agreement: state.Balances,
+	}
+
+	funded := make(chan error, 1)
+	go func() { funded <- f.Fund(ctx, req) }()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Duration(setup.Params.ChallengeDuration)*time.Second + time.Second)
+
+	err := <-funded
+	require.True(t, pchannel.IsFundingTimeoutError(err), "expected FundingTimeoutError, got %v", err)
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"math/big"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/channel/binding"
+)
+
+// Contract is the interface of the Adjudicator contract used by the
+// Adjudicator and Funder. It is implemented by the chaincode binding
+// binding.Adjudicator and can be implemented by an in-memory adjudicator for
+// testing, see NewAdjudicatorWithContract and NewFunderWithContract.
+type Contract interface {
+	// Deposit deposits the given amount for participant part into channel id.
+	Deposit(id channel.ID, part wallet.Address, amount *big.Int) error
+	// TotalHolding returns the sum of the holdings of all participants.
+	TotalHolding(id channel.ID, parts []wallet.Address) (*big.Int, error)
+	// Register registers the given signed channel state.
+	Register(ch *adj.SignedChannel) error
+	// StateReg returns the currently registered state of channel id.
+	StateReg(id channel.ID) (*adj.StateReg, error)
+	// StateRegs returns the currently registered states of the given
+	// channels, in order. The entry of an unregistered channel is nil.
+	StateRegs(ids []channel.ID) ([]*adj.StateReg, error)
+	// Withdraw withdraws the funds of the request's participant.
+	Withdraw(req adj.SignedWithdrawReq) (*big.Int, error)
+	// ConcludeFinal registers the final state and withdraws the funds of
+	// the given requests in one transaction.
+	ConcludeFinal(ch *adj.SignedChannel, reqs []adj.SignedWithdrawReq) ([]*big.Int, error)
+}
+
+var _ Contract = (*binding.Adjudicator)(nil)
@@ -16,7 +16,9 @@ package channel
 
 import (
 	"context"
+	"fmt"
 	"github.com/hyperledger/fabric-gateway/pkg/client"
+	adj "github.com/perun-network/perun-fabric/adjudicator"
 	"github.com/perun-network/perun-fabric/channel/binding"
 	"perun.network/go-perun/channel"
 	"sync"
@@ -29,9 +31,11 @@ const (
 
 // Funder provides functionality for channel funding.
 type Funder struct {
-	binding *binding.Adjudicator // binding gives access to the chaincode.
-	polling time.Duration        // The polling interval to wait for complete funding.
-	m       sync.Mutex           // m prevents sending parallel transactions.
+	binding Contract      // binding gives access to the chaincode.
+	domain  adj.Domain    // domain is the domain of the Adjudicator contract.
+	polling time.Duration // The polling interval to wait for complete funding.
+	clock   Clock         // The clock for the funding timeout.
+	m       sync.Mutex    // m prevents sending parallel transactions.
 }
 
 // FunderOpt extends the constructor of Funder.
@@ -44,11 +48,30 @@ func WithPollingInterval(d time.Duration) FunderOpt {
 	}
 }
 
-// NewFunder returns a new Funder.
+// WithFunderClock overwrites the clock of the Funder, which defaults to
+// SystemClock.
+func WithFunderClock(c Clock) FunderOpt {
+	return func(f *Funder) {
+		f.clock = c
+	}
+}
+
+// NewFunder returns a new Funder. Like the Adjudicator, it requires the
+// channel ids to be calculated in the domain of the given network and
+// chaincode, see CheckDomain.
 func NewFunder(network *client.Network, chaincode string, opts ...FunderOpt) *Funder {
+	return NewFunderWithContract(binding.NewAdjudicatorBinding(network, chaincode),
+		adj.NewDomain(network.Name(), chaincode), opts...)
+}
+
+// NewFunderWithContract returns a new Funder that deposits on the given
+// contract of the given domain.
+func NewFunderWithContract(c Contract, domain adj.Domain, opts ...FunderOpt) *Funder {
 	f := &Funder{
-		binding: binding.NewAdjudicatorBinding(network, chaincode),
+		binding: c,
+		domain:  domain,
 		polling: defaultFunderPollingInterval,
+		clock:   SystemClock{},
 	}
 	for _, opt := range opts {
 		opt(f)
@@ -68,6 +91,11 @@ func (f *Funder) Fund(ctx context.Context, req channel.FundingReq) error {
 	funding := req.Agreement[assetIndex][req.Idx]
 	part := req.Params.Parts[req.Idx]
 
+	// Funds must not be deposited for a channel id of another domain.
+	if err := CheckDomain(f.domain); err != nil {
+		return fmt.Errorf("fund: %w", err)
+	}
+
 	// Make deposit.
 	f.m.Lock()
 	defer f.m.Unlock()
@@ -78,8 +106,8 @@ func (f *Funder) Fund(ctx context.Context, req channel.FundingReq) error {
 
 	// Calculate funding timeout.
 	challengeDuration := time.Duration(req.Params.ChallengeDuration) * time.Second
-	wall := time.Now().UTC().Add(challengeDuration)
-	timeout := MakeTimeout(wall, f.polling)
+	wall := f.clock.Now().UTC().Add(challengeDuration)
+	timeout := MakeTimeoutWithClock(wall, f.polling, f.clock)
 
 	// Wait for Funding completion.
 	return f.awaitFundingComplete(ctx, timeout, req)
@@ -114,7 +142,7 @@ func (f *Funder) awaitFundingComplete(ctx context.Context, t *Timeout, req chann
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(f.polling):
+		case <-f.clock.After(f.polling):
 		}
 	}
 }
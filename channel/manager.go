@@ -0,0 +1,270 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/channel"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+const (
+	// timeoutSlack is added when waiting for a timeout, so that the timeout
+	// has elapsed when the states are queried next.
+	timeoutSlack = 10 * time.Millisecond
+	// maxRetryInterval is the interval up to which the polling backs off
+	// while queries fail, unless the maximal polling interval is longer.
+	maxRetryInterval = time.Minute
+)
+
+type (
+	// subscriptionManager queries the states of all channels with event
+	// subscriptions of an Adjudicator in batches and passes them on to the
+	// subscriptions, which derive their events from them.
+	//
+	// The polling interval adapts to the subscribed channels: while a
+	// channel is disputed, it is polled at the base interval of the
+	// Adjudicator, and right after the earliest challenge timeout. Otherwise,
+	// the interval is doubled up to the maximal interval of the Adjudicator.
+	//
+	// Failed queries are retried and the interval is doubled while they fail.
+	// If a batch query fails, the channels of the batch are queried
+	// separately, so that an error of one channel does not affect the others.
+	// The subscriptions of a channel only end with an error once the queries
+	// of the channel failed the maximal number of times in a row.
+	subscriptionManager struct {
+		adjudicator *Adjudicator
+
+		mutex   sync.Mutex
+		subs    map[channel.ID]map[*EventSubscription]struct{}
+		states  map[channel.ID]*adj.StateReg // states are the last queried states.
+		fails   map[channel.ID]int           // fails counts the consecutive failed queries.
+		wake    chan struct{}                // wake triggers a query for new subscriptions.
+		running bool
+	}
+
+	// stateUpdate is the result of a state query of a channel. The state is
+	// nil if no state is registered.
+	stateUpdate struct {
+		reg *adj.StateReg
+		err error
+	}
+)
+
+func newSubscriptionManager(a *Adjudicator) *subscriptionManager {
+	return &subscriptionManager{
+		adjudicator: a,
+		subs:        make(map[channel.ID]map[*EventSubscription]struct{}),
+		states:      make(map[channel.ID]*adj.StateReg),
+		fails:       make(map[channel.ID]int),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// add adds the subscription and starts polling if it is the first one.
+func (m *subscriptionManager) add(s *EventSubscription) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	subs, ok := m.subs[s.channelID]
+	if !ok {
+		subs = make(map[*EventSubscription]struct{})
+		m.subs[s.channelID] = subs
+	}
+	subs[s] = struct{}{}
+
+	if !m.running {
+		m.running = true
+		go m.run()
+		return
+	}
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// remove removes the subscription. Polling stops with the last one.
+func (m *subscriptionManager) remove(s *EventSubscription) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	subs := m.subs[s.channelID]
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(m.subs, s.channelID)
+		delete(m.states, s.channelID)
+		delete(m.fails, s.channelID)
+	}
+}
+
+// run polls the states of the subscribed channels until there are no
+// subscriptions left.
+func (m *subscriptionManager) run() {
+	interval := m.adjudicator.polling
+	for {
+		ids := m.channels()
+		if ids == nil {
+			return
+		}
+		changed, disputed, failed, timeout := m.poll(ids)
+		interval = m.nextInterval(interval, changed || disputed, failed, timeout)
+
+		select {
+		case <-m.wake:
+			interval = m.adjudicator.polling
+		case <-m.adjudicator.clock.After(interval):
+		}
+	}
+}
+
+// channels returns the ids of the subscribed channels. If there are none, it
+// returns nil and marks the manager as stopped.
+func (m *subscriptionManager) channels() []channel.ID {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.subs) == 0 {
+		m.running = false
+		return nil
+	}
+	ids := make([]channel.ID, 0, len(m.subs))
+	for id := range m.subs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// poll queries the states of the given channels in batches and passes them on
+// to the subscriptions. It returns whether any state changed since the last
+// query, whether any channel is disputed, whether any query failed and the
+// earliest challenge timeout that did not elapse yet, which is zero if there
+// is none.
+func (m *subscriptionManager) poll(ids []channel.ID) (changed, disputed, failed bool, timeout time.Time) {
+	batch := m.adjudicator.batchSize
+	if batch <= 0 {
+		batch = len(ids)
+	}
+	for start := 0; start < len(ids); start += batch {
+		end := start + batch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		updates := m.query(ids[start:end])
+
+		m.mutex.Lock()
+		for i, id := range ids[start:end] {
+			u := updates[i]
+			if u.err != nil {
+				failed = true
+				if m.fails[id]++; m.fails[id] < m.adjudicator.maxFails {
+					continue // Retry before ending the subscriptions.
+				}
+			} else {
+				delete(m.fails, id)
+				c, d, t := m.record(id, u.reg)
+				changed, disputed = changed || c, disputed || d
+				if !t.IsZero() && (timeout.IsZero() || t.Before(timeout)) {
+					timeout = t
+				}
+			}
+			for s := range m.subs[id] {
+				s.update(u)
+			}
+		}
+		m.mutex.Unlock()
+	}
+	return changed, disputed, failed, timeout
+}
+
+// query queries the states of the given channels. If the batch query fails,
+// the channels are queried separately to isolate the failing ones.
+func (m *subscriptionManager) query(ids []channel.ID) []stateUpdate {
+	updates := make([]stateUpdate, len(ids))
+	regs, err := m.queryBatch(ids)
+	if err == nil {
+		for i := range ids {
+			updates[i].reg = regs[i]
+		}
+		return updates
+	}
+	if len(ids) == 1 {
+		updates[0].err = err
+		return updates
+	}
+	for i, id := range ids {
+		regs, err := m.queryBatch([]channel.ID{id})
+		if err != nil {
+			updates[i].err = err
+		} else {
+			updates[i].reg = regs[0]
+		}
+	}
+	return updates
+}
+
+// queryBatch queries the states of the given channels at once.
+func (m *subscriptionManager) queryBatch(ids []channel.ID) ([]*adj.StateReg, error) {
+	regs, err := m.adjudicator.binding.StateRegs(ids)
+	if err == nil && len(regs) != len(ids) {
+		err = fmt.Errorf("expected %d states, got %d", len(ids), len(regs))
+	}
+	return regs, err
+}
+
+// record records the queried state of a channel. It returns whether the
+// state changed, whether the channel is disputed and its challenge timeout if
+// it did not elapse yet.
+func (m *subscriptionManager) record(id channel.ID, reg *adj.StateReg) (changed, disputed bool, timeout time.Time) {
+	prev, known := m.states[id]
+	changed = (known && (prev == nil) != (reg == nil)) || (prev != nil && reg != nil && !prev.Equal(*reg))
+	m.states[id] = reg
+	if reg == nil || reg.IsFinal {
+		return changed, false, time.Time{}
+	}
+	if t := reg.Timeout.Time(); m.adjudicator.clock.Now().Before(t) {
+		return changed, true, t
+	}
+	return changed, false, time.Time{}
+}
+
+// nextInterval returns the polling interval after the current one. If a
+// query failed, it is doubled up to the maximal retry interval. Otherwise, it
+// is reset to the base interval if active is set, and doubled up to the
+// maximal interval if not. It is shortened to reach the given timeout in time.
+func (m *subscriptionManager) nextInterval(current time.Duration, active, failed bool, timeout time.Time) time.Duration {
+	next := m.adjudicator.polling
+	switch max := m.adjudicator.maxPolling; {
+	case failed:
+		if max < maxRetryInterval {
+			max = maxRetryInterval
+		}
+		fallthrough
+	case !active:
+		if next = 2 * current; next > max { //nolint:gomnd
+			next = max
+		}
+	}
+	if !timeout.IsZero() {
+		if untilTimeout := timeout.Sub(m.adjudicator.clock.Now()) + timeoutSlack; untilTimeout < next {
+			next = untilTimeout
+		}
+	}
+	return next
+}
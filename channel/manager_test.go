@@ -0,0 +1,250 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pchannel "perun.network/go-perun/channel"
+	"polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/channel"
+	chtest "github.com/perun-network/perun-fabric/channel/test"
+)
+
+// countingContract counts the state queries on the in-memory contract.
+type countingContract struct {
+	*chtest.MemContract
+	queries  int64
+	maxBatch int64
+}
+
+func (c *countingContract) StateRegs(ids []pchannel.ID) ([]*adj.StateReg, error) {
+	atomic.AddInt64(&c.queries, 1)
+	for n := int64(len(ids)); ; {
+		max := atomic.LoadInt64(&c.maxBatch)
+		if n <= max || atomic.CompareAndSwapInt64(&c.maxBatch, max, n) {
+			break
+		}
+	}
+	return c.MemContract.StateRegs(ids)
+}
+
+func (c *countingContract) count() int64 { return atomic.LoadInt64(&c.queries) }
+
+// flakyContract fails the next queries or all queries of some channels.
+type flakyContract struct {
+	*countingContract
+	mutex    sync.Mutex
+	failNext int                      // failNext is the number of next queries to fail.
+	failing  map[pchannel.ID]struct{} // failing are the channels whose queries fail.
+}
+
+var errFlaky = errors.New("query failed")
+
+func (c *flakyContract) StateRegs(ids []pchannel.ID) ([]*adj.StateReg, error) {
+	c.mutex.Lock()
+	if c.failNext > 0 {
+		c.failNext--
+		c.mutex.Unlock()
+		return nil, errFlaky
+	}
+	for _, id := range ids {
+		if _, ok := c.failing[id]; ok {
+			c.mutex.Unlock()
+			return nil, errFlaky
+		}
+	}
+	c.mutex.Unlock()
+	return c.countingContract.StateRegs(ids)
+}
+
+// setupManagerTest returns a contract on the setup's adjudicator and signed
+// channels of the setup with the given number of different channel ids.
+func setupManagerTest(t *testing.T, setup *adjtest.Setup, channels int) (*countingContract, []*adj.SignedChannel) {
+	t.Helper()
+	chs := make([]*adj.SignedChannel, channels)
+	for i := range chs {
+		setup.Params.Nonce = big.NewInt(int64(i))
+		setup.State.ID = setup.Domain.CalcID(setup.Params.CoreParams())
+		chs[i] = setup.SignedChannel()
+	}
+	setDomain(t, setup.Domain)
+	return &countingContract{MemContract: chtest.NewMemContracts(setup.Adj).Contract(setup.IDs[0])}, chs
+}
+
+func TestSubscriptionManager_Batches(t *testing.T) {
+	const (
+		numChannels = 25
+		batchSize   = 10
+		polling     = 20 * time.Millisecond
+	)
+	ctx := context.Background()
+	setup := adjtest.NewSetup(test.Prng(t))
+	contract, chs := setupManagerTest(t, setup, numChannels)
+	a := channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0],
+		channel.WithSubPollingInterval(polling), channel.WithSubBatchSize(batchSize))
+
+	// Two subscriptions per channel share the queries.
+	subs := make([][2]pchannel.AdjudicatorSubscription, numChannels)
+	for i, ch := range chs {
+		for j := range subs[i] {
+			sub, err := a.Subscribe(ctx, ch.State.ID)
+			require.NoError(t, err)
+			subs[i][j] = sub
+		}
+	}
+
+	// Every third channel is registered. Its events are fanned out to its
+	// subscriptions only.
+	for i := 0; i < numChannels; i += 3 {
+		require.NoError(t, contract.Register(chs[i]))
+	}
+	for i := 0; i < numChannels; i += 3 {
+		for _, sub := range subs[i] {
+			e, ok := sub.Next().(*pchannel.RegisteredEvent)
+			require.True(t, ok, "expected RegisteredEvent")
+			require.Equal(t, chs[i].State.ID, e.ID())
+		}
+	}
+
+	// The queries are batched, instead of one query per subscription.
+	const rounds = 10
+	queries := contract.count()
+	time.Sleep(rounds * polling)
+	perRound := int64((numChannels + batchSize - 1) / batchSize)
+	require.Equal(t, int64(batchSize), atomic.LoadInt64(&contract.maxBatch))
+	require.Greater(t, contract.count(), queries, "polling continues")
+	require.LessOrEqual(t, contract.count()-queries, (rounds+1)*perRound)
+
+	// Polling stops with the last subscription.
+	for i := range subs {
+		for _, sub := range subs[i] {
+			require.NoError(t, sub.Close())
+		}
+	}
+	time.Sleep(2 * polling)
+	queries = contract.count()
+	time.Sleep(5 * polling)
+	require.Equal(t, queries, contract.count(), "polling stopped")
+}
+
+func TestSubscriptionManager_QueryErrors(t *testing.T) {
+	const (
+		polling  = 10 * time.Millisecond
+		maxFails = 3
+	)
+	ctx := context.Background()
+	setup := adjtest.NewSetup(test.Prng(t))
+	counting, chs := setupManagerTest(t, setup, 3)
+	contract := &flakyContract{countingContract: counting, failing: make(map[pchannel.ID]struct{})}
+	a := channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0],
+		channel.WithSubPollingInterval(polling), channel.WithSubMaxQueryFailures(maxFails))
+
+	subs := make([]pchannel.AdjudicatorSubscription, len(chs))
+	for i, ch := range chs {
+		sub, err := a.Subscribe(ctx, ch.State.ID)
+		require.NoError(t, err)
+		defer sub.Close()
+		subs[i] = sub
+	}
+
+	// Temporary query errors are retried and do not end the subscriptions.
+	contract.mutex.Lock()
+	contract.failNext = maxFails - 1
+	contract.mutex.Unlock()
+	require.NoError(t, contract.Register(chs[0]))
+	_, ok := subs[0].Next().(*pchannel.RegisteredEvent)
+	require.True(t, ok, "expected RegisteredEvent")
+
+	// Persistent errors of a channel only end its subscription.
+	contract.mutex.Lock()
+	contract.failing[chs[1].State.ID] = struct{}{}
+	contract.mutex.Unlock()
+	require.Nil(t, subs[1].Next())
+	require.ErrorIs(t, subs[1].Err(), errFlaky)
+	require.NoError(t, contract.Register(chs[2]))
+	_, ok = subs[2].Next().(*pchannel.RegisteredEvent)
+	require.True(t, ok, "expected RegisteredEvent")
+	require.NoError(t, subs[0].(*channel.EventSubscription).Err())
+}
+
+func TestSubscriptionManager_AdaptivePolling(t *testing.T) {
+	const (
+		polling    = 10 * time.Millisecond
+		maxPolling = 200 * time.Millisecond
+	)
+	ctx := context.Background()
+	setup := adjtest.NewSetup(test.Prng(t))
+	contract, chs := setupManagerTest(t, setup, 2)
+	a := channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0],
+		channel.WithSubPollingInterval(polling), channel.WithSubMaxPollingInterval(maxPolling))
+
+	// Without disputes, polling backs off.
+	sub, err := a.Subscribe(ctx, chs[0].State.ID)
+	require.NoError(t, err)
+	defer sub.Close()
+	time.Sleep(30 * polling)
+	require.Less(t, contract.count(), int64(15), "backed off polling")
+
+	// A new subscription is served right away.
+	sub1, err := a.Subscribe(ctx, chs[1].State.ID)
+	require.NoError(t, err)
+	defer sub1.Close()
+	require.NoError(t, contract.Register(chs[1]))
+	start := time.Now()
+	_, ok := sub1.Next().(*pchannel.RegisteredEvent)
+	require.True(t, ok, "expected RegisteredEvent")
+	require.Less(t, time.Since(start), maxPolling+polling)
+}
+
+func TestSubscriptionManager_Timeout(t *testing.T) {
+	// The base polling interval exceeds the challenge duration of a second.
+	const polling = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	setup := adjtest.NewSetup(test.Prng(t))
+	setup.Params.ChallengeDuration = 1
+	contract, chs := setupManagerTest(t, setup, 1)
+	a := channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0], channel.WithSubPollingInterval(polling))
+
+	require.NoError(t, contract.Register(chs[0]))
+	sub, err := a.Subscribe(ctx, chs[0].State.ID)
+	require.NoError(t, err)
+	defer sub.Close()
+	e := sub.Next()
+	_, ok := e.(*pchannel.RegisteredEvent)
+	require.True(t, ok, "expected RegisteredEvent")
+
+	// The states are queried right after the challenge timeout.
+	done := make(chan pchannel.AdjudicatorEvent, 1)
+	go func() { done <- sub.Next() }()
+	select {
+	case e := <-done:
+		_, ok := e.(*pchannel.ConcludedEvent)
+		require.True(t, ok, "expected ConcludedEvent")
+	case <-ctx.Done():
+		t.Fatal("no ConcludedEvent after timeout")
+	}
+}
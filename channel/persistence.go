@@ -0,0 +1,130 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"perun.network/go-perun/channel"
+	"polycry.pt/poly-go/sortedkv"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+// subscriptionPrefix is the prefix of the keys of the subscription records.
+// It differs from the prefixes used by keyvalue.PersistRestorer.
+const subscriptionPrefix = "Sub:"
+
+type (
+	// PersistentSubscriber subscribes to channel events like the Adjudicator
+	// does, but persists the last event of each channel that was handled by
+	// its subscriber. A subscription to the same channel, e.g., after a
+	// restart of the client, resumes after this event, so that it is not
+	// reported again and no later event is missed.
+	//
+	// An event counts as handled once the subscriber requests the next event.
+	// A RegisteredEvent that was returned by Next, but not handled before the
+	// restart, is reported again, so that the dispute can still be refuted.
+	// A ConcludedEvent is recorded when it is returned, as there is nothing
+	// left to refute.
+	//
+	// Subscriptions for the dispute watcher should be persistent, while
+	// one-off subscriptions, like the one of go-perun's Channel.Settle,
+	// should use the Adjudicator, which reports a registered state once.
+	PersistentSubscriber struct {
+		adjudicator *Adjudicator
+		db          sortedkv.Database
+	}
+
+	// subscriptionRecord is the last handled event of a channel.
+	subscriptionRecord struct {
+		Reg       adj.StateReg `json:"reg"`       // Reg is the registered state of the event.
+		Concluded bool         `json:"concluded"` // Concluded indicates a ConcludedEvent.
+	}
+)
+
+var _ channel.RegisterSubscriber = (*PersistentSubscriber)(nil)
+
+// NewPersistentSubscriber returns a PersistentSubscriber that subscribes to
+// the events of the given Adjudicator and records them in the given
+// database. The database may be shared with a keyvalue.PersistRestorer.
+func NewPersistentSubscriber(a *Adjudicator, db sortedkv.Database) *PersistentSubscriber {
+	return &PersistentSubscriber{adjudicator: a, db: sortedkv.NewTable(db, subscriptionPrefix)}
+}
+
+// Register registers the given ledger channel state on-chain, see
+// Adjudicator.Register.
+func (p *PersistentSubscriber) Register(ctx context.Context, req channel.AdjudicatorReq, subChannels []channel.SignedState) error {
+	return p.adjudicator.Register(ctx, req, subChannels)
+}
+
+// Subscribe returns an AdjudicatorEvent subscription that resumes after the
+// last handled event of the channel. If the channel was concluded, the
+// subscription ends immediately.
+func (p *PersistentSubscriber) Subscribe(ctx context.Context, ch channel.ID) (channel.AdjudicatorSubscription, error) {
+	rec, err := p.record(ch)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+	return newEventSubscription(p.adjudicator, ch, p, rec), nil
+}
+
+// Forget deletes the record of the given channel. It should be called once
+// the channel is withdrawn.
+func (p *PersistentSubscriber) Forget(ch channel.ID) error {
+	key := string(ch[:])
+	if ok, err := p.db.Has(key); err != nil {
+		return fmt.Errorf("reading subscription record: %w", err)
+	} else if !ok {
+		return nil
+	}
+	if err := p.db.Delete(key); err != nil {
+		return fmt.Errorf("deleting subscription record: %w", err)
+	}
+	return nil
+}
+
+// record returns the record of the given channel, or nil if there is none.
+func (p *PersistentSubscriber) record(ch channel.ID) (*subscriptionRecord, error) {
+	key := string(ch[:])
+	if ok, err := p.db.Has(key); err != nil {
+		return nil, fmt.Errorf("reading subscription record: %w", err)
+	} else if !ok {
+		return nil, nil
+	}
+	data, err := p.db.GetBytes(key)
+	if err != nil {
+		return nil, fmt.Errorf("reading subscription record: %w", err)
+	}
+	rec := new(subscriptionRecord)
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("decoding subscription record: %w", err)
+	}
+	return rec, nil
+}
+
+// put records the given event of the given channel.
+func (p *PersistentSubscriber) put(ch channel.ID, rec subscriptionRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding subscription record: %w", err)
+	}
+	if err := p.db.PutBytes(string(ch[:]), data); err != nil {
+		return fmt.Errorf("writing subscription record: %w", err)
+	}
+	return nil
+}
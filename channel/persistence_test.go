@@ -0,0 +1,109 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pchannel "perun.network/go-perun/channel"
+	"polycry.pt/poly-go/sortedkv/memorydb"
+	"polycry.pt/poly-go/test"
+
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/channel"
+	chtest "github.com/perun-network/perun-fabric/channel/test"
+)
+
+func TestPersistentSubscriber(t *testing.T) {
+	const polling = 10 * time.Millisecond
+	rng := test.Prng(t)
+	ctx := context.Background()
+
+	// A short challenge duration on the ledger's fixed clock lets the
+	// registrations time out locally, while refutations are still accepted.
+	setup := adjtest.NewSetup(rng)
+	setup.Params.ChallengeDuration = 1
+	setup.State.ID = setup.Domain.CalcID(setup.Params.CoreParams())
+	setDomain(t, setup.Domain)
+	id := setup.State.ID
+
+	contracts := chtest.NewMemContracts(setup.Adj)
+	contract := contracts.Contract(setup.IDs[0])
+	for i, part := range setup.Parts {
+		require.NoError(t, contracts.Mint(setup.IDs[0], setup.State.Balances[i]))
+		require.NoError(t, contract.Deposit(id, part, setup.State.Balances[i]))
+	}
+	a := channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0], channel.WithSubPollingInterval(polling))
+	db := memorydb.NewDatabase()
+
+	// subscribe subscribes like a restarted client, with a new subscriber on
+	// the same database.
+	subscribe := func() *channel.EventSubscription {
+		t.Helper()
+		sub, err := channel.NewPersistentSubscriber(a, db).Subscribe(ctx, id)
+		require.NoError(t, err)
+		return sub.(*channel.EventSubscription)
+	}
+	requireRegistered := func(sub *channel.EventSubscription, version uint64) {
+		t.Helper()
+		e, ok := sub.Next().(*pchannel.RegisteredEvent)
+		require.True(t, ok, "expected RegisteredEvent")
+		require.Equal(t, version, e.Version())
+	}
+	requireNoEvent := func(sub *channel.EventSubscription) {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(ctx, 5*polling)
+		defer cancel()
+		require.Nil(t, sub.NextContext(ctx))
+		require.NoError(t, sub.Err())
+	}
+
+	sub := subscribe()
+	require.NoError(t, contract.Register(setup.SignedChannel()))
+	requireRegistered(sub, 0)
+	require.NoError(t, sub.Close())
+
+	// An event that was not handled is reported again. Requesting the next
+	// event records it as handled.
+	sub = subscribe()
+	requireRegistered(sub, 0)
+	requireNoEvent(sub)
+	require.NoError(t, sub.Close())
+
+	// A handled event is not reported again, but later events are.
+	sub = subscribe()
+	requireNoEvent(sub)
+	setup.State.Version = 1
+	setup.State.Balances[0], setup.State.Balances[1] = setup.State.Balances[1], setup.State.Balances[0]
+	require.NoError(t, contract.Register(setup.SignedChannel()))
+	requireRegistered(sub, 1)
+	_, ok := sub.Next().(*pchannel.ConcludedEvent)
+	require.True(t, ok, "expected ConcludedEvent")
+	require.NoError(t, sub.Close())
+
+	// Subscriptions to a concluded channel end right away.
+	sub = subscribe()
+	require.Nil(t, sub.Next())
+	require.NoError(t, sub.Err())
+
+	// Forgetting the channel starts over.
+	require.NoError(t, channel.NewPersistentSubscriber(a, db).Forget(id))
+	sub = subscribe()
+	requireRegistered(sub, 1)
+	require.NoError(t, sub.Close())
+}
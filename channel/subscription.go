@@ -18,126 +18,215 @@ import (
 	"context"
 	"fmt"
 	adj "github.com/perun-network/perun-fabric/adjudicator"
-	fabclient "github.com/perun-network/perun-fabric/client"
 	"sync"
-	"time"
 
 	"perun.network/go-perun/channel"
 )
 
 // EventSubscription provides methods for consuming channel events.
+//
+// A subscription ends once the channel is concluded, the state of the channel
+// cannot be queried repeatedly (see WithSubMaxQueryFailures), an invalid state
+// is queried, i.e., a state of another channel or of a lower version than the
+// previous one, or the subscription is closed. Afterwards, Next returns nil and Err returns the
+// error that ended the subscription, if any. The conclusion of the channel is
+// the regular end of the subscription, after which Err returns nil.
 type EventSubscription struct {
-	adjudicator *Adjudicator // adjudicator is the referenced adjudicator instance.
-	channelID   channel.ID   // channelID is the channel identifier.
-	prevState   adj.StateReg // prevState is the previous channel state.
-	timeout     *Timeout     // timeout is the current Event timeout.
-	concluded   bool         // concluded indicates if a concluded event was created.
-	registered  bool         // registered indicates if any state has been registered on-chain.
-	err         chan error   // err forwards errors during event parsing.
-	quit        chan bool    // quit indicates that the sub got closed.
-	closed      bool         // closed indicates that the channel is closed.
-	once        sync.Once    // once used to close() channels.
-	mtx         sync.Mutex   // mtx secures against a Close() call during the evaluation of detectEvent().
+	adjudicator *Adjudicator          // adjudicator is the referenced adjudicator instance.
+	channelID   channel.ID            // channelID is the channel identifier.
+	prevState   adj.StateReg          // prevState is the previous channel state.
+	timeout     *Timeout              // timeout is the current Event timeout.
+	registered  bool                  // registered indicates if any state has been registered on-chain.
+	updates     chan stateUpdate      // updates holds the latest state queried by the subscription manager.
+	done        chan struct{}         // done is closed when the subscription ends.
+	ended       bool                  // ended indicates that the subscription ended.
+	err         error                 // err is the error that ended the subscription, if any.
+	persister   *PersistentSubscriber // persister records the handled events, nil if the subscription is not persistent.
+	pending     *subscriptionRecord   // pending is the last reported event, which is recorded once the next event is requested.
+	mtx         sync.Mutex            // mtx secures against ending the subscription during the evaluation of detectEvent().
 }
 
 // NewEventSubscription generates a subscriber on the given channel.
+//
+// The subscription starts without knowledge of previous events. If a state is
+// already registered, it is reported once by a RegisteredEvent, or a
+// ConcludedEvent if it is final. Subscriptions of a PersistentSubscriber
+// instead resume after the last event handled before, e.g., before a restart
+// of the client.
+//
+// The channel's state is queried by the Adjudicator together with the states
+// of all other subscribed channels. If several states are registered between
+// two calls to Next, only the latest one is reported.
 func NewEventSubscription(a *Adjudicator, ch channel.ID) (*EventSubscription, error) {
-	return &EventSubscription{
+	return newEventSubscription(a, ch, nil, nil), nil
+}
+
+// newEventSubscription generates a subscriber on the given channel, which
+// records the handled events with the given persister, if any. The
+// subscription resumes after the given record, if any.
+func newEventSubscription(a *Adjudicator, ch channel.ID, p *PersistentSubscriber, rec *subscriptionRecord) *EventSubscription {
+	s := &EventSubscription{
 		adjudicator: a,
 		channelID:   ch,
-		err:         make(chan error, 1),
-		quit:        make(chan bool, 1),
+		updates:     make(chan stateUpdate, 1),
+		done:        make(chan struct{}),
 		prevState:   adj.StateReg{},
 		timeout:     nil,
-	}, nil
+		persister:   p,
+	}
+	if rec != nil {
+		s.prevState = rec.Reg
+		s.registered = true
+		s.timeout = a.makeTimeout(rec.Reg.Timeout)
+		if rec.Concluded {
+			// There are no further events.
+			s.ended = true
+			close(s.done)
+			return s
+		}
+	}
+	a.subs.add(s)
+	return s
 }
 
-// Next returns the most recent or next future event. If the subscription is
-// closed or any other error occurs, it returns nil.
+// Next returns the most recent or next future event. If the subscription
+// ended, it returns nil.
 func (s *EventSubscription) Next() channel.AdjudicatorEvent {
-	for {
-		event, err := s.detectEvent()
-		if err != nil {
-			return nil // Err is available in err chan if subscription is not closed.
-		} else if event != nil {
-			return event
-		}
+	return s.NextContext(context.Background())
+}
 
+// NextContext returns the most recent or next future event, like Next. It
+// also returns nil if the context is done, in which case the subscription
+// does not end and the reason is available from the context.
+func (s *EventSubscription) NextContext(ctx context.Context) channel.AdjudicatorEvent {
+	s.recordPending()
+	for {
 		select {
-		case <-s.quit:
+		case <-s.done:
+			return nil
+		case <-ctx.Done():
 			return nil
-		case <-time.After(s.adjudicator.polling):
+		case u := <-s.updates:
+			if event := s.detectEvent(u); event != nil {
+				return event
+			}
 		}
 	}
 }
 
-// Err returns the error status of the subscription. After Next returns nil,
-// Err should be checked for an error.
-func (s *EventSubscription) Err() error {
-	if s.closed {
-		return nil
+// recordPending records the last reported event, which the subscriber handled
+// if it requests the next event. If recording fails, the subscription ends
+// with the error.
+func (s *EventSubscription) recordPending() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.pending == nil || s.ended {
+		return
+	}
+	if err := s.persister.put(s.channelID, *s.pending); err != nil {
+		s.end(err)
+		return
+	}
+	s.pending = nil
+}
+
+// update replaces the pending state update of the subscription. It does not
+// block.
+func (s *EventSubscription) update(u stateUpdate) {
+	select {
+	case <-s.updates:
+	default:
+	}
+	select {
+	case s.updates <- u:
+	default:
 	}
-	return <-s.err
 }
 
-// Close closes the subscription.
+// Err returns the error that ended the subscription. It returns nil if the
+// subscription did not end, was closed or ended with the conclusion of the
+// channel. After Next returns nil, Err should be checked for an error.
+func (s *EventSubscription) Err() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.err
+}
+
+// Close closes the subscription. It may be called concurrently and more
+// than once. Calls to Next that wait for an event return nil.
 func (s *EventSubscription) Close() error {
-	s.once.Do(func() {
-		s.mtx.Lock()
-		defer s.mtx.Unlock()
-
-		s.closed = true
-		close(s.quit)
-		close(s.err)
-	})
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.end(nil)
 	return nil
 }
 
-// detectEvent compares the previous and current state of the channel to derive new chain events.
-func (s *EventSubscription) detectEvent() (channel.AdjudicatorEvent, error) {
+// end ends the subscription with the given error, unless it already ended.
+// The mutex must be held.
+func (s *EventSubscription) end(err error) {
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.err = err
+	s.adjudicator.subs.remove(s)
+	close(s.done)
+}
+
+// detectEvent compares the previous and the queried state of the channel to derive new chain events.
+// After a ConcludedEvent or an error, the subscription ends.
+func (s *EventSubscription) detectEvent(u stateUpdate) channel.AdjudicatorEvent {
 	// Lock to prevent closing during evaluation.
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	// Abort if subscription got closed.
-	if s.closed {
-		return nil, fmt.Errorf("subscription closed")
+	// Abort if subscription ended.
+	if s.ended {
+		return nil
 	}
 
 	// Get the on chain state.
-	d, err := s.fetchState()
+	d, err := s.checkState(u)
 	if err != nil {
-		s.err <- err
-		return nil, err
+		s.end(err)
+		return nil
 	}
 
 	// Only progress if some state is registered.
-	if s.registered { //nolint:nestif
-		if !s.concluded {
-			// If channel isFinal or the timeout elapsed the channel is concluded.
-			if d.IsFinal || s.timeoutElapsed() {
-				s.concluded = true // ConcludedEvent is only emitted once.
-				return s.makeConcludedEvent(d), nil
-			}
+	if !s.registered {
+		return nil
+	}
+
+	// A state change of a non-final state indicates a registered event.
+	// It is checked before the timeout of the previous state, so that
+	// a refutation is not missed if the local clock is ahead of the
+	// ledger's.
+	if !d.IsFinal && !d.Equal(s.prevState) {
+		s.prevState = *d
+		if s.persister != nil {
+			s.pending = &subscriptionRecord{Reg: *d.Clone()}
+		}
+		return s.makeRegisteredEvent(d)
+	}
 
-			// Otherwise, check state change which indicates a registered event.
-			if !d.Equal(s.prevState) {
-				s.prevState = *d
-				return s.makeRegisteredEvent(d), nil
+	// If channel isFinal or the timeout elapsed the channel is concluded.
+	// There will be no further events.
+	if d.IsFinal || s.timeoutElapsed() {
+		if s.persister != nil {
+			if err := s.persister.put(s.channelID, subscriptionRecord{Reg: *d.Clone(), Concluded: true}); err != nil {
+				s.end(err)
+				return nil
 			}
-		} else {
-			// There will be no further events because the ConcludedEvent got already returned.
-			err = fmt.Errorf("already concluded")
-			s.err <- err
-			return nil, err
 		}
+		s.end(nil)
+		return s.makeConcludedEvent(d)
 	}
-	return nil, nil
+	return nil
 }
 
 // makeRegisteredEvent returns a new registered event dependent on the given state.
 func (s *EventSubscription) makeRegisteredEvent(d *adj.StateReg) channel.AdjudicatorEvent {
-	s.timeout = MakeTimeout(d.Timeout.Time(), s.adjudicator.polling)
+	s.timeout = s.adjudicator.makeTimeout(d.Timeout)
 	state := d.State.CoreState()
 	cID := state.ID
 	v := state.Version
@@ -147,7 +236,7 @@ func (s *EventSubscription) makeRegisteredEvent(d *adj.StateReg) channel.Adjudic
 
 // makeConcludedEvent returns a new concluded or registered event dependent on the given state and timeout.
 func (s *EventSubscription) makeConcludedEvent(d *adj.StateReg) channel.AdjudicatorEvent {
-	s.timeout = MakeTimeout(d.Timeout.Time(), s.adjudicator.polling)
+	s.timeout = s.adjudicator.makeTimeout(d.Timeout)
 	state := d.State.CoreState()
 	cID := state.ID
 	v := state.Version
@@ -163,20 +252,29 @@ func (s *EventSubscription) timeoutElapsed() bool {
 	return s.timeout.IsElapsed(context.Background())
 }
 
-// fetchState queries the current channel state from the ledger.
-// If there is no state available yet, pass.
-func (s *EventSubscription) fetchState() (*adj.StateReg, error) {
-	ch := s.channelID
-	d, err := s.adjudicator.binding.StateReg(ch)
+// checkState checks the queried state of the channel.
+// If there is no state available yet, pass. A registered state must belong to
+// the channel and its version must not be older than that of the previous
+// state.
+func (s *EventSubscription) checkState(u stateUpdate) (*adj.StateReg, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
 
 	// Check fist time registration.
-	if err != nil {
-		if s.registered || !fabclient.IsChannelUnknownErr(err) {
-			return nil, err
+	if u.reg == nil {
+		if s.registered {
+			return nil, fmt.Errorf("registered state of channel %x vanished", s.channelID)
 		}
-	} else if !s.registered {
-		s.registered = true
+		return u.reg, nil
 	}
 
-	return d, nil
+	if u.reg.ID != s.channelID {
+		return nil, fmt.Errorf("registered state of channel %x queried for channel %x", u.reg.ID, s.channelID)
+	} else if u.reg.Version < s.prevState.Version {
+		return nil, fmt.Errorf("registered version %d of channel %x older than previous version %d",
+			u.reg.Version, s.channelID, s.prevState.Version)
+	}
+	s.registered = true
+	return u.reg, nil
 }
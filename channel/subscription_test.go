@@ -0,0 +1,253 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package channel_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pchannel "perun.network/go-perun/channel"
+	"polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/channel"
+	chtest "github.com/perun-network/perun-fabric/channel/test"
+)
+
+func TestEventSubscription(t *testing.T) {
+	const polling = 10 * time.Millisecond
+	rng := test.Prng(t)
+	ctx := context.Background()
+
+	// A short challenge duration on the ledger's fixed clock lets the
+	// registrations time out locally, while refutations are still accepted.
+	setup := adjtest.NewSetup(rng)
+	setup.Params.ChallengeDuration = 1
+	setup.State.ID = setup.Domain.CalcID(setup.Params.CoreParams())
+	setDomain(t, setup.Domain)
+	id := setup.State.ID
+
+	contracts := chtest.NewMemContracts(setup.Adj)
+	contract := contracts.Contract(setup.IDs[0])
+	for i, part := range setup.Parts {
+		require.NoError(t, contracts.Mint(setup.IDs[0], setup.State.Balances[i]))
+		require.NoError(t, contract.Deposit(id, part, setup.State.Balances[i]))
+	}
+	a := channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0], channel.WithSubPollingInterval(polling))
+
+	requireRegistered := func(sub pchannel.AdjudicatorSubscription, version uint64) {
+		t.Helper()
+		e, ok := sub.Next().(*pchannel.RegisteredEvent)
+		require.True(t, ok, "expected RegisteredEvent")
+		require.Equal(t, id, e.ID())
+		require.Equal(t, version, e.Version())
+	}
+
+	sub, err := a.Subscribe(ctx, id)
+	require.NoError(t, err)
+	require.NoError(t, contract.Register(setup.SignedChannel()))
+	requireRegistered(sub, 0)
+
+	// A refutation is reported, the unchanged state not again.
+	setup.State.Version = 1
+	setup.State.Balances[0], setup.State.Balances[1] = setup.State.Balances[1], setup.State.Balances[0]
+	require.NoError(t, contract.Register(setup.SignedChannel()))
+	requireRegistered(sub, 1)
+
+	// A new subscription, e.g., after a restart, reports the current state
+	// once.
+	resub, err := a.Subscribe(ctx, id)
+	require.NoError(t, err)
+	requireRegistered(resub, 1)
+
+	for _, s := range []pchannel.AdjudicatorSubscription{sub, resub} {
+		e, ok := s.Next().(*pchannel.ConcludedEvent)
+		require.True(t, ok, "expected ConcludedEvent")
+		require.Equal(t, uint64(1), e.Version())
+		require.NoError(t, s.Close())
+	}
+
+	// A final state is reported as concluded right away.
+	setup = adjtest.NewSetup(rng, adjtest.WithFinalState)
+	id = setup.State.ID
+	contract = chtest.NewMemContracts(setup.Adj).Contract(setup.IDs[0])
+	a = channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0], channel.WithSubPollingInterval(polling))
+	sub, err = a.Subscribe(ctx, id)
+	require.NoError(t, err)
+	require.NoError(t, contract.Register(setup.SignedChannel()))
+	_, ok := sub.Next().(*pchannel.ConcludedEvent)
+	require.True(t, ok, "expected ConcludedEvent")
+	require.NoError(t, sub.Close())
+}
+
+// failingContract fails all state queries with err, once set. Otherwise, it
+// answers all state queries with reg, once set.
+type failingContract struct {
+	*chtest.MemContract
+	mutex sync.Mutex
+	err   error
+	reg   *adj.StateReg
+}
+
+func (c *failingContract) StateRegs(ids []pchannel.ID) ([]*adj.StateReg, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	} else if c.reg != nil {
+		regs := make([]*adj.StateReg, len(ids))
+		for i := range regs {
+			regs[i] = c.reg.Clone()
+		}
+		return regs, nil
+	}
+	return c.MemContract.StateRegs(ids)
+}
+
+func (c *failingContract) serve(reg *adj.StateReg) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.reg = reg
+}
+
+func (c *failingContract) fail(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.err = err
+}
+
+func TestEventSubscription_Lifecycle(t *testing.T) {
+	const polling = 10 * time.Millisecond
+
+	setupSub := func(t *testing.T) (*failingContract, *adj.SignedChannel, *channel.EventSubscription) {
+		t.Helper()
+		setup := adjtest.NewSetup(test.Prng(t))
+		setup.Params.ChallengeDuration = 1
+		setup.State.ID = setup.Domain.CalcID(setup.Params.CoreParams())
+		setDomain(t, setup.Domain)
+		contract := &failingContract{MemContract: chtest.NewMemContracts(setup.Adj).Contract(setup.IDs[0])}
+		a := channel.NewAdjudicatorWithContract(contract, setup.Domain, setup.IDs[0],
+			channel.WithSubPollingInterval(polling), channel.WithSubMaxQueryFailures(2))
+		sub, err := channel.NewEventSubscription(a, setup.State.ID)
+		require.NoError(t, err)
+		return contract, setup.SignedChannel(), sub
+	}
+
+	t.Run("Active", func(t *testing.T) {
+		_, _, sub := setupSub(t)
+		defer sub.Close()
+		// Err does not block on an active subscription.
+		require.NoError(t, sub.Err())
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		_, _, sub := setupSub(t)
+		next := make(chan pchannel.AdjudicatorEvent)
+		go func() { next <- sub.Next() }()
+
+		// Concurrent and repeated calls to Close end waiting calls to Next.
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				require.NoError(t, sub.Close())
+			}()
+		}
+		wg.Wait()
+		require.Nil(t, <-next)
+		require.Nil(t, sub.Next())
+		require.NoError(t, sub.Err())
+		require.NoError(t, sub.Close())
+	})
+
+	t.Run("Concluded", func(t *testing.T) {
+		contract, ch, sub := setupSub(t)
+		require.NoError(t, contract.Register(ch))
+		require.IsType(t, &pchannel.RegisteredEvent{}, sub.Next())
+		require.IsType(t, &pchannel.ConcludedEvent{}, sub.Next())
+
+		// The conclusion is the regular end of the subscription.
+		require.Nil(t, sub.Next())
+		require.NoError(t, sub.Err())
+		require.NoError(t, sub.Close())
+		require.NoError(t, sub.Err())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		contract, _, sub := setupSub(t)
+		errQuery := errors.New("query failed")
+		contract.fail(errQuery)
+		require.Nil(t, sub.Next())
+		require.ErrorIs(t, sub.Err(), errQuery)
+
+		// The error is set once and is kept when closing.
+		contract.fail(errors.New("other error"))
+		require.Nil(t, sub.Next())
+		require.ErrorIs(t, sub.Err(), errQuery)
+		require.NoError(t, sub.Close())
+		require.ErrorIs(t, sub.Err(), errQuery)
+	})
+
+	t.Run("InvalidState", func(t *testing.T) {
+		// The served states do not time out during the test.
+		stateReg := func(ch *adj.SignedChannel, version uint64) *adj.StateReg {
+			reg := &adj.StateReg{State: ch.State.Clone(), Timeout: adj.StdNow().Add(3600)}
+			reg.Version = version
+			return reg
+		}
+
+		contract, ch, sub := setupSub(t)
+		contract.serve(stateReg(ch, 5))
+		e, ok := sub.Next().(*pchannel.RegisteredEvent)
+		require.True(t, ok, "expected RegisteredEvent")
+		require.Equal(t, uint64(5), e.Version())
+
+		// An older version ends the subscription.
+		contract.serve(stateReg(ch, 4))
+		require.Nil(t, sub.Next())
+		require.Contains(t, sub.Err().Error(), "older than previous version 5")
+		require.NoError(t, sub.Close())
+
+		// So does the state of another channel.
+		contract, ch, sub = setupSub(t)
+		reg := stateReg(ch, 0)
+		reg.ID[0]++
+		contract.serve(reg)
+		require.Nil(t, sub.Next())
+		require.Contains(t, sub.Err().Error(), "queried for channel")
+		require.NoError(t, sub.Close())
+	})
+
+	t.Run("NextContext", func(t *testing.T) {
+		contract, ch, sub := setupSub(t)
+		defer sub.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*polling)
+		defer cancel()
+
+		// A canceled call does not end the subscription.
+		require.Nil(t, sub.NextContext(ctx))
+		require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+		require.NoError(t, sub.Err())
+
+		require.NoError(t, contract.Register(ch))
+		require.IsType(t, &pchannel.RegisteredEvent{}, sub.NextContext(context.Background()))
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"sync"
+	"time"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+type (
+	// FakeClock is a channel.Clock whose time only changes when it is advanced
+	// with Advance. It lets timeouts elapse instantly in tests.
+	FakeClock struct {
+		mutex  sync.Mutex
+		cond   *sync.Cond
+		now    time.Time
+		timers []fakeTimer
+	}
+
+	// fakeTimer is a pending call of FakeClock.After.
+	fakeTimer struct {
+		deadline time.Time
+		c        chan time.Time
+	}
+
+	// ClockLedger is a MemLedger whose time is read from a FakeClock, so that
+	// the channel timeouts and the ledger's challenge periods elapse together.
+	ClockLedger struct {
+		*adj.MemLedger
+		clock *FakeClock
+	}
+)
+
+// NewFakeClock creates a FakeClock starting at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mutex)
+	return c
+}
+
+// Now returns the current time of the FakeClock.
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// After returns a channel on which the time is sent once the FakeClock has
+// been advanced by d. A non-positive duration fires immediately.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, fakeTimer{deadline: c.now.Add(d), c: ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+// Advance advances the FakeClock by d and fires all timers that are due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = pending
+}
+
+// BlockUntil blocks until at least n timers are pending, i.e., until callers
+// of After wait for the FakeClock to be advanced.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// NewClockLedger creates an in-memory ledger whose time is read from the given
+// FakeClock.
+func NewClockLedger(clock *FakeClock) *ClockLedger {
+	return &ClockLedger{MemLedger: adj.NewMemLedger(), clock: clock}
+}
+
+// Now returns the current time of the FakeClock.
+func (l *ClockLedger) Now() adj.Timestamp {
+	return adj.Timestamp(l.clock.Now())
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"math/big"
+	"sync"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+// MemContracts provides access to an in-memory Adjudicator contract for
+// several clients. It serializes all calls, like the ledger serializes
+// transactions.
+type MemContracts struct {
+	mutex sync.Mutex
+	adj   *adj.Adjudicator
+	book  *adj.AddressBook
+}
+
+// MemContract is the in-memory Adjudicator contract as seen by a single
+// client. It implements channel.Contract and the address book, holding and
+// token transactions of the chaincode binding.
+type MemContract struct {
+	contracts *MemContracts
+	caller    adj.AccountID
+}
+
+// NewMemContracts creates the in-memory contract of the given Adjudicator,
+// together with an in-memory address book in its domain.
+func NewMemContracts(a *adj.Adjudicator) *MemContracts {
+	return &MemContracts{adj: a, book: adj.NewAddressBook(a.Domain(), adj.NewMemLedger())}
+}
+
+// Contract returns the contract as seen by the client with the given
+// AccountID, which is the sender of deposits.
+func (m *MemContracts) Contract(caller adj.AccountID) *MemContract {
+	return &MemContract{contracts: m, caller: caller}
+}
+
+// Mint mints the given amount of tokens for the given AccountID.
+func (m *MemContracts) Mint(id adj.AccountID, amount *big.Int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.adj.Mint(id, amount)
+}
+
+// TokenBalance returns the token balance of the given AccountID.
+func (m *MemContracts) TokenBalance(id adj.AccountID) (*big.Int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.adj.BalanceOfID(id)
+}
+
+// Deposit deposits the given amount from the caller's tokens for participant
+// part into channel id.
+func (c *MemContract) Deposit(id channel.ID, part wallet.Address, amount *big.Int) error {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.Deposit(c.caller, id, part, amount)
+}
+
+// Holding returns the holding of participant part in channel id.
+func (c *MemContract) Holding(id channel.ID, part wallet.Address) (*big.Int, error) {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.Holding(id, part)
+}
+
+// TotalHolding returns the sum of the holdings of all participants.
+func (c *MemContract) TotalHolding(id channel.ID, parts []wallet.Address) (*big.Int, error) {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.TotalHolding(id, parts)
+}
+
+// Register registers the given signed channel state.
+func (c *MemContract) Register(ch *adj.SignedChannel) error {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.Register(ch)
+}
+
+// StateReg returns the currently registered state of channel id.
+func (c *MemContract) StateReg(id channel.ID) (*adj.StateReg, error) {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.StateReg(id)
+}
+
+// StateRegs returns the currently registered states of the given channels.
+func (c *MemContract) StateRegs(ids []channel.ID) ([]*adj.StateReg, error) {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.StateRegs(ids)
+}
+
+// Withdraw withdraws the funds of the request's participant.
+func (c *MemContract) Withdraw(req adj.SignedWithdrawReq) (*big.Int, error) {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.Withdraw(req)
+}
+
+// ConcludeFinal registers the final state and withdraws the funds of the
+// given requests.
+func (c *MemContract) ConcludeFinal(ch *adj.SignedChannel, reqs []adj.SignedWithdrawReq) ([]*big.Int, error) {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.ConcludeFinal(ch, reqs)
+}
+
+// MintToken mints the given amount of tokens for the caller.
+func (c *MemContract) MintToken(amount *big.Int) error {
+	return c.contracts.Mint(c.caller, amount)
+}
+
+// BurnToken burns the given amount of the caller's tokens.
+func (c *MemContract) BurnToken(amount *big.Int) error {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.Burn(c.caller, amount)
+}
+
+// TokenTransfer transfers the given amount of the caller's tokens to the
+// receiver.
+func (c *MemContract) TokenTransfer(receiver adj.AccountID, amount *big.Int) error {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.adj.Transfer(c.caller, receiver, amount)
+}
+
+// TokenBalance returns the token balance of the given AccountID.
+func (c *MemContract) TokenBalance(owner adj.AccountID) (*big.Int, error) {
+	return c.contracts.TokenBalance(owner)
+}
+
+// RegisterAddress registers the signed registration of the caller in the
+// address book.
+func (c *MemContract) RegisterAddress(reg *adj.SignedRegistration) error {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.book.Register(c.caller, reg)
+}
+
+// RegistrationByAccountID returns the registration of the given AccountID.
+func (c *MemContract) RegistrationByAccountID(id adj.AccountID) (*adj.SignedRegistration, error) {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.book.ByAccountID(id)
+}
+
+// RegistrationByAddress returns the registration of the given wallet address.
+func (c *MemContract) RegistrationByAddress(addr wallet.Address) (*adj.SignedRegistration, error) {
+	c.contracts.mutex.Lock()
+	defer c.contracts.mutex.Unlock()
+	return c.contracts.book.ByAddress(addr)
+}
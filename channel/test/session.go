@@ -31,13 +31,15 @@ type Session struct {
 	Binding        *binding.Adjudicator
 	Funder         *channel.Funder
 	Account        *wallet.Account
+	Network        *client.Network
 	conn           *grpc.ClientConn
 	gw             *client.Gateway
 }
 
 // NewTestSession generates a new session for testing the fabric backend with.
 // The user certificate is chosen by the given organization.
-// For connecting with the chaincode the adjudicator id is given.
+// For connecting with the chaincode the adjudicator id is given. The channel
+// backend domain is set to the domain of this adjudicator.
 func NewTestSession(org Org, adjudicator string) (*Session, error) {
 	clientConn, err := NewGrpcConnection(org)
 	if err != nil {
@@ -52,12 +54,14 @@ func NewTestSession(org Org, adjudicator string) (*Session, error) {
 	}
 
 	network := gateway.GetNetwork(ChannelName)
+	channel.SetDomain(adj.NewDomain(ChannelName, adjudicator))
 	return &Session{
 		ClientFabricID: clientID,
 		Adjudicator:    channel.NewAdjudicator(network, adjudicator, clientID),
 		Binding:        binding.NewAdjudicatorBinding(network, adjudicator),
 		Funder:         channel.NewFunder(network, adjudicator),
 		Account:        acc,
+		Network:        network,
 		conn:           clientConn,
 		gw:             gateway,
 	}, nil
@@ -15,6 +15,7 @@
 package test
 
 import (
+	"crypto/x509"
 	"fmt"
 	adj "github.com/perun-network/perun-fabric/adjudicator"
 	"log"
@@ -28,6 +29,7 @@ import (
 
 	pclient "github.com/perun-network/perun-fabric/client"
 	"github.com/perun-network/perun-fabric/wallet"
+	"github.com/perun-network/perun-fabric/wire"
 )
 
 const (
@@ -97,22 +99,12 @@ func cryptoPath(org Org) string {
 	return path.Join(fabricSamplesPath, "test-network/organizations/peerOrganizations/"+string(org)+".example.com")
 }
 
-func certPath(org Org) string {
-	return cryptoPath(org) + "/users/User1@" + string(org) + ".example.com/msp/signcerts/cert.pem"
+func mspPath(org Org) string {
+	return cryptoPath(org) + "/users/User1@" + string(org) + ".example.com/msp"
 }
 
-func keyDir(org Org) string {
-	return cryptoPath(org) + "/users/User1@" + string(org) + ".example.com/msp/keystore/"
-}
-
-func keyPath(org Org) (string, error) {
-	kp := keyDir(org)
-	files, err := os.ReadDir(kp)
-	if err != nil {
-		return "", fmt.Errorf("reading private key directory: %w", err)
-	}
-	// the first and only file in the keystore is the secret key
-	return path.Join(kp, files[0].Name()), nil
+func caCertPath(org Org) string {
+	return cryptoPath(org) + "/ca/ca." + string(org) + ".example.com-cert.pem"
 }
 
 func tlsCertPath(org Org) string {
@@ -126,37 +118,54 @@ func NewGrpcConnection(org Org) (*grpc.ClientConn, error) {
 
 // NewIdentity creates a client identity for this Gateway connection using an X.509 certificate.
 func NewIdentity(org Org) (*identity.X509Identity, *wallet.Address, adj.AccountID, error) {
-	return pclient.NewIdentity(mspID(org), certPath(org))
+	id, err := pclient.LoadMSPIdentity(mspID(org), mspPath(org))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return id.X509, id.Address, id.AccountID, nil
 }
 
 // NewAccountWithSigner creates a function that generates a digital signature from a message digest using a private key.
 func NewAccountWithSigner(org Org) (identity.Sign, *wallet.Account, error) {
-	path, err := keyPath(org)
+	id, err := pclient.LoadMSPIdentity(mspID(org), mspPath(org))
 	if err != nil {
 		return nil, nil, err
 	}
-	return pclient.NewAccountWithSigner(path)
+	return id.Sign, id.Account, nil
 }
 
-// NewGateway creates a Gateway for a specific client identity with several timeouts for gRPC calls.
-func NewGateway(org Org, clientConn *grpc.ClientConn) (*client.Gateway, *wallet.Account, adj.AccountID, error) {
-	id, addr, onChainID, err := NewIdentity(org)
+// LoadIdentity loads the client identity of the organization.
+func LoadIdentity(org Org) (*pclient.Identity, error) {
+	return pclient.LoadMSPIdentity(mspID(org), mspPath(org))
+}
+
+// NewCACertPool returns the pool of the CA certificates of both demo
+// organizations, which authenticate the wire identities of their clients.
+func NewCACertPool() (*x509.CertPool, error) {
+	return pclient.ReadCertPool(caCertPath(Org1), caCertPath(Org2))
+}
+
+// NewWireAccount creates the Perun wire account of the organization's client
+// identity, backed by its X.509 certificate.
+func NewWireAccount(org Org) (*wire.Account, error) {
+	id, err := pclient.LoadMSPIdentity(mspID(org), mspPath(org))
 	if err != nil {
-		return nil, nil, "", err
+		return nil, err
 	}
-	sign, acc, err := NewAccountWithSigner(org)
+	return id.WireAccount()
+}
+
+// NewGateway creates a Gateway for a specific client identity with several timeouts for gRPC calls.
+func NewGateway(org Org, clientConn *grpc.ClientConn) (*client.Gateway, *wallet.Account, adj.AccountID, error) {
+	id, err := pclient.LoadMSPIdentity(mspID(org), mspPath(org))
 	if err != nil {
 		return nil, nil, "", err
 	}
 
-	if acca := acc.Address(); !acca.Equal(addr) {
-		return nil, nil, "", fmt.Errorf("identity and signer public key mismatch, %v != %v", acca, addr)
-	}
-
 	// Create a Gateway connection for a specific client identity
 	gw, err := client.Connect(
-		id,
-		client.WithSign(sign),
+		id.X509,
+		client.WithSign(id.Sign),
 		client.WithClientConnection(clientConn),
 		// Default timeouts for different gRPC calls
 		client.WithEvaluateTimeout(evalTimeout),
@@ -165,7 +174,7 @@ func NewGateway(org Org, clientConn *grpc.ClientConn) (*client.Gateway, *wallet.
 		client.WithCommitStatusTimeout(commitTimeout),
 	)
 
-	return gw, acc, onChainID, err
+	return gw, id.Account, id.AccountID, err
 }
 
 // FatalErr prints msg followed by err and then exits the program immediately, if
@@ -23,21 +23,29 @@ import (
 type Timeout struct {
 	timeout time.Time     // timeout is the time representing the timeout in UTC.
 	polling time.Duration // polling is used to periodically check if the timeout elapsed.
+	clock   Clock         // clock is the clock the timeout is evaluated with.
 }
 
 // MakeTimeout generates a timeout with the given time as wall.
 // Timeout is expected to be given in UTC.
 func MakeTimeout(t time.Time, polling time.Duration) *Timeout {
+	return MakeTimeoutWithClock(t, polling, SystemClock{})
+}
+
+// MakeTimeoutWithClock generates a timeout with the given time as wall that
+// is evaluated with the given clock.
+func MakeTimeoutWithClock(t time.Time, polling time.Duration, clock Clock) *Timeout {
 	return &Timeout{
 		timeout: t,
 		polling: polling,
+		clock:   clock,
 	}
 }
 
 // IsElapsed should return whether the timeout has concluded at the time of the call of this method.
 func (t *Timeout) IsElapsed(ctx context.Context) bool {
-	current := time.Now().UTC()     // Fabric does not have a block time.
-	return current.After(t.timeout) // Instead, use the UTC system time to compare against.
+	current := t.clock.Now().UTC()  // Fabric does not have a block time.
+	return current.After(t.timeout) // Instead, use the UTC time of the clock to compare against.
 }
 
 // Wait waits for the timeout to elapse.
@@ -47,7 +55,7 @@ func (t *Timeout) Wait(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(t.polling):
+		case <-t.clock.After(t.polling):
 		}
 	}
 	return nil
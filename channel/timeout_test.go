@@ -17,6 +17,7 @@ package channel_test
 import (
 	"context"
 	"github.com/perun-network/perun-fabric/channel"
+	"github.com/perun-network/perun-fabric/channel/test"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -56,4 +57,21 @@ func TestTimeout(t *testing.T) {
 		err := t0.Wait(ctx)
 		assert.Error(t, ctx.Err(), err)
 	})
+
+	t.Run("Clock", func(t *testing.T) {
+		clock := test.NewFakeClock(time.Now().UTC())
+		t0 := channel.MakeTimeoutWithClock(clock.Now().Add(duration), polling, clock)
+		assert.False(t, t0.IsElapsed(context.Background()))
+
+		waited := make(chan error, 1)
+		go func() { waited <- t0.Wait(context.Background()) }()
+		clock.BlockUntil(1)
+		clock.Advance(duration)
+		assert.False(t, t0.IsElapsed(context.Background()))
+
+		clock.BlockUntil(1)
+		clock.Advance(polling)
+		assert.NoError(t, <-waited)
+		assert.True(t, t0.IsElapsed(context.Background()))
+	})
 }
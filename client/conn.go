@@ -15,25 +15,28 @@
 package client
 
 import (
-	"crypto/ecdsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	adj "github.com/perun-network/perun-fabric/adjudicator"
 	"os"
 
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
 	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go/msp"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 
 	"github.com/perun-network/perun-fabric/wallet"
 )
 
 // NewGrpcConnection creates a gRPC connection to the Gateway server.
-func NewGrpcConnection(gatewayPeer, peerEndpoint, peerTLSCertPath string) (*grpc.ClientConn, error) {
+func NewGrpcConnection(gatewayPeer, peerEndpoint, peerTLSCertPath string, opts ...ConnOpt) (*grpc.ClientConn, error) {
 	cert, err := ReadCertificate(peerTLSCertPath)
 	if err != nil {
 		return nil, fmt.Errorf("loading certificate: %w", err)
@@ -41,9 +44,18 @@ func NewGrpcConnection(gatewayPeer, peerEndpoint, peerTLSCertPath string) (*grpc
 
 	certPool := x509.NewCertPool()
 	certPool.AddCert(cert)
-	transportCredentials := credentials.NewClientTLSFromCert(certPool, gatewayPeer)
+	return NewGrpcConnectionWithCertPool(gatewayPeer, peerEndpoint, certPool, opts...)
+}
 
-	connection, err := grpc.Dial(peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+// NewGrpcConnectionWithCertPool creates a gRPC connection to the Gateway server
+// whose TLS certificate is verified against the given pool.
+func NewGrpcConnectionWithCertPool(gatewayPeer, peerEndpoint string, certPool *x509.CertPool,
+	opts ...ConnOpt) (*grpc.ClientConn, error) {
+	var cfg connConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	connection, err := grpc.Dial(peerEndpoint, grpc.WithTransportCredentials(transportCredentials(certPool, gatewayPeer, cfg)))
 	if err != nil {
 		return nil, fmt.Errorf("creating gRPC connection: %w", err)
 	}
@@ -58,7 +70,10 @@ func NewIdentity(mspID, certPath string) (*identity.X509Identity, *wallet.Addres
 	if err != nil {
 		return nil, nil, "", fmt.Errorf("loading certificate: %w", err)
 	}
+	return newIdentity(mspID, cert)
+}
 
+func newIdentity(mspID string, cert *x509.Certificate) (*identity.X509Identity, *wallet.Address, adj.AccountID, error) {
 	addr, err := wallet.AddressFromX509Certificate(cert)
 	if err != nil {
 		return nil, nil, "", err
@@ -83,23 +98,27 @@ func NewAccountWithSigner(privateKeyPEMPath string) (identity.Sign, *wallet.Acco
 	if err != nil {
 		return nil, nil, fmt.Errorf("reading private key file: %w", err)
 	}
+	return newAccountWithSigner(privateKeyPEM)
+}
 
+func newAccountWithSigner(privateKeyPEM []byte) (identity.Sign, *wallet.Account, error) {
 	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
 	if err != nil {
 		return nil, nil, fmt.Errorf("parsing private key PEM: %w", err)
 	}
 
-	ecdsaPrivateKey, ok := privateKey.(*ecdsa.PrivateKey)
-	if !ok {
-		return nil, nil, fmt.Errorf("private key of unexpected type %T", privateKey)
+	acc, err := wallet.AccountFromPrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	// The gateway client only signs transactions with ECDSA keys.
 	sign, err := identity.NewPrivateKeySign(privateKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("creating signer: %w", err)
 	}
 
-	return sign, (*wallet.Account)(ecdsaPrivateKey), nil
+	return sign, acc, nil
 }
 
 // ReadCertificate takes the given path to the certificate file, reads it and returns a x509.Certificate.
@@ -111,6 +130,66 @@ func ReadCertificate(filename string) (*x509.Certificate, error) {
 	return identity.CertificateFromPEM(certificatePEM)
 }
 
+// ReadCertPool reads the given certificate files, usually the CA certificates
+// of the trusted organizations, into a certificate pool.
+func ReadCertPool(filenames ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, f := range filenames {
+		cert, err := ReadCertificate(f)
+		if err != nil {
+			return nil, fmt.Errorf("loading certificate %s: %w", f, err)
+		}
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// AccountIDFromIdentity returns the on-chain AccountID of the given X.509
+// client identity, as derived by the chaincode. Idemix identities have no
+// stable identifier, use IdemixAccount for them.
+func AccountIDFromIdentity(id identity.Identity) (adj.AccountID, error) {
+	block, _ := pem.Decode(id.Credentials())
+	if block == nil {
+		return "", errors.New("identity without certificate, see IdemixAccount for Idemix identities")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("parsing certificate: %w", err)
+	}
+	onChainID, err := calcOnChainCertID(cert)
+	return adj.AccountID(onChainID), err
+}
+
+// IdemixAccount authorizes the given Idemix client identity to act on behalf
+// of the AccountID of the given account key, see adj.IdemixAuth. It returns
+// the AccountID and the transient data that must be sent with every
+// transaction of the identity, see binding.Adjudicator.Transient. The
+// identity's credentials are a serialized Idemix identity and must not change
+// between transactions.
+func IdemixAccount(id identity.Identity, acc *wallet.Account) (adj.AccountID, map[string][]byte, error) {
+	var idemix msp.SerializedIdemixIdentity
+	if err := proto.Unmarshal(id.Credentials(), &idemix); err != nil {
+		return "", nil, fmt.Errorf("unmarshaling idemix identity: %w", err)
+	} else if len(idemix.NymX) == 0 || len(idemix.NymY) == 0 {
+		return "", nil, errors.New("idemix identity without pseudonym")
+	}
+
+	// The creator as serialized by the Gateway client.
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: id.MspID(), IdBytes: id.Credentials()})
+	if err != nil {
+		return "", nil, fmt.Errorf("marshaling creator: %w", err)
+	}
+	auth, err := adj.SignIdemixAuth(acc, creator)
+	if err != nil {
+		return "", nil, err
+	}
+	authData, err := json.Marshal(auth)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshaling idemix auth: %w", err)
+	}
+	return adj.IdemixAccountID(id.MspID(), acc.Address()), map[string][]byte{adj.IdemixAuthKey: authData}, nil
+}
+
 // calcOnChainCertID returns a unique ID associated with the invoking identity.
 // This code is a direct copy of GetID() in the fabric-chaincode-go sdk as it is not exposed there.
 // https://github.com/hyperledger/fabric-chaincode-go/blob/9207360bbddd5952479c24154353b82c4c044677/pkg/cid/cid.go#L96
@@ -18,12 +18,12 @@ import (
 	"context"
 	"github.com/perun-network/perun-fabric/channel"
 	ctest "github.com/perun-network/perun-fabric/client/test"
+	"github.com/perun-network/perun-fabric/wire"
 	"math/big"
 	"math/rand"
 	pchannel "perun.network/go-perun/channel"
 	clienttest "perun.network/go-perun/client/test"
-	"perun.network/go-perun/wire"
-	simplewire "perun.network/go-perun/wire/net/simple"
+	pwire "perun.network/go-perun/wire"
 	wiretest "perun.network/go-perun/wire/test"
 	"testing"
 	"time"
@@ -46,7 +46,7 @@ func TestFundRecovery(t *testing.T) {
 	)
 
 	_, setup, _ = ctest.SetupClientTest(t, names, disputeChallengeDur) // Adjudicator and initial bals not needed.
-	wiretest.SetNewRandomAccount(func(rng *rand.Rand) wire.Account { return simplewire.NewRandomAccount(rng) })
+	wiretest.SetNewRandomAccount(func(rng *rand.Rand) pwire.Account { return wire.NewRandomAccount(rng) })
 	clienttest.TestFundRecovery(
 		ctx,
 		t,
@@ -0,0 +1,184 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/wallet"
+	"github.com/perun-network/perun-fabric/wire"
+)
+
+const (
+	mspSignCertsDir = "signcerts"
+	mspKeystoreDir  = "keystore"
+
+	walletIdentityExt  = ".id"
+	walletIdentityType = "X.509"
+)
+
+// Identity is a Fabric client identity together with its Perun account.
+type Identity struct {
+	X509      *identity.X509Identity // X509 identifies the client towards the Gateway.
+	Sign      identity.Sign          // Sign signs the client's transactions.
+	Account   *wallet.Account        // Account is the Perun account of the identity's private key.
+	Address   *wallet.Address        // Address is the Perun address of the identity's certificate.
+	AccountID adj.AccountID          // AccountID is the on-chain id of the identity.
+	Cert      *x509.Certificate      // Cert is the X.509 certificate of the identity.
+}
+
+// WireAccount returns the Perun wire account of the identity. Peers
+// authenticate it by the identity's certificate.
+func (id *Identity) WireAccount() (*wire.Account, error) {
+	return wire.NewAccount(id.Account, id.Cert)
+}
+
+// walletIdentity is the identity file format of the Fabric SDK file system wallets.
+type walletIdentity struct {
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+	MspID   string `json:"mspId"`
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+}
+
+// LoadMSPIdentity loads the identity of the given MSP from a standard MSP
+// directory. The certificate is read from the signcerts folder and the
+// private key matching it from the keystore folder.
+func LoadMSPIdentity(mspID, mspDir string) (*Identity, error) {
+	certFiles, err := listFiles(filepath.Join(mspDir, mspSignCertsDir))
+	if err != nil {
+		return nil, err
+	} else if len(certFiles) != 1 {
+		return nil, fmt.Errorf("expected one certificate in %s, found %d", mspSignCertsDir, len(certFiles))
+	}
+	certPEM, err := os.ReadFile(certFiles[0])
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate: %w", err)
+	}
+
+	keyFiles, err := listFiles(filepath.Join(mspDir, mspKeystoreDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, kf := range keyFiles {
+		keyPEM, err := os.ReadFile(kf)
+		if err != nil {
+			return nil, fmt.Errorf("reading private key: %w", err)
+		}
+		id, err := newIdentityFromPEM(mspID, certPEM, keyPEM)
+		if errors.Is(err, errKeyMismatch) {
+			continue // The keystore may hold several keys.
+		} else if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", kf, err)
+		}
+		return id, nil
+	}
+	return nil, fmt.Errorf("no private key in %s matches the certificate", mspKeystoreDir)
+}
+
+// LoadWalletIdentity loads an identity from a Fabric SDK wallet identity file.
+func LoadWalletIdentity(path string) (*Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading identity file: %w", err)
+	}
+	var wid walletIdentity
+	if err := json.Unmarshal(data, &wid); err != nil {
+		return nil, fmt.Errorf("unmarshaling identity file: %w", err)
+	}
+	if wid.Type != walletIdentityType {
+		return nil, fmt.Errorf("unsupported identity type: %s", wid.Type)
+	}
+	return newIdentityFromPEM(wid.MspID, []byte(wid.Credentials.Certificate), []byte(wid.Credentials.PrivateKey))
+}
+
+// LoadWallet loads all identities of a Fabric SDK file system wallet
+// directory. The identities are indexed by their label, the name of their
+// identity file without the .id extension.
+func LoadWallet(dir string) (map[string]*Identity, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]*Identity)
+	for _, f := range files {
+		if !strings.HasSuffix(f, walletIdentityExt) {
+			continue
+		}
+		id, err := LoadWalletIdentity(f)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", f, err)
+		}
+		ids[strings.TrimSuffix(filepath.Base(f), walletIdentityExt)] = id
+	}
+	return ids, nil
+}
+
+var errKeyMismatch = errors.New("identity and signer public key mismatch")
+
+func newIdentityFromPEM(mspID string, certPEM, keyPEM []byte) (*Identity, error) {
+	cert, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate: %w", err)
+	}
+	x509ID, addr, accountID, err := newIdentity(mspID, cert)
+	if err != nil {
+		return nil, err
+	}
+	sign, acc, err := newAccountWithSigner(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if !acc.Address().Equal(addr) {
+		return nil, errKeyMismatch
+	}
+	return &Identity{
+		X509:      x509ID,
+		Sign:      sign,
+		Account:   acc,
+		Address:   addr,
+		AccountID: accountID,
+		Cert:      cert,
+	}, nil
+}
+
+// listFiles returns the sorted paths of the regular files in dir.
+func listFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading directory: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
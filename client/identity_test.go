@@ -0,0 +1,215 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/stretchr/testify/require"
+	"polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/client"
+	ctest "github.com/perun-network/perun-fabric/client/test"
+	"github.com/perun-network/perun-fabric/wallet"
+	"github.com/perun-network/perun-fabric/wire"
+)
+
+const testMSPID = "Org1MSP"
+
+func TestLoadMSPIdentity(t *testing.T) {
+	require := require.New(t)
+	rng := test.Prng(t)
+	dir := t.TempDir()
+	certPEM, keyPEM, key := newTestCredentials(t, rng, "User1")
+	_, otherKeyPEM, _ := newTestCredentials(t, rng, "User2")
+
+	writeFile(t, filepath.Join(dir, "signcerts", "cert.pem"), certPEM)
+	// The matching key is found among several keys in the keystore.
+	writeFile(t, filepath.Join(dir, "keystore", "a_sk"), otherKeyPEM)
+	writeFile(t, filepath.Join(dir, "keystore", "b_sk"), keyPEM)
+
+	id, err := client.LoadMSPIdentity(testMSPID, dir)
+	require.NoError(err)
+	requireIdentity(t, id, key, "User1")
+
+	require.NoError(os.Remove(filepath.Join(dir, "keystore", "b_sk")))
+	_, err = client.LoadMSPIdentity(testMSPID, dir)
+	require.Error(err, "no matching key")
+}
+
+func TestLoadWallet(t *testing.T) {
+	require := require.New(t)
+	rng := test.Prng(t)
+	dir := t.TempDir()
+
+	keys := make(map[string]*ecdsa.PrivateKey)
+	for _, label := range []string{"alice", "bob"} {
+		certPEM, keyPEM, key := newTestCredentials(t, rng, label)
+		keys[label] = key
+		writeFile(t, filepath.Join(dir, label+".id"), walletIdentityJSON(t, "X.509", certPEM, keyPEM))
+	}
+	writeFile(t, filepath.Join(dir, "README"), []byte("not an identity"))
+
+	ids, err := client.LoadWallet(dir)
+	require.NoError(err)
+	require.Len(ids, len(keys))
+	for label, key := range keys {
+		requireIdentity(t, ids[label], key, label)
+	}
+
+	id, err := client.LoadWalletIdentity(filepath.Join(dir, "alice.id"))
+	require.NoError(err)
+	requireIdentity(t, id, keys["alice"], "alice")
+
+	// Unsupported identity types and mismatching keys are rejected.
+	certPEM, keyPEM, _ := newTestCredentials(t, rng, "carol")
+	_, otherKeyPEM, _ := newTestCredentials(t, rng, "dave")
+	writeFile(t, filepath.Join(dir, "carol.id"), walletIdentityJSON(t, "Idemix", certPEM, keyPEM))
+	_, err = client.LoadWalletIdentity(filepath.Join(dir, "carol.id"))
+	require.Error(err)
+	writeFile(t, filepath.Join(dir, "carol.id"), walletIdentityJSON(t, "X.509", certPEM, otherKeyPEM))
+	_, err = client.LoadWalletIdentity(filepath.Join(dir, "carol.id"))
+	require.Error(err)
+	_, err = client.LoadWallet(dir)
+	require.Error(err)
+}
+
+func requireIdentity(t *testing.T, id *client.Identity, key *ecdsa.PrivateKey, cn string) {
+	t.Helper()
+	require := require.New(t)
+	require.NotNil(id)
+	require.Equal(testMSPID, id.X509.MspID())
+	require.True(key.Equal(id.Account.ECDSA()))
+	require.True(key.PublicKey.Equal(id.Address.ECDSA()))
+	require.True(id.Account.Address().Equal(id.Address))
+	// Matches the id the chaincode derives from the certificate.
+	expID := base64.StdEncoding.EncodeToString([]byte("x509::CN=" + cn + "::CN=" + cn))
+	require.Equal(adj.AccountID(expID), id.AccountID)
+
+	sig, err := id.Sign([]byte("digest"))
+	require.NoError(err)
+	require.NotEmpty(sig)
+
+	wacc, err := id.WireAccount()
+	require.NoError(err)
+	roots := x509.NewCertPool()
+	roots.AddCert(id.Cert)
+	require.NoError(wire.VerifyCertificate(id.Cert, roots, wacc.Address()))
+}
+
+// newTestCredentials generates a key and a self-signed certificate for it.
+func newTestCredentials(t *testing.T, rng *rand.Rand, cn string) (certPEM, keyPEM []byte, key *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rng)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(rng.Int63()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rng, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+		key
+}
+
+func walletIdentityJSON(t *testing.T, typ string, certPEM, keyPEM []byte) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"credentials": map[string]string{
+			"certificate": string(certPEM),
+			"privateKey":  string(keyPEM),
+		},
+		"mspId":   testMSPID,
+		"type":    typ,
+		"version": 1,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestAccountIDFromIdentity(t *testing.T) {
+	rng := test.Prng(t)
+
+	certPEM, _, _ := newTestCredentials(t, rng, "alice")
+	cert, err := identity.CertificateFromPEM(certPEM)
+	require.NoError(t, err)
+	x509ID, err := identity.NewX509Identity(testMSPID, cert)
+	require.NoError(t, err)
+	id, err := client.AccountIDFromIdentity(x509ID)
+	require.NoError(t, err)
+	expID := base64.StdEncoding.EncodeToString([]byte("x509::CN=alice::CN=alice"))
+	require.Equal(t, adj.AccountID(expID), id)
+
+	// Idemix identities are identified by their account key.
+	acc := wallet.NewRandomAccount(rng)
+	issuer, err := ctest.NewIdemixIssuer(rng, testMSPID)
+	require.NoError(t, err)
+	user, err := issuer.NewUser(rng)
+	require.NoError(t, err)
+	idemix0, err := user.NewIdentity(rng)
+	require.NoError(t, err)
+	idemix1, err := user.NewIdentity(rng)
+	require.NoError(t, err)
+	_, err = client.AccountIDFromIdentity(idemix0)
+	require.Error(t, err)
+	id0, transient, err := client.IdemixAccount(idemix0, acc)
+	require.NoError(t, err)
+	require.Contains(t, transient, adj.IdemixAuthKey)
+	id1, _, err := client.IdemixAccount(idemix1, acc)
+	require.NoError(t, err)
+	require.Equal(t, id0, id1, "stable across pseudonyms")
+	id2, _, err := client.IdemixAccount(idemix0, wallet.NewRandomAccount(rng))
+	require.NoError(t, err)
+	require.NotEqual(t, id0, id2, "different account keys")
+	decoded, err := base64.StdEncoding.DecodeString(string(id0))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(decoded), "idemix::"+testMSPID+"::"))
+
+	_, err = client.AccountIDFromIdentity(invalidIdentity{})
+	require.Error(t, err)
+	_, _, err = client.IdemixAccount(invalidIdentity{}, acc)
+	require.Error(t, err)
+}
+
+type invalidIdentity struct{}
+
+func (invalidIdentity) MspID() string       { return testMSPID }
+func (invalidIdentity) Credentials() []byte { return []byte{0xff} }
@@ -0,0 +1,645 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	pchannel "perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence/keyvalue"
+	pclient "perun.network/go-perun/client"
+	"perun.network/go-perun/wallet"
+	"perun.network/go-perun/watcher/local"
+	pwire "perun.network/go-perun/wire"
+	wirenet "perun.network/go-perun/wire/net"
+	"perun.network/go-perun/wire/perunio/serializer"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/channel"
+	"github.com/perun-network/perun-fabric/channel/binding"
+	fabwallet "github.com/perun-network/perun-fabric/wallet"
+	fabwire "github.com/perun-network/perun-fabric/wire"
+	fabnet "github.com/perun-network/perun-fabric/wire/net"
+)
+
+const (
+	defaultChallengeDuration = 60 // Seconds.
+	defaultDialTimeout       = 10 * time.Second
+	defaultFundingTimeout    = 2 * time.Minute
+	defaultSettleTimeout     = 2 * time.Minute
+	watchPollInterval        = 10 * time.Millisecond
+)
+
+type (
+	// PaymentClient opens, updates and settles two-party payment channels on
+	// Fabric. Counterparties are addressed by their AccountID, which is
+	// resolved to their Perun addresses and network endpoint through the
+	// on-chain address book.
+	PaymentClient struct {
+		id       *Identity
+		ledger   Ledger
+		domain   adj.Domain
+		perun    *pclient.Client
+		bus      *wirenet.Bus
+		dialer   *fabnet.Dialer
+		wireAcc  *fabwire.Account
+		recorder *channel.StateRecorder
+		// persister persists the channels and subscriber the events of their
+		// watcher. They are nil without persistence.
+		persister  *keyvalue.PersistRestorer
+		subscriber *channel.PersistentSubscriber
+		endpoint   string
+		cfg        paymentConfig
+
+		mutex    sync.Mutex
+		channels map[pchannel.ID]*PaymentChannel
+	}
+
+	// Ledger is the on-chain address book and token ledger of a
+	// PaymentClient. It is implemented by the chaincode binding
+	// binding.Adjudicator and can be implemented in memory for testing, see
+	// NewPaymentClientWithContract.
+	Ledger interface {
+		// TokenBalance returns the token balance of the given AccountID.
+		TokenBalance(owner adj.AccountID) (*big.Int, error)
+		// RegisterAddress registers the signed registration of the caller.
+		RegisterAddress(reg *adj.SignedRegistration) error
+		// RegistrationByAccountID returns the registration of the given
+		// AccountID.
+		RegistrationByAccountID(id adj.AccountID) (*adj.SignedRegistration, error)
+		// RegistrationByAddress returns the registration of the given wallet
+		// address.
+		RegistrationByAddress(addr wallet.Address) (*adj.SignedRegistration, error)
+	}
+
+	// PaymentChannel is a two-party payment channel of a PaymentClient.
+	PaymentChannel struct {
+		client    *PaymentClient
+		ch        *pclient.Channel
+		peer      adj.AccountID
+		watchDone chan struct{} // watchDone is closed when watching the channel ended.
+	}
+
+	// PaymentProposal is an incoming proposal to open a payment channel.
+	PaymentProposal struct {
+		Peer              adj.AccountID  // Peer is the AccountID of the proposer. It is empty if the proposer is not registered.
+		PeerAddress       wallet.Address // PeerAddress is the Perun address of the proposer.
+		PeerBalance       *big.Int       // PeerBalance is the initial balance of the proposer.
+		OwnBalance        *big.Int       // OwnBalance is the initial balance of the receiver of the proposal.
+		ChallengeDuration uint64         // ChallengeDuration is the challenge duration of the channel in seconds.
+	}
+
+	// PaymentOpt extends the construction of a PaymentClient.
+	PaymentOpt func(*paymentConfig)
+
+	paymentConfig struct {
+		listenAddr        string
+		endpoint          string
+		challengeDuration uint64
+		dialTimeout       time.Duration
+		fundingTimeout    time.Duration
+		settleTimeout     time.Duration
+		funderOpts        []channel.FunderOpt
+		adjOpts           []channel.AdjudicatorOpt
+		persistDir        string
+
+		onProposal func(*PaymentProposal) bool
+		onChannel  func(*PaymentChannel)
+		onPayment  func(*PaymentChannel, *big.Int)
+		onSettled  func(*PaymentChannel, error)
+	}
+)
+
+var _ Ledger = (*binding.Adjudicator)(nil)
+
+// WithListenAddress lets the PaymentClient listen for peers on the given
+// address, in the form host:port. Without a listen address, the client can
+// only open channels, but not receive proposals.
+func WithListenAddress(addr string) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.listenAddr = addr
+	}
+}
+
+// WithEndpoint sets the endpoint, in the form host:port, under which peers
+// can reach the client. It defaults to the address of the listener.
+func WithEndpoint(endpoint string) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.endpoint = endpoint
+	}
+}
+
+// WithChallengeDuration overwrites the challenge duration, in seconds, of
+// proposed channels.
+func WithChallengeDuration(d uint64) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.challengeDuration = d
+	}
+}
+
+// WithDialTimeout overwrites the timeout for connecting to peers.
+func WithDialTimeout(d time.Duration) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.dialTimeout = d
+	}
+}
+
+// WithFundingTimeout overwrites the timeout for funding channels that were
+// proposed by a peer.
+func WithFundingTimeout(d time.Duration) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.fundingTimeout = d
+	}
+}
+
+// WithSettleTimeout overwrites the timeout for settling channels that were
+// closed by the peer. Settling a channel whose dispute is resumed after a
+// restart may take the challenge duration of the channel in addition.
+func WithSettleTimeout(d time.Duration) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.settleTimeout = d
+	}
+}
+
+// WithFunderOpts passes the given options to the Funder of the client.
+func WithFunderOpts(opts ...channel.FunderOpt) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.funderOpts = append(c.funderOpts, opts...)
+	}
+}
+
+// WithAdjudicatorOpts passes the given options to the Adjudicator of the
+// client.
+func WithAdjudicatorOpts(opts ...channel.AdjudicatorOpt) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.adjOpts = append(c.adjOpts, opts...)
+	}
+}
+
+// WithProposalHandler sets the callback that decides whether an incoming
+// channel proposal is accepted. Without it, all proposals are rejected.
+func WithProposalHandler(h func(*PaymentProposal) bool) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.onProposal = h
+	}
+}
+
+// WithChannelHandler sets the callback that is called for every channel that
+// was opened by a peer.
+func WithChannelHandler(h func(*PaymentChannel)) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.onChannel = h
+	}
+}
+
+// WithPaymentHandler sets the callback that is called for every payment
+// received on a channel.
+func WithPaymentHandler(h func(ch *PaymentChannel, amount *big.Int)) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.onPayment = h
+	}
+}
+
+// WithSettleHandler sets the callback that is called after a channel that
+// was closed by the peer was settled, or settling it failed.
+func WithSettleHandler(h func(ch *PaymentChannel, err error)) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.onSettled = h
+	}
+}
+
+// NewPaymentClient sets up a PaymentClient for the given identity on the
+// chaincode of the network. Peers are authenticated against the given roots,
+// usually the CA certificates of the trusted Fabric organizations. The
+// channel backend domain must be set to the domain of the chaincode, see
+// channel.SetDomain.
+func NewPaymentClient(network *client.Network, chaincode string, id *Identity, roots *x509.CertPool,
+	opts ...PaymentOpt) (*PaymentClient, error) {
+	b := binding.NewAdjudicatorBinding(network, chaincode)
+	return NewPaymentClientWithContract(b, b, adj.NewDomain(network.Name(), chaincode), id, roots, opts...)
+}
+
+// NewPaymentClientWithContract sets up a PaymentClient for the given identity
+// on the given Adjudicator contract and ledger of the given domain, like
+// NewPaymentClient.
+func NewPaymentClientWithContract(contract channel.Contract, ledger Ledger, domain adj.Domain, id *Identity,
+	roots *x509.CertPool, opts ...PaymentOpt) (*PaymentClient, error) {
+	if err := channel.CheckDomain(domain); err != nil {
+		return nil, err
+	}
+	cfg := paymentConfig{
+		challengeDuration: defaultChallengeDuration,
+		dialTimeout:       defaultDialTimeout,
+		fundingTimeout:    defaultFundingTimeout,
+		settleTimeout:     defaultSettleTimeout,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	wireAcc, err := id.WireAccount()
+	if err != nil {
+		return nil, fmt.Errorf("creating wire account: %w", err)
+	}
+	var listener *fabnet.Listener
+	if cfg.listenAddr != "" {
+		if listener, err = fabnet.NewTCPListener(wireAcc, roots, cfg.listenAddr); err != nil {
+			return nil, err
+		}
+		if cfg.endpoint == "" {
+			cfg.endpoint = listener.Addr().String()
+		}
+	}
+	dialer := fabnet.NewTCPDialer(wireAcc, roots, cfg.dialTimeout)
+	bus := wirenet.NewBus(wireAcc, dialer, serializer.Serializer())
+
+	c := &PaymentClient{
+		id:       id,
+		ledger:   ledger,
+		domain:   domain,
+		bus:      bus,
+		dialer:   dialer,
+		wireAcc:  wireAcc,
+		endpoint: cfg.endpoint,
+		cfg:      cfg,
+		channels: make(map[pchannel.ID]*PaymentChannel),
+	}
+	fail := func(err error) (*PaymentClient, error) {
+		bus.Close()
+		c.closeDatabase()
+		closeListener(listener)
+		return nil, err
+	}
+	// The watcher subscribes to the events of the channels. With persistence,
+	// its subscriptions resume after the events handled before a restart.
+	adjudicator := channel.NewAdjudicatorWithContract(contract, domain, id.AccountID, cfg.adjOpts...)
+	var subscriber pchannel.RegisterSubscriber = adjudicator
+	if cfg.persistDir != "" {
+		if err := c.openDatabase(adjudicator); err != nil {
+			return fail(err)
+		}
+		subscriber = c.subscriber
+	}
+	watcher, err := local.NewWatcher(subscriber)
+	if err != nil {
+		return fail(fmt.Errorf("creating watcher: %w", err))
+	}
+	c.recorder = channel.NewStateRecorder(watcher, domain)
+	perun, err := pclient.New(wireAcc.Address(), bus, channel.NewFunderWithContract(contract, domain, cfg.funderOpts...),
+		adjudicator, fabwallet.NewWallet(id.Account), c.recorder)
+	if err != nil {
+		return fail(fmt.Errorf("creating client: %w", err))
+	}
+	c.perun = perun
+	if c.persister != nil {
+		if err := c.restore(); err != nil {
+			_ = c.Close()
+			closeListener(listener)
+			return nil, err
+		}
+	}
+	go perun.Handle(pclient.ProposalHandlerFunc(c.handleProposal), pclient.UpdateHandlerFunc(c.handleUpdate))
+	if listener != nil {
+		go bus.Listen(listener)
+	}
+	return c, nil
+}
+
+// AccountID returns the AccountID of the client.
+func (c *PaymentClient) AccountID() adj.AccountID {
+	return c.id.AccountID
+}
+
+// Endpoint returns the endpoint under which peers can reach the client. It is
+// empty if the client does not listen for peers.
+func (c *PaymentClient) Endpoint() string {
+	return c.endpoint
+}
+
+// Register registers the Perun addresses and the endpoint of the client in
+// the on-chain address book, so that peers can open channels with it. An
+// earlier registration of the client is replaced.
+func (c *PaymentClient) Register() error {
+	if c.endpoint == "" {
+		return errors.New("registering client without endpoint")
+	}
+	var seq uint64
+	if prev, err := c.ledger.RegistrationByAccountID(c.id.AccountID); err == nil {
+		seq = prev.Reg.Sequence + 1
+	} else if !binding.IsNotFoundErr(err) {
+		return fmt.Errorf("querying registration: %w", err)
+	}
+	reg, err := adj.SignRegistration(c.id.Account, c.wireAcc, c.domain, c.id.AccountID, seq, c.endpoint)
+	if err != nil {
+		return fmt.Errorf("signing registration: %w", err)
+	}
+	return c.ledger.RegisterAddress(reg)
+}
+
+// TokenBalance returns the on-chain token balance of the client.
+func (c *PaymentClient) TokenBalance() (*big.Int, error) {
+	return c.ledger.TokenBalance(c.id.AccountID)
+}
+
+// OpenChannel opens a payment channel with the given peer. The client and
+// the peer deposit the given balances from their on-chain token balances.
+func (c *PaymentClient) OpenChannel(ctx context.Context, peer adj.AccountID, ownBal, peerBal *big.Int) (*PaymentChannel, error) {
+	reg, err := c.ledger.RegistrationByAccountID(peer)
+	if err != nil {
+		return nil, fmt.Errorf("resolving peer %s: %w", peer, err)
+	}
+	c.dialer.Register(reg.Reg.WireAddress, reg.Reg.Endpoint)
+
+	alloc := pchannel.NewAllocation(2, channel.Asset)
+	alloc.SetAssetBalances(channel.Asset, []pchannel.Bal{ownBal, peerBal})
+	prop, err := pclient.NewLedgerChannelProposal(
+		c.cfg.challengeDuration,
+		c.id.Address,
+		alloc,
+		[]pwire.Address{c.wireAcc.Address(), reg.Reg.WireAddress},
+		pclient.WithRandomNonce(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating channel proposal: %w", err)
+	}
+	ch, err := c.perun.ProposeChannel(ctx, prop)
+	if err != nil {
+		return nil, fmt.Errorf("opening channel: %w", err)
+	}
+	return c.startChannel(ch, peer), nil
+}
+
+// Channel returns the open payment channel with the given ID.
+func (c *PaymentClient) Channel(id pchannel.ID) (*PaymentChannel, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ch, ok := c.channels[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %x", id)
+	}
+	return ch, nil
+}
+
+// Channels returns the open payment channels of the client.
+func (c *PaymentClient) Channels() []*PaymentChannel {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	chs := make([]*PaymentChannel, 0, len(c.channels))
+	for _, ch := range c.channels {
+		chs = append(chs, ch)
+	}
+	return chs
+}
+
+// Close closes the client and its connections to peers. Open channels are
+// not settled. With persistence, they are restored by the next client.
+func (c *PaymentClient) Close() error {
+	err := c.perun.Close()
+	if berr := c.bus.Close(); err == nil {
+		err = berr
+	}
+	if c.persister != nil {
+		if perr := c.persister.Close(); err == nil {
+			err = perr
+		}
+	}
+	return err
+}
+
+// closeDatabase closes the database of the client, if any.
+func (c *PaymentClient) closeDatabase() {
+	if c.persister != nil {
+		_ = c.persister.Close()
+	}
+}
+
+// closeListener closes the given listener, if any.
+func closeListener(l *fabnet.Listener) {
+	if l != nil {
+		_ = l.Close()
+	}
+}
+
+// startChannel registers the channel with the client and starts watching it
+// for disputes.
+func (c *PaymentClient) startChannel(ch *pclient.Channel, peer adj.AccountID) *PaymentChannel {
+	pch := c.addChannel(ch, peer)
+	go func() {
+		defer close(pch.watchDone)
+		if err := ch.Watch(pch); err != nil {
+			ch.Log().WithError(err).Warn("Watching channel failed.")
+		}
+	}()
+	return pch
+}
+
+// addChannel registers the channel with the client.
+func (c *PaymentClient) addChannel(ch *pclient.Channel, peer adj.AccountID) *PaymentChannel {
+	pch := &PaymentChannel{client: c, ch: ch, peer: peer, watchDone: make(chan struct{})}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.channels[ch.ID()] = pch
+	return pch
+}
+
+func (c *PaymentClient) removeChannel(id pchannel.ID) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.channels, id)
+}
+
+// handleProposal asks the proposal handler whether to accept a two-party
+// ledger channel proposal and opens the channel if so. Accepting includes
+// funding the channel, so it runs under the funding timeout.
+func (c *PaymentClient) handleProposal(p pclient.ChannelProposal, r *pclient.ProposalResponder) {
+	reject := func(reason string) {
+		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.dialTimeout)
+		defer cancel()
+		_ = r.Reject(ctx, reason)
+	}
+
+	lp, ok := p.(*pclient.LedgerChannelProposalMsg)
+	if !ok || len(lp.Peers) != 2 || lp.App != nil && !pchannel.IsNoApp(lp.App) ||
+		len(lp.InitBals.Assets) != 1 || !channel.Asset.Equal(lp.InitBals.Assets[0]) {
+		reject("unsupported channel proposal")
+		return
+	}
+	bals := lp.InitBals.Balances[0]
+	prop := &PaymentProposal{
+		PeerAddress:       lp.Participant,
+		PeerBalance:       new(big.Int).Set(bals[0]),
+		OwnBalance:        new(big.Int).Set(bals[1]),
+		ChallengeDuration: lp.ChallengeDuration,
+	}
+	// The proposer's wire address is authenticated by its TLS certificate.
+	// Its AccountID is only trusted if the registration links both addresses.
+	if reg, err := c.ledger.RegistrationByAddress(lp.Participant); err == nil && reg.Reg.WireAddress.Equal(lp.Peers[0]) {
+		prop.Peer = reg.Reg.AccountID
+		c.dialer.Register(reg.Reg.WireAddress, reg.Reg.Endpoint)
+	}
+
+	if c.cfg.onProposal == nil || !c.cfg.onProposal(prop) {
+		reject("proposal rejected")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.fundingTimeout)
+	defer cancel()
+	ch, err := r.Accept(ctx, lp.Accept(c.id.Address, pclient.WithRandomNonce()))
+	if err != nil {
+		return
+	}
+	pch := c.startChannel(ch, prop.Peer)
+	if c.cfg.onChannel != nil {
+		go c.cfg.onChannel(pch)
+	}
+}
+
+// handleUpdate accepts incoming payments and channel closings. Updates that
+// decrease the client's balance are rejected.
+func (c *PaymentClient) handleUpdate(cur *pchannel.State, next pclient.ChannelUpdate, r *pclient.UpdateResponder) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.dialTimeout)
+	defer cancel()
+
+	ch, err := c.Channel(cur.ID)
+	if err != nil {
+		_ = r.Reject(ctx, "unknown channel")
+		return
+	}
+	own := ch.ch.Idx()
+	amount := new(big.Int).Sub(next.State.Balances[0][own], cur.Balances[0][own])
+	if amount.Sign() < 0 {
+		_ = r.Reject(ctx, "payment must not decrease own balance")
+		return
+	}
+	if err := r.Accept(ctx); err != nil {
+		return
+	}
+
+	if amount.Sign() > 0 && c.cfg.onPayment != nil {
+		go c.cfg.onPayment(ch, amount)
+	}
+	if next.State.IsFinal {
+		go c.settleClosed(ch, c.cfg.settleTimeout)
+	}
+}
+
+// settleClosed settles a channel that was closed by the peer, or whose
+// settlement was interrupted by a restart of the client, within the given
+// timeout.
+func (c *PaymentClient) settleClosed(ch *PaymentChannel, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := ch.settle(ctx)
+	if c.cfg.onSettled != nil {
+		c.cfg.onSettled(ch, err)
+	}
+}
+
+// ID returns the ID of the channel.
+func (ch *PaymentChannel) ID() pchannel.ID {
+	return ch.ch.ID()
+}
+
+// Peer returns the AccountID of the peer. It is empty if the peer opened the
+// channel and is not registered.
+func (ch *PaymentChannel) Peer() adj.AccountID {
+	return ch.peer
+}
+
+// Balances returns the current balances of the client and the peer.
+func (ch *PaymentChannel) Balances() (own, peer *big.Int) {
+	bals := ch.ch.State().Balances[0]
+	idx := ch.ch.Idx()
+	return new(big.Int).Set(bals[idx]), new(big.Int).Set(bals[1-idx])
+}
+
+// Pay transfers the given amount to the peer.
+func (ch *PaymentChannel) Pay(ctx context.Context, amount *big.Int) error {
+	if amount.Sign() <= 0 {
+		return errors.New("payment amount must be positive")
+	}
+	return ch.ch.Update(ctx, func(s *pchannel.State) {
+		idx := ch.ch.Idx()
+		s.Balances[0][idx].Sub(s.Balances[0][idx], amount)
+		s.Balances[0][1-idx].Add(s.Balances[0][1-idx], amount)
+	})
+}
+
+// Backup returns a backup of the latest fully signed state of the channel.
+// It can be registered and withdrawn from without the client, see
+// channel.Adjudicator.RegisterBackup and WithdrawBackup.
+func (ch *PaymentChannel) Backup() (*adj.Backup, error) {
+	return ch.client.recorder.Backup(ch.ID())
+}
+
+// Settle closes the channel with the peer and withdraws the client's
+// balance to its on-chain token balance. If the peer does not respond, the
+// channel is settled in a dispute, which takes at least the challenge
+// duration.
+func (ch *PaymentChannel) Settle(ctx context.Context) error {
+	if !ch.ch.State().IsFinal {
+		if err := ch.ch.Update(ctx, func(s *pchannel.State) { s.IsFinal = true }); err != nil {
+			ch.ch.Log().WithError(err).Warn("Final update failed, settling in dispute.")
+		}
+	}
+	return ch.settle(ctx)
+}
+
+// settle withdraws the client's balance and closes the channel.
+func (ch *PaymentChannel) settle(ctx context.Context) error {
+	if err := ch.awaitWatching(ctx); err != nil {
+		return fmt.Errorf("awaiting watcher: %w", err)
+	}
+	if err := ch.ch.Settle(ctx, false); err != nil {
+		return fmt.Errorf("settling channel: %w", err)
+	}
+	ch.client.removeChannel(ch.ID())
+	if err := ch.ch.Close(); err != nil {
+		return err
+	}
+	return ch.client.forget(ch.ID())
+}
+
+// awaitWatching waits until the watcher watches the channel, or watching it
+// ended. go-perun's Channel.Watch does not handle the channel being closed
+// while it sets up watching, which is done once the watcher recorded the
+// channel and the channel is unlocked.
+func (ch *PaymentChannel) awaitWatching(ctx context.Context) error {
+	for {
+		if _, err := ch.client.recorder.SignedChannel(ch.ID()); err == nil {
+			return nil
+		}
+		select {
+		case <-ch.watchDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchPollInterval):
+		}
+	}
+}
+
+// HandleAdjudicatorEvent logs the on-chain events of the channel. Disputes
+// are answered by the watcher of the client.
+func (ch *PaymentChannel) HandleAdjudicatorEvent(e pchannel.AdjudicatorEvent) {
+	ch.ch.Log().Infof("Adjudicator event: %T, version %d", e, e.Version())
+}
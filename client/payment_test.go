@@ -0,0 +1,112 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	chtest "github.com/perun-network/perun-fabric/channel/test"
+	"github.com/perun-network/perun-fabric/client"
+)
+
+const (
+	paymentTestTimeout  = 2 * time.Minute
+	paymentChallengeDur = 10
+)
+
+func TestPaymentClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), paymentTestTimeout)
+	defer cancel()
+
+	const A, B = 0, 1 // Indices of Alice and Bob.
+	roots, err := chtest.NewCACertPool()
+	require.NoError(t, err)
+
+	var (
+		sessions [2]*chtest.Session
+		clients  [2]*client.PaymentClient
+		initBal  [2]*big.Int
+	)
+	bobChannel := make(chan *client.PaymentChannel, 1)
+	bobPayment := make(chan *big.Int, 1)
+	bobSettled := make(chan error, 1)
+	opts := [2][]client.PaymentOpt{
+		{client.WithChallengeDuration(paymentChallengeDur)},
+		{
+			client.WithProposalHandler(func(p *client.PaymentProposal) bool {
+				return p.Peer == sessions[A].ClientFabricID && p.OwnBalance.Sign() == 0
+			}),
+			client.WithChannelHandler(func(ch *client.PaymentChannel) { bobChannel <- ch }),
+			client.WithPaymentHandler(func(_ *client.PaymentChannel, amount *big.Int) { bobPayment <- amount }),
+			client.WithSettleHandler(func(_ *client.PaymentChannel, err error) { bobSettled <- err }),
+		},
+	}
+	for i := range clients {
+		org := chtest.OrgNum(uint(i + 1))
+		sessions[i], err = chtest.NewTestSession(org, chtest.AdjudicatorName)
+		require.NoError(t, err)
+		defer sessions[i].Close()
+		id, err := chtest.LoadIdentity(org)
+		require.NoError(t, err)
+
+		opts[i] = append(opts[i], client.WithListenAddress("127.0.0.1:0"))
+		clients[i], err = client.NewPaymentClient(sessions[i].Network, chtest.AdjudicatorName, id, roots, opts[i]...)
+		require.NoError(t, err)
+		defer clients[i].Close()
+		require.NoError(t, clients[i].Register())
+
+		initBal[i], err = clients[i].TokenBalance()
+		require.NoError(t, err)
+	}
+	require.NoError(t, sessions[A].Binding.MintToken(big.NewInt(100)))
+
+	ch, err := clients[A].OpenChannel(ctx, sessions[B].ClientFabricID, big.NewInt(100), big.NewInt(0))
+	require.NoError(t, err)
+	require.Equal(t, sessions[B].ClientFabricID, ch.Peer())
+	select {
+	case bobCh := <-bobChannel:
+		require.Equal(t, ch.ID(), bobCh.ID())
+		require.Equal(t, sessions[A].ClientFabricID, bobCh.Peer())
+	case <-ctx.Done():
+		t.Fatal("Bob did not receive channel")
+	}
+
+	require.NoError(t, ch.Pay(ctx, big.NewInt(30)))
+	require.Equal(t, big.NewInt(30), <-bobPayment)
+	backup, err := ch.Backup()
+	require.NoError(t, err)
+	require.NoError(t, backup.Verify())
+	require.Equal(t, uint64(1), backup.Channel.State.Version)
+	own, peer := ch.Balances()
+	require.Equal(t, big.NewInt(70), own)
+	require.Equal(t, big.NewInt(30), peer)
+	require.Error(t, ch.Pay(ctx, big.NewInt(0)))
+
+	require.NoError(t, ch.Settle(ctx))
+	require.NoError(t, <-bobSettled)
+	_, err = clients[A].Channel(ch.ID())
+	require.Error(t, err)
+
+	for i, want := range []*big.Int{big.NewInt(70), big.NewInt(30)} {
+		bal, err := clients[i].TokenBalance()
+		require.NoError(t, err)
+		require.Equal(t, new(big.Int).Add(initBal[i], want), bal)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	pkgsync "polycry.pt/poly-go/sync"
+)
+
+const (
+	defaultHealthCheckInterval = 5 * time.Second
+	peerResolverScheme         = "perun-fabric-peers"
+	pickFirstServiceConfig     = `{"loadBalancingConfig":[{"pick_first":{}}]}`
+)
+
+// peerResolverID makes the resolver scheme of every PeerConnection unique.
+var peerResolverID uint64
+
+// GatewayPeer is a Fabric peer that runs a Gateway service.
+type GatewayPeer struct {
+	Endpoint   string // Endpoint is the host:port of the peer.
+	ServerName string // ServerName is the name in the peer's TLS certificate. Defaults to the host of Endpoint.
+}
+
+// PeerConnection is a gRPC connection to one of several Gateway peers. It is
+// connected to the first reachable peer, in the given order. If the peer
+// fails, the connection reconnects and fails over to the next reachable
+// peer. A Gateway created on the connection therefore keeps working as long
+// as one peer is reachable. A health check periodically wakes up failed
+// connections, so that they reconnect without waiting for the next call.
+type PeerConnection struct {
+	*grpc.ClientConn
+	peers []GatewayPeer
+
+	pkgsync.Closer
+}
+
+type connConfig struct {
+	clientCert          *tls.Certificate
+	healthCheckInterval time.Duration
+}
+
+// ConnOpt extends the construction of gRPC connections to Gateway peers.
+type ConnOpt func(*connConfig)
+
+// WithClientCertificate sets the TLS client certificate, which is presented
+// to the peers for mutual TLS.
+func WithClientCertificate(cert tls.Certificate) ConnOpt {
+	return func(c *connConfig) {
+		c.clientCert = &cert
+	}
+}
+
+// WithHealthCheckInterval overwrites the interval of the connection health
+// check.
+func WithHealthCheckInterval(d time.Duration) ConnOpt {
+	return func(c *connConfig) {
+		c.healthCheckInterval = d
+	}
+}
+
+// LoadClientCertificate loads a TLS client certificate and its private key
+// from the given PEM files.
+func LoadClientCertificate(certPath, keyPath string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("loading client certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// NewPeerConnection creates a gRPC connection to the given Gateway peers. The
+// TLS certificates of the peers are verified against the given pool. The
+// connection is established lazily.
+func NewPeerConnection(peers []GatewayPeer, certPool *x509.CertPool, opts ...ConnOpt) (*PeerConnection, error) {
+	if len(peers) == 0 {
+		return nil, errors.New("no gateway peers")
+	}
+	cfg := connConfig{healthCheckInterval: defaultHealthCheckInterval}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	addrs := make([]resolver.Address, 0, len(peers))
+	for _, p := range peers {
+		addr := resolver.Address{Addr: p.Endpoint, ServerName: p.ServerName}
+		if addr.ServerName == "" {
+			host, _, err := net.SplitHostPort(p.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("parsing peer endpoint: %w", err)
+			}
+			addr.ServerName = host
+		}
+		addrs = append(addrs, addr)
+	}
+	r := manual.NewBuilderWithScheme(fmt.Sprintf("%s-%d", peerResolverScheme, atomic.AddUint64(&peerResolverID, 1)))
+	r.InitialState(resolver.State{Addresses: addrs})
+
+	conn, err := grpc.Dial(r.Scheme()+":///gateway",
+		grpc.WithResolvers(r),
+		grpc.WithDefaultServiceConfig(pickFirstServiceConfig),
+		grpc.WithTransportCredentials(transportCredentials(certPool, "", cfg)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating gRPC connection: %w", err)
+	}
+
+	c := &PeerConnection{ClientConn: conn, peers: peers}
+	go c.healthCheck(cfg.healthCheckInterval)
+	return c, nil
+}
+
+// Peers returns the peers of the connection.
+func (c *PeerConnection) Peers() []GatewayPeer {
+	return c.peers
+}
+
+// Healthy returns whether the connection is connected to a peer.
+func (c *PeerConnection) Healthy() bool {
+	return c.GetState() == connectivity.Ready
+}
+
+// WaitHealthy connects the connection and waits until it is connected to a
+// peer or the context is done.
+func (c *PeerConnection) WaitHealthy(ctx context.Context) error {
+	c.Connect()
+	for state := c.GetState(); state != connectivity.Ready; state = c.GetState() {
+		if !c.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("waiting for healthy connection (%v): %w", state, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// Close stops the health check and closes the gRPC connection.
+func (c *PeerConnection) Close() error {
+	if err := c.Closer.Close(); err != nil {
+		return err
+	}
+	return c.ClientConn.Close()
+}
+
+// healthCheck wakes up failed or idle connections, so that they reconnect to
+// one of the peers without delay.
+func (c *PeerConnection) healthCheck(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Closed():
+			return
+		case <-ticker.C:
+		}
+		switch c.GetState() {
+		case connectivity.TransientFailure:
+			c.ResetConnectBackoff()
+		case connectivity.Idle:
+			c.Connect()
+		case connectivity.Connecting, connectivity.Ready, connectivity.Shutdown:
+		}
+	}
+}
+
+// transportCredentials returns the TLS credentials verifying the server
+// against the pool and presenting the configured client certificate.
+func transportCredentials(certPool *x509.CertPool, serverName string, cfg connConfig) credentials.TransportCredentials {
+	tlsConfig := &tls.Config{
+		RootCAs:    certPool,
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if cfg.clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*cfg.clientCert}
+	}
+	return credentials.NewTLS(tlsConfig)
+}
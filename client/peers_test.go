@@ -0,0 +1,201 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"polycry.pt/poly-go/test"
+
+	"github.com/perun-network/perun-fabric/client"
+)
+
+const peerTestTimeout = 10 * time.Second
+
+func TestPeerConnectionFailover(t *testing.T) {
+	rng := test.Prng(t)
+	ca := newTestCA(t, rng)
+	peer0 := startHealthServer(t, ca.serverConfig(t, rng, "peer0.org1.example.com"), "peer0")
+	peer1 := startHealthServer(t, ca.serverConfig(t, rng, "peer1.org1.example.com"), "peer1")
+
+	conn, err := client.NewPeerConnection([]client.GatewayPeer{
+		{Endpoint: peer0.addr, ServerName: "peer0.org1.example.com"},
+		{Endpoint: peer1.addr, ServerName: "peer1.org1.example.com"},
+	}, ca.pool(), client.WithHealthCheckInterval(50*time.Millisecond))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), peerTestTimeout)
+	defer cancel()
+	require.NoError(t, conn.WaitHealthy(ctx))
+	require.True(t, conn.Healthy())
+	health := healthpb.NewHealthClient(conn)
+	// The connection prefers the first peer.
+	requireServing(ctx, t, health, "peer0")
+
+	peer0.server.Stop()
+	// Calls in flight when the first peer fails may fail with Unavailable.
+	// Afterwards, the connection fails over to the second peer.
+	require.Eventually(t, func() bool {
+		resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: "peer1"}, grpc.WaitForReady(true))
+		return err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING //nolint:nosnakecase
+	}, peerTestTimeout, 10*time.Millisecond)
+	require.True(t, conn.Healthy())
+
+	require.NoError(t, conn.Close())
+	require.False(t, conn.Healthy())
+}
+
+func TestPeerConnectionMutualTLS(t *testing.T) {
+	rng := test.Prng(t)
+	ca := newTestCA(t, rng)
+	cfg := ca.serverConfig(t, rng, "peer0.org1.example.com")
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	cfg.ClientCAs = ca.pool()
+	peer := startHealthServer(t, cfg, "peer0")
+	peers := []client.GatewayPeer{{Endpoint: peer.addr, ServerName: "peer0.org1.example.com"}}
+
+	t.Run("without client certificate", func(t *testing.T) {
+		conn, err := client.NewPeerConnection(peers, ca.pool())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "peer0"})
+		require.Error(t, err)
+	})
+
+	t.Run("with client certificate", func(t *testing.T) {
+		clientCert := ca.certificate(t, rng, "User1@org1.example.com")
+		conn, err := client.NewPeerConnection(peers, ca.pool(), client.WithClientCertificate(clientCert))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), peerTestTimeout)
+		defer cancel()
+		requireServing(ctx, t, healthpb.NewHealthClient(conn), "peer0")
+	})
+}
+
+func TestNewPeerConnectionInvalid(t *testing.T) {
+	_, err := client.NewPeerConnection(nil, x509.NewCertPool())
+	require.Error(t, err, "no peers")
+	_, err = client.NewPeerConnection([]client.GatewayPeer{{Endpoint: "localhost"}}, x509.NewCertPool())
+	require.Error(t, err, "endpoint without port")
+}
+
+// requireServing requires that the peer the connection is connected to
+// serves the given health service.
+func requireServing(ctx context.Context, t *testing.T, health healthpb.HealthClient, service string) {
+	t.Helper()
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: service}, grpc.WaitForReady(true))
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status) //nolint:nosnakecase
+}
+
+type healthServer struct {
+	server *grpc.Server
+	addr   string
+}
+
+// startHealthServer starts a TLS gRPC server that serves only the named
+// health service.
+func startHealthServer(t *testing.T, cfg *tls.Config, service string) *healthServer {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(cfg)))
+	h := health.NewServer()
+	h.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING) //nolint:nosnakecase
+	healthpb.RegisterHealthServer(s, h)
+	go s.Serve(lis) //nolint:errcheck
+	t.Cleanup(s.Stop)
+	return &healthServer{server: s, addr: lis.Addr().String()}
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, rng *rand.Rand) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rng)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(rng.Int63()),
+		Subject:               pkix.Name{CommonName: "tlsca.org1.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rng, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// certificate issues a TLS certificate for the given name, usable by both
+// servers and clients.
+func (ca *testCA) certificate(t *testing.T, rng *rand.Rand, name string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rng)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(rng.Int63()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rng, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (ca *testCA) serverConfig(t *testing.T, rng *rand.Rand, name string) *tls.Config {
+	t.Helper()
+	return &tls.Config{
+		Certificates: []tls.Certificate{ca.certificate(t, rng, name)},
+		MinVersion:   tls.VersionTLS12,
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pchannel "perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence/keyvalue"
+	pclient "perun.network/go-perun/client"
+	"polycry.pt/poly-go/sortedkv/leveldb"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/channel"
+)
+
+// WithPersistence persists the channels of the PaymentClient in a LevelDB
+// database in the given directory. Channels persisted by a previous client
+// of the same identity are restored on construction, see NewPaymentClient.
+func WithPersistence(dir string) PaymentOpt {
+	return func(c *paymentConfig) {
+		c.persistDir = dir
+	}
+}
+
+// openDatabase opens the database in the configured directory, which persists
+// the channels and the events handled by the watcher of the given
+// adjudicator.
+func (c *PaymentClient) openDatabase(a *channel.Adjudicator) error {
+	db, err := leveldb.LoadDatabase(c.cfg.persistDir)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	c.persister = keyvalue.NewPersistRestorer(db)
+	c.subscriber = channel.NewPersistentSubscriber(a, db)
+	return nil
+}
+
+// restore enables the persistence of the client's channels in the database
+// and restores the channels that were persisted before.
+func (c *PaymentClient) restore() error {
+	c.perun.EnablePersistence(c.persister)
+
+	// Channels of different peers are restored concurrently.
+	var (
+		mutex    sync.Mutex
+		restored []*pclient.Channel
+	)
+	c.perun.OnNewChannel(func(ch *pclient.Channel) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		restored = append(restored, ch)
+	})
+	err := c.perun.Restore(context.Background())
+	c.perun.OnNewChannel(nil)
+	if err != nil {
+		return fmt.Errorf("restoring channels: %w", err)
+	}
+
+	for _, ch := range restored {
+		c.resumeChannel(ch)
+	}
+	return nil
+}
+
+// resumeChannel continues where the previous client left a restored channel.
+// All channels that were not withdrawn from are watched again, so that
+// disputes are refuted. The settlement of channels that were closed, or that
+// were being disputed or withdrawn from, is resumed in the background and
+// reported to the settle handler. A dispute may take the challenge duration of
+// the channel to time out, which extends the settle timeout.
+func (c *PaymentClient) resumeChannel(ch *pclient.Channel) {
+	phase := ch.Phase()
+	if phase == pchannel.Withdrawn {
+		if err := ch.Close(); err != nil {
+			ch.Log().WithError(err).Warn("Closing withdrawn channel failed.")
+		}
+		if err := c.forget(ch.ID()); err != nil {
+			ch.Log().WithError(err).Warn("Forgetting withdrawn channel failed.")
+		}
+		return
+	}
+
+	pch := c.startChannel(ch, c.resolvePeer(ch))
+	if phase >= pchannel.Final || ch.State().IsFinal {
+		challenge := time.Duration(ch.Params().ChallengeDuration) * time.Second
+		go c.settleClosed(pch, challenge+c.cfg.settleTimeout)
+	}
+}
+
+// forget deletes the watcher's record of the events of the given channel, once
+// the channel is withdrawn from.
+func (c *PaymentClient) forget(id pchannel.ID) error {
+	if c.subscriber == nil {
+		return nil
+	}
+	return c.subscriber.Forget(id)
+}
+
+// resolvePeer resolves the AccountID of the peer of the channel and its
+// endpoint through the address book. The AccountID is empty if the peer is not
+// registered.
+func (c *PaymentClient) resolvePeer(ch *pclient.Channel) adj.AccountID {
+	idx := 1 - ch.Idx()
+	reg, err := c.ledger.RegistrationByAddress(ch.Params().Parts[idx])
+	if err != nil || !reg.Reg.WireAddress.Equal(ch.Peers()[idx]) {
+		return ""
+	}
+	c.dialer.Register(reg.Reg.WireAddress, reg.Reg.Endpoint)
+	return reg.Reg.AccountID
+}
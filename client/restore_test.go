@@ -0,0 +1,247 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/channel"
+	chtest "github.com/perun-network/perun-fabric/channel/test"
+	"github.com/perun-network/perun-fabric/client"
+	fabwire "github.com/perun-network/perun-fabric/wire"
+)
+
+const (
+	restoreTestTimeout       = 30 * time.Second
+	restoreChallengeDuration = 2 // Seconds.
+	restorePolling           = 20 * time.Millisecond
+	restoreFunds             = 100
+)
+
+// restoreNode is a PaymentClient on the in-memory ledger whose channels are
+// persisted, so that it can be restarted.
+type restoreNode struct {
+	t         *testing.T
+	id        *client.Identity
+	dir       string
+	contracts *chtest.MemContracts
+	contract  channel.Contract
+	roots     *x509.CertPool
+	opts      []client.PaymentOpt
+
+	client  *client.PaymentClient
+	settled chan error
+}
+
+// failingContract fails all withdrawals, while failing is set.
+type failingContract struct {
+	*chtest.MemContract
+	failing int32
+}
+
+var errWithdrawal = errors.New("withdrawal failed")
+
+func (c *failingContract) Withdraw(req adj.SignedWithdrawReq) (*big.Int, error) {
+	if atomic.LoadInt32(&c.failing) != 0 {
+		return nil, errWithdrawal
+	}
+	return c.MemContract.Withdraw(req)
+}
+
+func (c *failingContract) ConcludeFinal(ch *adj.SignedChannel, reqs []adj.SignedWithdrawReq) ([]*big.Int, error) {
+	if atomic.LoadInt32(&c.failing) != 0 {
+		return nil, errWithdrawal
+	}
+	return c.MemContract.ConcludeFinal(ch, reqs)
+}
+
+func newRestoreNode(t *testing.T, rng *rand.Rand, id adj.AccountID, contracts *chtest.MemContracts,
+	roots *x509.CertPool, opts ...client.PaymentOpt) *restoreNode {
+	t.Helper()
+	require.NoError(t, contracts.Mint(id, big.NewInt(restoreFunds)))
+	wireAcc := fabwire.NewRandomAccount(rng)
+	roots.AddCert(wireAcc.Certificate())
+	acc := wireAcc.WalletAccount()
+	return &restoreNode{
+		t:         t,
+		id:        &client.Identity{Account: acc, Address: acc.FabricAddress(), AccountID: id, Cert: wireAcc.Certificate()},
+		dir:       t.TempDir(),
+		contracts: contracts,
+		contract:  contracts.Contract(id),
+		roots:     roots,
+		opts:      opts,
+		settled:   make(chan error, 1),
+	}
+}
+
+// start starts the client, which restores the persisted channels, and
+// registers its new endpoint.
+func (n *restoreNode) start() {
+	n.t.Helper()
+	opts := append([]client.PaymentOpt{
+		client.WithPersistence(n.dir),
+		client.WithListenAddress("127.0.0.1:0"),
+		client.WithChallengeDuration(restoreChallengeDuration),
+		client.WithAdjudicatorOpts(channel.WithSubPollingInterval(restorePolling)),
+		client.WithFunderOpts(channel.WithPollingInterval(restorePolling)),
+		client.WithProposalHandler(func(*client.PaymentProposal) bool { return true }),
+		client.WithSettleHandler(func(_ *client.PaymentChannel, err error) { n.settled <- err }),
+	}, n.opts...)
+	var err error
+	n.client, err = client.NewPaymentClientWithContract(n.contract, n.contracts.Contract(n.id.AccountID),
+		channel.Domain(), n.id, n.roots, opts...)
+	require.NoError(n.t, err)
+	require.NoError(n.t, n.client.Register())
+}
+
+// stop shuts down the client without settling its channels.
+func (n *restoreNode) stop() {
+	n.t.Helper()
+	require.NoError(n.t, n.client.Close())
+}
+
+// restored returns the only channel of the client.
+func (n *restoreNode) restored() *client.PaymentChannel {
+	n.t.Helper()
+	chs := n.client.Channels()
+	require.Len(n.t, chs, 1)
+	return chs[0]
+}
+
+func (n *restoreNode) awaitSettled(ctx context.Context) error {
+	n.t.Helper()
+	select {
+	case err := <-n.settled:
+		return err
+	case <-ctx.Done():
+		n.t.Fatal("channel not settled")
+		return nil
+	}
+}
+
+func (n *restoreNode) requireTokenBalance(expected int64) {
+	n.t.Helper()
+	bal, err := n.client.TokenBalance()
+	require.NoError(n.t, err)
+	require.Zero(n.t, bal.Cmp(big.NewInt(expected)), "token balance of %s: %v", n.id.AccountID, bal)
+}
+
+func setupRestoreTest(t *testing.T, bobOpts ...client.PaymentOpt) (alice, bob *restoreNode) {
+	t.Helper()
+	rng := test.Prng(t)
+	contracts := chtest.NewMemContracts(adj.NewAdjudicator(chtest.AdjudicatorName, channel.Domain(),
+		adj.NewMemLedger(), adj.NewMemAsset()))
+	roots := x509.NewCertPool()
+	return newRestoreNode(t, rng, "alice", contracts, roots), newRestoreNode(t, rng, "bob", contracts, roots, bobOpts...)
+}
+
+func TestPaymentClient_RestoreDispute(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTestTimeout)
+	defer cancel()
+	// Bob's settle timeout is shorter than the challenge duration.
+	alice, bob := setupRestoreTest(t, client.WithSettleTimeout(restorePolling))
+	alice.start()
+	bob.start()
+
+	chA, err := alice.client.OpenChannel(ctx, bob.id.AccountID, big.NewInt(restoreFunds), big.NewInt(restoreFunds))
+	require.NoError(t, err)
+	var outdated *adj.Backup
+	require.Eventually(t, func() bool {
+		outdated, err = chA.Backup()
+		return err == nil
+	}, time.Second, restorePolling)
+	require.NoError(t, chA.Pay(ctx, big.NewInt(10)))
+	id := chA.ID()
+
+	// While both clients are down, Alice registers the outdated state.
+	alice.stop()
+	bob.stop()
+	contract := alice.contracts.Contract(alice.id.AccountID)
+	require.NoError(t, contract.Register(&outdated.Channel))
+
+	// Bob's restored channel is watched again and the registration refuted.
+	bob.start()
+	chB := bob.restored()
+	require.Equal(t, id, chB.ID())
+	require.Eventually(t, func() bool {
+		reg, err := contract.StateReg(id)
+		return err == nil && reg.Version == 1
+	}, restoreChallengeDuration*time.Second, restorePolling)
+
+	// Bob stops while waiting for the dispute to time out.
+	settleCtx, settleCancel := context.WithTimeout(ctx, restorePolling)
+	defer settleCancel()
+	require.Error(t, chB.Settle(settleCtx))
+	bob.stop()
+
+	// The restarted client resumes the dispute until the challenge duration
+	// has passed and withdraws Bob's balance.
+	bob.start()
+	require.NoError(t, bob.awaitSettled(ctx))
+	require.Empty(t, bob.client.Channels())
+	bob.requireTokenBalance(restoreFunds + 10)
+	bob.stop()
+
+	// Alice withdraws the refuted balance.
+	alice.start()
+	require.NoError(t, alice.restored().Settle(ctx))
+	alice.requireTokenBalance(restoreFunds - 10)
+	alice.stop()
+}
+
+func TestPaymentClient_RestoreWithdrawal(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTestTimeout)
+	defer cancel()
+	alice, bob := setupRestoreTest(t)
+	contract := &failingContract{MemContract: bob.contracts.Contract(bob.id.AccountID), failing: 1}
+	bob.contract = contract
+	alice.start()
+	bob.start()
+
+	chA, err := alice.client.OpenChannel(ctx, bob.id.AccountID, big.NewInt(restoreFunds), big.NewInt(restoreFunds))
+	require.NoError(t, err)
+	require.NoError(t, chA.Pay(ctx, big.NewInt(10)))
+
+	// Alice closes the channel, but Bob fails to withdraw and stops.
+	require.NoError(t, chA.Settle(ctx))
+	alice.requireTokenBalance(restoreFunds - 10)
+	require.ErrorIs(t, bob.awaitSettled(ctx), errWithdrawal)
+	bob.stop()
+
+	// The restarted client resumes the withdrawal.
+	atomic.StoreInt32(&contract.failing, 0)
+	bob.start()
+	require.NoError(t, bob.awaitSettled(ctx))
+	require.Empty(t, bob.client.Channels())
+	bob.requireTokenBalance(restoreFunds + 10)
+
+	// Settled channels are not restored.
+	bob.stop()
+	bob.start()
+	require.Empty(t, bob.client.Channels())
+	bob.stop()
+	alice.stop()
+}
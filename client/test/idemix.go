@@ -0,0 +1,231 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/golang/protobuf/proto" //nolint:staticcheck
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+// idemixBigLen is the byte length of the big integers of Idemix, e.g., of the
+// coordinates of pseudonyms.
+const idemixBigLen = 32
+
+// fp256bn is the pairing-friendly BN curve y² = x³ + 3 of Fabric's Idemix
+// implementation. Its group G1 of prime order r is generated by g1.
+var fp256bn = struct {
+	p, r *big.Int
+	g1   g1Point
+}{
+	p:  mustBigHex("fffffffffffcf0cd46e5f25eee71a49f0cdc65fb12980a82d3292ddbaed33013"),
+	r:  mustBigHex("fffffffffffcf0cd46e5f25eee71a49e0cdc65fb1299921af62d536cd10b500d"),
+	g1: g1Point{x: big.NewInt(1), y: big.NewInt(2)}, //nolint:gomnd
+}
+
+type (
+	// IdemixIssuer is an Idemix test issuer. Its public key holds the bases
+	// HSk and HRand of the pseudonyms of its users, see IdemixUser.
+	IdemixIssuer struct {
+		mspID string
+		hSk   g1Point
+		hRand g1Point
+	}
+
+	// IdemixUser is a user of an IdemixIssuer, identified by its secret key.
+	IdemixUser struct {
+		issuer *IdemixIssuer
+		sk     *big.Int
+	}
+
+	// IdemixIdentity is an Idemix test identity of an IdemixUser. It is a
+	// serialized Idemix identity with a fresh pseudonym of the user, like the
+	// identities an Idemix client presents in its transactions. It holds no
+	// credential proof, which is verified by the MSP of the peers and not by
+	// the chaincode.
+	IdemixIdentity struct {
+		mspID string
+		creds []byte
+	}
+
+	// g1Point is an affine point of G1 of fp256bn. The point at infinity is
+	// represented by nil coordinates.
+	g1Point struct {
+		x, y *big.Int
+	}
+)
+
+// NewIdemixIssuer generates a new Idemix test issuer of the given MSP with
+// random pseudonym bases.
+func NewIdemixIssuer(rng io.Reader, mspID string) (*IdemixIssuer, error) {
+	hSk, err := randomG1(rng)
+	if err != nil {
+		return nil, err
+	}
+	hRand, err := randomG1(rng)
+	if err != nil {
+		return nil, err
+	}
+	return &IdemixIssuer{mspID: mspID, hSk: hSk, hRand: hRand}, nil
+}
+
+// NewUser generates a new user of the issuer with a random secret key.
+func (iss *IdemixIssuer) NewUser(rng io.Reader) (*IdemixUser, error) {
+	sk, err := randomZr(rng)
+	if err != nil {
+		return nil, err
+	}
+	return &IdemixUser{issuer: iss, sk: sk}, nil
+}
+
+// NewIdentity generates a new identity of the user with a fresh pseudonym
+// Nym = HSk^sk · HRand^rNym, like Idemix's MakeNym. Different identities of the
+// same user are unlinkable.
+func (u *IdemixUser) NewIdentity(rng io.Reader) (*IdemixIdentity, error) {
+	rNym, err := randomZr(rng)
+	if err != nil {
+		return nil, err
+	}
+	nym := u.issuer.hSk.mul(u.sk).add(u.issuer.hRand.mul(rNym))
+	if !nym.isOnCurve() {
+		return nil, errors.New("pseudonym not on curve")
+	}
+
+	mspID := u.issuer.mspID
+	ou, err := proto.Marshal(&msp.OrganizationUnit{
+		MspIdentifier:                mspID,
+		OrganizationalUnitIdentifier: "client",
+	})
+	if err != nil {
+		return nil, err
+	}
+	role, err := proto.Marshal(&msp.MSPRole{MspIdentifier: mspID, Role: msp.MSPRole_MEMBER}) //nolint:nosnakecase
+	if err != nil {
+		return nil, err
+	}
+	creds, err := proto.Marshal(&msp.SerializedIdemixIdentity{
+		NymX: nym.x.FillBytes(make([]byte, idemixBigLen)),
+		NymY: nym.y.FillBytes(make([]byte, idemixBigLen)),
+		Ou:   ou,
+		Role: role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &IdemixIdentity{mspID: mspID, creds: creds}, nil
+}
+
+// MspID returns the ID of the identity's MSP.
+func (id *IdemixIdentity) MspID() string { return id.mspID }
+
+// Credentials returns the serialized Idemix identity.
+func (id *IdemixIdentity) Credentials() []byte { return id.creds }
+
+// Creator returns the serialized identity as seen by the chaincode.
+func (id *IdemixIdentity) Creator() ([]byte, error) {
+	return proto.Marshal(&msp.SerializedIdentity{Mspid: id.mspID, IdBytes: id.creds})
+}
+
+// randomZr returns a random non-zero scalar of G1.
+func randomZr(rng io.Reader) (*big.Int, error) {
+	buf := make([]byte, idemixBigLen)
+	for {
+		if _, err := io.ReadFull(rng, buf); err != nil {
+			return nil, err
+		}
+		if k := new(big.Int).SetBytes(buf); k.Sign() != 0 && k.Cmp(fp256bn.r) < 0 {
+			return k, nil
+		}
+	}
+}
+
+// randomG1 returns a random point of G1 other than the point at infinity.
+func randomG1(rng io.Reader) (g1Point, error) {
+	k, err := randomZr(rng)
+	if err != nil {
+		return g1Point{}, err
+	}
+	return fp256bn.g1.mul(k), nil
+}
+
+// add returns p + q. It is not constant-time and only suited for testing.
+func (p g1Point) add(q g1Point) g1Point {
+	if p.x == nil {
+		return q
+	} else if q.x == nil {
+		return p
+	}
+	mod := fp256bn.p
+	var l *big.Int
+	if p.x.Cmp(q.x) == 0 {
+		if new(big.Int).Add(p.y, q.y).Cmp(mod) == 0 || p.y.Sign() == 0 {
+			return g1Point{}
+		}
+		// l = 3x² / 2y
+		l = new(big.Int).Mul(p.x, p.x)
+		l.Mul(l, big.NewInt(3)) //nolint:gomnd
+		l.Mul(l, new(big.Int).ModInverse(new(big.Int).Lsh(p.y, 1), mod))
+	} else {
+		// l = (y2 - y1) / (x2 - x1)
+		d := new(big.Int).Sub(q.x, p.x)
+		d.Mod(d, mod)
+		l = new(big.Int).Sub(q.y, p.y)
+		l.Mul(l, d.ModInverse(d, mod))
+	}
+	l.Mod(l, mod)
+	x := new(big.Int).Mul(l, l)
+	x.Sub(x, p.x).Sub(x, q.x).Mod(x, mod)
+	y := new(big.Int).Sub(p.x, x)
+	y.Mul(y, l).Sub(y, p.y).Mod(y, mod)
+	return g1Point{x: x, y: y}
+}
+
+// mul returns k·p by double-and-add. It is not constant-time and only suited
+// for testing.
+func (p g1Point) mul(k *big.Int) g1Point {
+	var r g1Point
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = r.add(r)
+		if k.Bit(i) == 1 {
+			r = r.add(p)
+		}
+	}
+	return r
+}
+
+// isOnCurve checks that p is a point of fp256bn other than the point at
+// infinity.
+func (p g1Point) isOnCurve() bool {
+	if p.x == nil {
+		return false
+	}
+	mod := fp256bn.p
+	lhs := new(big.Int).Mul(p.y, p.y)
+	lhs.Mod(lhs, mod)
+	rhs := new(big.Int).Exp(p.x, big.NewInt(3), mod) //nolint:gomnd
+	rhs.Add(rhs, big.NewInt(3)).Mod(rhs, mod)        //nolint:gomnd
+	return lhs.Cmp(rhs) == 0
+}
+
+func mustBigHex(s string) *big.Int {
+	b, ok := new(big.Int).SetString(s, 16) //nolint:gomnd
+	if !ok {
+		panic("invalid hex integer " + s)
+	}
+	return b
+}
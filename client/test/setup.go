@@ -26,8 +26,6 @@ import (
 	"perun.network/go-perun/wallet/test"
 	"perun.network/go-perun/watcher/local"
 	"perun.network/go-perun/wire"
-	simplewire "perun.network/go-perun/wire/net/simple"
-	pkgtest "polycry.pt/poly-go/test"
 	"testing"
 	"time"
 )
@@ -38,7 +36,6 @@ const clientTestTimeout = 30 * time.Second // Fixed. Not to be confused with the
 // Per client a channel test session, a client role setup and the initial asset balance is returned.
 func SetupClientTest(t *testing.T, name [2]string, chDuration uint64) ([]*chtest.Session, [2]clienttest.RoleSetup, [2]*big.Int) {
 	t.Helper()
-	rng := pkgtest.Prng(t)
 
 	var session []*chtest.Session
 	for i := uint(1); i <= 2; i++ {
@@ -56,9 +53,12 @@ func SetupClientTest(t *testing.T, name [2]string, chDuration uint64) ([]*chtest
 	for i := 0; i < len(roleSetup); i++ {
 		// Build role roleSetup for test.
 		watcher, _ := local.NewWatcher(session[i].Adjudicator)
+		// The wire identity is backed by the client's Fabric certificate.
+		identity, err := chtest.NewWireAccount(chtest.OrgNum(uint(i + 1)))
+		chtest.FatalErr(fmt.Sprintf("creating wire account[%d]", i), err)
 		roleSetup[i] = clienttest.RoleSetup{
 			Name:              name[i],
-			Identity:          simplewire.NewRandomAccount(rng),
+			Identity:          identity,
 			Bus:               bus,
 			Funder:            session[i].Funder,
 			Adjudicator:       session[i].Adjudicator,
@@ -0,0 +1,398 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	fabchannel "github.com/perun-network/perun-fabric/channel"
+	"github.com/perun-network/perun-fabric/channel/binding"
+	fabwallet "github.com/perun-network/perun-fabric/wallet"
+)
+
+// commands maps the command names to their implementations.
+var commands = map[string]func(*cli, []string) error{
+	"mint":     (*cli).mint,
+	"burn":     (*cli).burn,
+	"transfer": (*cli).transfer,
+	"balance":  (*cli).balance,
+	"deposit":  (*cli).deposit,
+	"holding":  (*cli).holding,
+	"channel":  (*cli).channel,
+	"register": (*cli).register,
+	"withdraw": (*cli).withdraw,
+}
+
+type (
+	balanceResult struct {
+		AccountID adj.AccountID `json:"accountId"`
+		Balance   *big.Int      `json:"balance"`
+	}
+
+	holdingResult struct {
+		ID      channel.ID     `json:"id"`
+		Part    wallet.Address `json:"part"`
+		Holding *big.Int       `json:"holding"`
+	}
+
+	channelResult struct {
+		ID           channel.ID       `json:"id"`
+		Registered   bool             `json:"registered"`
+		StateReg     *adj.StateReg    `json:"stateReg,omitempty"`
+		Holdings     []*holdingResult `json:"holdings,omitempty"`
+		TotalHolding *big.Int         `json:"totalHolding,omitempty"`
+	}
+
+	withdrawResult struct {
+		ID       channel.ID    `json:"id"`
+		Receiver adj.AccountID `json:"receiver"`
+		Amount   *big.Int      `json:"amount"`
+	}
+)
+
+func (c *cli) mint(args []string) error {
+	amount, err := parseArgsAmount(args)
+	if err != nil {
+		return err
+	}
+	if err := c.contract.MintToken(amount); err != nil {
+		return err
+	}
+	return c.printBalance(c.id.AccountID)
+}
+
+func (c *cli) burn(args []string) error {
+	amount, err := parseArgsAmount(args)
+	if err != nil {
+		return err
+	}
+	if err := c.contract.BurnToken(amount); err != nil {
+		return err
+	}
+	return c.printBalance(c.id.AccountID)
+}
+
+func (c *cli) transfer(args []string) error {
+	if len(args) != 2 { //nolint:gomnd
+		return errors.New("expected receiver and amount")
+	}
+	amount, err := parseAmount(args[1])
+	if err != nil {
+		return err
+	}
+	if err := c.contract.TokenTransfer(adj.AccountID(args[0]), amount); err != nil {
+		return err
+	}
+	return c.printBalance(c.id.AccountID)
+}
+
+func (c *cli) balance(args []string) error {
+	switch len(args) {
+	case 0:
+		return c.printBalance(c.id.AccountID)
+	case 1:
+		return c.printBalance(adj.AccountID(args[0]))
+	}
+	return errors.New("expected at most one account")
+}
+
+func (c *cli) printBalance(id adj.AccountID) error {
+	bal, err := c.contract.TokenBalance(id)
+	if err != nil {
+		return err
+	}
+	return c.out.print(balanceResult{AccountID: id, Balance: bal}, "Balance of %s: %v\n", id, bal)
+}
+
+func (c *cli) deposit(args []string) error {
+	fs := flag.NewFlagSet("deposit", flag.ContinueOnError)
+	partFlag := fs.String("part", "", "address of the participant, by default the own")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 2 { //nolint:gomnd
+		return errors.New("expected channel and amount")
+	}
+	id, err := parseChannelID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	part, err := c.parsePart(*partFlag)
+	if err != nil {
+		return err
+	}
+	amount, err := parseAmount(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if err := c.contract.Deposit(id, part, amount); err != nil {
+		return err
+	}
+	return c.printHolding(id, part)
+}
+
+func (c *cli) holding(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("expected channel and optional address")
+	}
+	id, err := parseChannelID(args[0])
+	if err != nil {
+		return err
+	}
+	var addr string
+	if len(args) == 2 { //nolint:gomnd
+		addr = args[1]
+	}
+	part, err := c.parsePart(addr)
+	if err != nil {
+		return err
+	}
+	return c.printHolding(id, part)
+}
+
+func (c *cli) printHolding(id channel.ID, part wallet.Address) error {
+	h, err := c.contract.Holding(id, part)
+	if err != nil {
+		return err
+	}
+	return c.out.print(holdingResult{ID: id, Part: part, Holding: h}, "Holding of %v in channel %x: %v\n", part, id, h)
+}
+
+func (c *cli) channel(args []string) error {
+	if len(args) < 1 {
+		return errors.New("expected channel")
+	}
+	id, err := parseChannelID(args[0])
+	if err != nil {
+		return err
+	}
+	res := channelResult{ID: id}
+	reg, err := c.contract.StateReg(id)
+	if err == nil {
+		res.Registered, res.StateReg = true, reg
+	} else if !isUnknownChannel(err) {
+		return err
+	}
+
+	parts := make([]wallet.Address, 0, len(args)-1)
+	for _, s := range args[1:] {
+		part, err := fabwallet.ParseAddress(s)
+		if err != nil {
+			return fmt.Errorf("parsing address %q: %w", s, err)
+		}
+		h, err := c.contract.Holding(id, part)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, part)
+		res.Holdings = append(res.Holdings, &holdingResult{ID: id, Part: part, Holding: h})
+	}
+	if len(parts) > 0 {
+		if res.TotalHolding, err = c.contract.TotalHolding(id, parts); err != nil {
+			return err
+		}
+	}
+
+	if c.out.json {
+		return c.out.print(res, "")
+	}
+	var s strings.Builder
+	fmt.Fprintf(&s, "Channel %x\n", id)
+	if reg != nil {
+		fmt.Fprintf(&s, "  Registered version: %d\n  Final: %t\n  Balances: %v\n  Timeout: %s\n",
+			reg.Version, reg.IsFinal, reg.Balances, reg.Timeout.Time().Format(time.RFC3339))
+	} else {
+		s.WriteString("  Not registered\n")
+	}
+	for _, h := range res.Holdings {
+		fmt.Fprintf(&s, "  Holding of %v: %v\n", h.Part, h.Holding)
+	}
+	if res.TotalHolding != nil {
+		fmt.Fprintf(&s, "  Total holding: %v\n", res.TotalHolding)
+	}
+	return c.out.print(nil, "%s", s.String())
+}
+
+func (c *cli) register(args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected backup or signed channel file")
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var ch adj.SignedChannel
+	if b, err := adj.ParseBackup(data); err == nil {
+		if err := c.adjudicator(c.id.AccountID).RegisterBackup(b); err != nil {
+			return err
+		}
+		ch = b.Channel
+	} else if !errors.Is(err, adj.ErrUnsupportedBackup) {
+		return err
+	} else if err := json.Unmarshal(data, &ch); err != nil {
+		return fmt.Errorf("decoding signed channel: %w", err)
+	} else if err := c.contract.Register(&ch); err != nil {
+		return err
+	}
+
+	reg, err := c.contract.StateReg(ch.State.ID)
+	if err != nil {
+		return err
+	}
+	return c.out.print(reg, "Registered channel %x at version %d, timeout %s\n",
+		reg.ID, reg.Version, reg.Timeout.Time().Format(time.RFC3339))
+}
+
+func (c *cli) withdraw(args []string) error {
+	fs := flag.NewFlagSet("withdraw", flag.ContinueOnError)
+	receiver := fs.String("receiver", "", "AccountID that receives the funds, by default the own")
+	backup := fs.String("backup", "", "path to the backup of the channel")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	to := c.id.AccountID
+	if *receiver != "" {
+		to = adj.AccountID(*receiver)
+	}
+	if *backup != "" {
+		if fs.NArg() != 0 {
+			return errors.New("unexpected channel with backup")
+		}
+		return c.withdrawBackup(*backup, to)
+	} else if fs.NArg() != 1 {
+		return errors.New("expected channel")
+	}
+
+	id, err := parseChannelID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	req, err := adj.SignWithdrawRequest(c.id.Account, c.domain, id, to)
+	if err != nil {
+		return fmt.Errorf("signing withdraw request: %w", err)
+	}
+	amount, err := c.contract.Withdraw(*req)
+	if err != nil {
+		return err
+	}
+	return c.printWithdrawal(id, to, amount)
+}
+
+func (c *cli) withdrawBackup(path string, to adj.AccountID) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b, err := adj.ReadBackup(f)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	amount, err := c.adjudicator(to).WithdrawBackup(ctx, b, c.id.Account)
+	if err != nil {
+		return err
+	}
+	return c.printWithdrawal(b.Channel.State.ID, to, amount)
+}
+
+func (c *cli) printWithdrawal(id channel.ID, to adj.AccountID, amount *big.Int) error {
+	return c.out.print(withdrawResult{ID: id, Receiver: to, Amount: amount},
+		"Withdrew %v from channel %x to %s\n", amount, id, to)
+}
+
+// adjudicator returns the Adjudicator withdrawing to the given AccountID.
+func (c *cli) adjudicator(receiver adj.AccountID) *fabchannel.Adjudicator {
+	return fabchannel.NewAdjudicatorWithContract(c.contract, c.domain, receiver, c.adjOpts...)
+}
+
+// parsePart parses the address of a participant. An empty string is the own
+// address.
+func (c *cli) parsePart(s string) (wallet.Address, error) {
+	if s == "" {
+		return c.id.Address, nil
+	}
+	addr, err := fabwallet.ParseAddress(s)
+	if err != nil {
+		return nil, fmt.Errorf("parsing address %q: %w", s, err)
+	}
+	return addr, nil
+}
+
+func parseArgsAmount(args []string) (*big.Int, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected amount")
+	}
+	return parseAmount(args[0])
+}
+
+func parseAmount(s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10) //nolint:gomnd
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+func parseChannelID(s string) (channel.ID, error) {
+	var id channel.ID
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return id, fmt.Errorf("parsing channel id: %w", err)
+	} else if len(b) != len(id) {
+		return id, errors.New("invalid channel id length")
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// isUnknownChannel returns whether err indicates that no state is registered
+// for the channel.
+func isUnknownChannel(err error) bool {
+	return errors.Is(err, adj.ErrUnknownChannel) || binding.IsChannelUnknownErr(err)
+}
+
+// output prints command results either as text or as JSON.
+type output struct {
+	json bool
+	w    io.Writer
+}
+
+// print prints v as JSON or the formatted text otherwise.
+func (o output) print(v interface{}, format string, args ...interface{}) error {
+	if o.json {
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	_, err := fmt.Fprintf(o.w, format, args...)
+	return err
+}
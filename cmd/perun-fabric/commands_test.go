@@ -0,0 +1,435 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	ptest "polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	fabchannel "github.com/perun-network/perun-fabric/channel"
+	chtest "github.com/perun-network/perun-fabric/channel/test"
+	fabclient "github.com/perun-network/perun-fabric/client"
+	fabwallet "github.com/perun-network/perun-fabric/wallet"
+)
+
+// cmdEnv is the environment of a command invocation, whose cli runs on the
+// in-memory contract of the setup's Adjudicator as participant 0.
+type cmdEnv struct {
+	*adjtest.Setup
+	contracts *chtest.MemContracts
+	c         *cli
+	out       *bytes.Buffer
+	dir       string
+}
+
+func newCmdEnv(t *testing.T, jsonOut bool, opts ...adjtest.SetupOption) *cmdEnv {
+	t.Helper()
+	s := adjtest.NewSetup(ptest.Prng(t), opts...)
+	acc, ok := s.Accs[0].(*fabwallet.Account)
+	require.True(t, ok)
+	addr, ok := acc.Address().(*fabwallet.Address)
+	require.True(t, ok)
+
+	contracts := chtest.NewMemContracts(s.Adj)
+	out := new(bytes.Buffer)
+	return &cmdEnv{
+		Setup:     s,
+		contracts: contracts,
+		c: &cli{
+			contract: contracts.Contract(s.IDs[0]),
+			id:       &fabclient.Identity{Account: acc, Address: addr, AccountID: s.IDs[0]},
+			domain:   s.Domain,
+			out:      output{json: jsonOut, w: out},
+		},
+		out: out,
+		dir: t.TempDir(),
+	}
+}
+
+// id returns the hex encoded channel id of the setup.
+func (e *cmdEnv) id() string {
+	return fmt.Sprintf("%x", e.State.ID)
+}
+
+// bal returns the balance of participant idx in the setup's state.
+func (e *cmdEnv) bal(idx int) *big.Int {
+	return e.State.Balances[idx]
+}
+
+// writeFile writes data to the file name in the environment's directory and
+// returns its path.
+func (e *cmdEnv) writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(e.dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func (e *cmdEnv) writeSignedChannel(t *testing.T) string {
+	t.Helper()
+	data, err := json.Marshal(e.SignedChannel())
+	require.NoError(t, err)
+	return e.writeFile(t, "channel.json", data)
+}
+
+func (e *cmdEnv) writeBackup(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := adj.NewBackup(e.Domain, e.SignedChannel()).WriteTo(&buf)
+	require.NoError(t, err)
+	return e.writeFile(t, "backup.json", buf.Bytes())
+}
+
+func (e *cmdEnv) register(t *testing.T) {
+	t.Helper()
+	require.NoError(t, e.Adj.Register(e.SignedChannel()))
+}
+
+func (e *cmdEnv) mint(t *testing.T, idx int, amount int64) {
+	t.Helper()
+	require.NoError(t, e.contracts.Mint(e.IDs[idx], big.NewInt(amount)))
+}
+
+func (e *cmdEnv) stateReg(t *testing.T) *adj.StateReg {
+	t.Helper()
+	reg, err := e.Adj.StateReg(e.State.ID)
+	require.NoError(t, err)
+	return reg
+}
+
+func formatTime(ts adj.Timestamp) string {
+	return ts.Time().Format(time.RFC3339)
+}
+
+func TestCommands(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []adjtest.SetupOption
+		// prepare prepares the environment and returns the command line.
+		prepare func(*testing.T, *cmdEnv) []string
+		// text and json return the expected output.
+		text func(*testing.T, *cmdEnv) string
+		json func(*testing.T, *cmdEnv) interface{}
+	}{
+		{
+			name: "mint",
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				return []string{"mint", "10"}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Balance of %s: 10\n", e.IDs[0])
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return balanceResult{AccountID: e.IDs[0], Balance: big.NewInt(10)}
+			},
+		},
+		{
+			name: "burn",
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.mint(t, 0, 10)
+				return []string{"burn", "4"}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Balance of %s: 6\n", e.IDs[0])
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return balanceResult{AccountID: e.IDs[0], Balance: big.NewInt(6)}
+			},
+		},
+		{
+			name: "transfer",
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.mint(t, 0, 10)
+				return []string{"transfer", string(e.IDs[1]), "3"}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Balance of %s: 7\n", e.IDs[0])
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return balanceResult{AccountID: e.IDs[0], Balance: big.NewInt(7)}
+			},
+		},
+		{
+			name: "balance",
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.mint(t, 0, 5)
+				return []string{"balance"}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Balance of %s: 5\n", e.IDs[0])
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return balanceResult{AccountID: e.IDs[0], Balance: big.NewInt(5)}
+			},
+		},
+		{
+			name: "balance-account",
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.mint(t, 1, 8)
+				return []string{"balance", string(e.IDs[1])}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Balance of %s: 8\n", e.IDs[1])
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return balanceResult{AccountID: e.IDs[1], Balance: big.NewInt(8)}
+			},
+		},
+		{
+			name: "deposit",
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.mint(t, 0, 5)
+				return []string{"deposit", e.id(), "5"}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Holding of %v in channel %x: 5\n", e.Parts[0], e.State.ID)
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return holdingResult{ID: e.State.ID, Part: e.Parts[0], Holding: big.NewInt(5)}
+			},
+		},
+		{
+			name: "deposit-part",
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.mint(t, 0, 5)
+				return []string{"deposit", "-part", e.Parts[1].String(), "0x" + e.id(), "5"}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Holding of %v in channel %x: 5\n", e.Parts[1], e.State.ID)
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return holdingResult{ID: e.State.ID, Part: e.Parts[1], Holding: big.NewInt(5)}
+			},
+		},
+		{
+			name: "holding",
+			opts: []adjtest.SetupOption{adjtest.Funded},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				return []string{"holding", e.id()}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Holding of %v in channel %x: %v\n", e.Parts[0], e.State.ID, e.bal(0))
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return holdingResult{ID: e.State.ID, Part: e.Parts[0], Holding: e.bal(0)}
+			},
+		},
+		{
+			name: "holding-part",
+			opts: []adjtest.SetupOption{adjtest.Funded},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				return []string{"holding", e.id(), e.Parts[1].String()}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Holding of %v in channel %x: %v\n", e.Parts[1], e.State.ID, e.bal(1))
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return holdingResult{ID: e.State.ID, Part: e.Parts[1], Holding: e.bal(1)}
+			},
+		},
+		{
+			name: "channel-unregistered",
+			opts: []adjtest.SetupOption{adjtest.Funded},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				return []string{"channel", e.id(), e.Parts[0].String(), e.Parts[1].String()}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Channel %x\n  Not registered\n  Holding of %v: %v\n  Holding of %v: %v\n  Total holding: %v\n",
+					e.State.ID, e.Parts[0], e.bal(0), e.Parts[1], e.bal(1), new(big.Int).Add(e.bal(0), e.bal(1)))
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return channelResult{
+					ID: e.State.ID,
+					Holdings: []*holdingResult{
+						{ID: e.State.ID, Part: e.Parts[0], Holding: e.bal(0)},
+						{ID: e.State.ID, Part: e.Parts[1], Holding: e.bal(1)},
+					},
+					TotalHolding: new(big.Int).Add(e.bal(0), e.bal(1)),
+				}
+			},
+		},
+		{
+			name: "channel-registered",
+			opts: []adjtest.SetupOption{adjtest.Funded, adjtest.WithVersion(3)},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.register(t)
+				return []string{"channel", e.id()}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Channel %x\n  Registered version: 3\n  Final: false\n  Balances: %v\n  Timeout: %s\n",
+					e.State.ID, e.State.Balances, formatTime(e.stateReg(t).Timeout))
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return channelResult{ID: e.State.ID, Registered: true, StateReg: e.stateReg(t)}
+			},
+		},
+		{
+			name: "register",
+			opts: []adjtest.SetupOption{adjtest.Funded, adjtest.WithVersion(2)},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				return []string{"register", e.writeSignedChannel(t)}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Registered channel %x at version 2, timeout %s\n",
+					e.State.ID, formatTime(e.stateReg(t).Timeout))
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return e.stateReg(t)
+			},
+		},
+		{
+			name: "register-backup",
+			opts: []adjtest.SetupOption{adjtest.Funded, adjtest.WithVersion(4)},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				return []string{"register", e.writeBackup(t)}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Registered channel %x at version 4, timeout %s\n",
+					e.State.ID, formatTime(e.stateReg(t).Timeout))
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return e.stateReg(t)
+			},
+		},
+		{
+			name: "withdraw",
+			opts: []adjtest.SetupOption{adjtest.Funded, adjtest.WithFinalState},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.register(t)
+				return []string{"withdraw", e.id()}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Withdrew %v from channel %x to %s\n", e.bal(0), e.State.ID, e.IDs[0])
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return withdrawResult{ID: e.State.ID, Receiver: e.IDs[0], Amount: e.bal(0)}
+			},
+		},
+		{
+			name: "withdraw-receiver",
+			opts: []adjtest.SetupOption{adjtest.Funded, adjtest.WithFinalState},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.register(t)
+				return []string{"withdraw", "-receiver", string(e.IDs[1]), e.id()}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Withdrew %v from channel %x to %s\n", e.bal(0), e.State.ID, e.IDs[1])
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return withdrawResult{ID: e.State.ID, Receiver: e.IDs[1], Amount: e.bal(0)}
+			},
+		},
+		{
+			name: "withdraw-backup-final",
+			opts: []adjtest.SetupOption{adjtest.Funded, adjtest.WithFinalState},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				return []string{"withdraw", "-backup", e.writeBackup(t)}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Withdrew %v from channel %x to %s\n", e.bal(0), e.State.ID, e.IDs[0])
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return withdrawResult{ID: e.State.ID, Receiver: e.IDs[0], Amount: e.bal(0)}
+			},
+		},
+		{
+			name: "withdraw-backup",
+			opts: []adjtest.SetupOption{adjtest.Funded},
+			prepare: func(t *testing.T, e *cmdEnv) []string {
+				e.register(t)
+				e.Ledger.AdvanceNow(e.Params.ChallengeDuration + 1)
+				clock := chtest.NewFakeClock(e.stateReg(t).Timeout.Time().Add(time.Second))
+				e.c.adjOpts = []fabchannel.AdjudicatorOpt{fabchannel.WithAdjudicatorClock(clock)}
+				return []string{"withdraw", "-receiver", string(e.IDs[1]), "-backup", e.writeBackup(t)}
+			},
+			text: func(t *testing.T, e *cmdEnv) string {
+				return fmt.Sprintf("Withdrew %v from channel %x to %s\n", e.bal(0), e.State.ID, e.IDs[1])
+			},
+			json: func(t *testing.T, e *cmdEnv) interface{} {
+				return withdrawResult{ID: e.State.ID, Receiver: e.IDs[1], Amount: e.bal(0)}
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("text", func(t *testing.T) {
+				e := newCmdEnv(t, false, tc.opts...)
+				args := tc.prepare(t, e)
+				require.NoError(t, commands[args[0]](e.c, args[1:]))
+				require.Equal(t, tc.text(t, e), e.out.String())
+			})
+			t.Run("json", func(t *testing.T) {
+				e := newCmdEnv(t, true, tc.opts...)
+				args := tc.prepare(t, e)
+				require.NoError(t, commands[args[0]](e.c, args[1:]))
+				want, err := json.Marshal(tc.json(t, e))
+				require.NoError(t, err)
+				require.JSONEq(t, string(want), e.out.String())
+			})
+		})
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	const id = "c0ffee"
+	for _, tc := range []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{"mint-missing", []string{"mint"}, "expected amount"},
+		{"mint-extra", []string{"mint", "1", "2"}, "expected amount"},
+		{"mint-negative", []string{"mint", "-1"}, `invalid amount "-1"`},
+		{"burn-invalid", []string{"burn", "1.5"}, `invalid amount "1.5"`},
+		{"transfer-missing", []string{"transfer", "alice"}, "expected receiver and amount"},
+		{"transfer-invalid", []string{"transfer", "alice", "ten"}, `invalid amount "ten"`},
+		{"balance-extra", []string{"balance", "alice", "bob"}, "expected at most one account"},
+		{"deposit-missing", []string{"deposit", id}, "expected channel and amount"},
+		{"deposit-flag", []string{"deposit", "-receiver", "alice", id, "1"}, "flag provided but not defined: -receiver"},
+		{"deposit-part", []string{"deposit", "-part", "xyz", strings.Repeat("00", 32), "1"}, `parsing address "xyz"`},
+		{"holding-missing", []string{"holding"}, "expected channel and optional address"},
+		{"holding-hex", []string{"holding", "xyz"}, "parsing channel id"},
+		{"holding-length", []string{"holding", id}, "invalid channel id length"},
+		{"channel-missing", []string{"channel"}, "expected channel"},
+		{"channel-address", []string{"channel", strings.Repeat("00", 32), "xyz"}, `parsing address "xyz"`},
+		{"register-missing", []string{"register"}, "expected backup or signed channel file"},
+		{"register-file", []string{"register", "does-not-exist.json"}, "no such file or directory"},
+		{"withdraw-missing", []string{"withdraw"}, "expected channel"},
+		{"withdraw-extra", []string{"withdraw", id, id}, "expected channel"},
+		{"withdraw-backup-channel", []string{"withdraw", "-backup", "backup.json", id}, "unexpected channel with backup"},
+		{"withdraw-flag", []string{"withdraw", "-part"}, "flag provided but not defined: -part"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			e := newCmdEnv(t, false)
+			err := commands[tc.args[0]](e.c, tc.args[1:])
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+			require.Empty(t, e.out.String())
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command perun-fabric is an operator tool for the adjudicator chaincode. It
+// manages tokens, funds channels and settles registered channel states.
+//
+// Usage:
+//
+//	perun-fabric [flags] <command> [arguments]
+//
+// Run perun-fabric -h for the list of flags and commands.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	fabchannel "github.com/perun-network/perun-fabric/channel"
+	"github.com/perun-network/perun-fabric/channel/binding"
+	fabclient "github.com/perun-network/perun-fabric/client"
+	"github.com/perun-network/perun-fabric/config"
+)
+
+const usage = `Usage: perun-fabric [flags] <command> [arguments]
+
+Commands:
+  mint <amount>                        Mint tokens to the own account.
+  burn <amount>                        Burn tokens of the own account.
+  transfer <account> <amount>          Transfer tokens to the given AccountID.
+  balance [account]                    Print the token balance of an AccountID, by default the own.
+  deposit [-part address] <channel> <amount>
+                                       Deposit tokens into a channel for a participant, by default the own address.
+  holding <channel> [address]          Print the holding of a participant, by default the own address.
+  channel <channel> [address...]       Print the registered state and the holdings of the given participants.
+  register <file>                      Register the signed channel state of the backup or signed channel file.
+  withdraw [-receiver account] <channel>
+                                       Withdraw the own funds of a concluded channel, by default to the own account.
+  withdraw [-receiver account] -backup <file>
+                                       Withdraw the own funds of the channel of the backup file. A final state is
+                                       concluded, otherwise the end of the challenge duration is awaited.
+
+Flags:
+`
+
+// cli holds the contract and identity of a command invocation.
+type cli struct {
+	contract contract
+	id       *fabclient.Identity
+	domain   adj.Domain
+	adjOpts  []fabchannel.AdjudicatorOpt // options of the Adjudicator handling backups
+	out      output
+}
+
+// contract is the part of the chaincode binding used by the commands. It is
+// implemented by binding.Adjudicator.
+type contract interface {
+	fabchannel.Contract
+	Holding(id channel.ID, part wallet.Address) (*big.Int, error)
+	MintToken(amount *big.Int) error
+	BurnToken(amount *big.Int) error
+	TokenTransfer(receiver adj.AccountID, amount *big.Int) error
+	TokenBalance(owner adj.AccountID) (*big.Int, error)
+}
+
+var _ contract = (*binding.Adjudicator)(nil)
+
+// options holds the global flags of a command invocation.
+type options struct {
+	profile      string
+	peerEndpoint string
+	gatewayPeer  string
+	tlsCert      string
+	mspID        string
+	mspDir       string
+	channel      string
+	chaincode    string
+	json         bool
+}
+
+func main() {
+	opts, cmd, args, err := parseArgs(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		os.Exit(2) //nolint:gomnd
+	}
+
+	c, closeConn, err := connect(opts)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	c.out = output{json: opts.json, w: os.Stdout}
+	err = commands[cmd](c, args)
+	_ = closeConn()
+	if err != nil {
+		fatalf("%s: %v\n%s", cmd, err, fabclient.ParseClientErr(err))
+	}
+}
+
+// parseArgs parses the global flags and the command of the command line
+// arguments args. It returns the command's name and arguments. The usage and
+// parsing errors are written to w.
+func parseArgs(args []string, w io.Writer) (opts options, cmd string, cmdArgs []string, err error) {
+	fs := flag.NewFlagSet("perun-fabric", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.StringVar(&opts.profile, "profile", "", "path to a connection profile; overrides the connection and identity flags")
+	fs.StringVar(&opts.peerEndpoint, "peer", "localhost:7051", "endpoint of the gateway peer")
+	fs.StringVar(&opts.gatewayPeer, "gateway", "peer0.org1.example.com", "TLS server name of the gateway peer")
+	fs.StringVar(&opts.tlsCert, "tlscert", "", "path to the TLS CA certificate of the gateway peer")
+	fs.StringVar(&opts.mspID, "msp", "Org1MSP", "MSP id of the client identity")
+	fs.StringVar(&opts.mspDir, "mspdir", "", "path to the MSP directory of the client identity")
+	fs.StringVar(&opts.channel, "channel", "mychannel", "name of the Fabric channel")
+	fs.StringVar(&opts.chaincode, "chaincode", "adjudicator", "name of the adjudicator chaincode")
+	fs.BoolVar(&opts.json, "json", false, "print the output as JSON")
+	fs.Usage = func() {
+		fmt.Fprint(w, usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, "", nil, err
+	}
+	cmd = fs.Arg(0)
+	if _, ok := commands[cmd]; !ok {
+		if cmd == "" {
+			err = errors.New("missing command")
+		} else {
+			err = fmt.Errorf("unknown command %q", cmd)
+		}
+		fmt.Fprintf(w, "%v\n", err)
+		fs.Usage()
+		return opts, "", nil, err
+	}
+	return opts, cmd, fs.Args()[1:], nil
+}
+
+// connect connects to the gateway peer given by the options and returns the
+// cli of the client identity, together with a function closing the
+// connection.
+func connect(opts options) (*cli, func() error, error) {
+	if opts.profile != "" {
+		cfg, err := config.Load(opts.profile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading profile: %w", err)
+		}
+		s, err := cfg.Connect()
+		if err != nil {
+			return nil, nil, fmt.Errorf("connecting: %w", err)
+		}
+		return &cli{
+			contract: binding.NewAdjudicatorBinding(s.Network, cfg.Perun.Chaincode),
+			id:       s.Identity,
+			domain:   adj.NewDomain(cfg.Perun.Channel, cfg.Perun.Chaincode),
+		}, s.Close, nil
+	}
+
+	conn, err := fabclient.NewGrpcConnection(opts.gatewayPeer, opts.peerEndpoint, opts.tlsCert)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating gRPC connection: %w", err)
+	}
+	id, err := fabclient.LoadMSPIdentity(opts.mspID, opts.mspDir)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("loading identity: %w", err)
+	}
+	gw, err := client.Connect(id.X509, client.WithSign(id.Sign), client.WithClientConnection(conn))
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("connecting to gateway: %w", err)
+	}
+	c := &cli{
+		contract: binding.NewAdjudicatorBinding(gw.GetNetwork(opts.channel), opts.chaincode),
+		id:       id,
+		domain:   adj.NewDomain(opts.channel, opts.chaincode),
+	}
+	closeConn := func() error {
+		gw.Close()
+		return conn.Close()
+	}
+	return c, closeConn, nil
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArgs(t *testing.T) {
+	defaults := options{
+		peerEndpoint: "localhost:7051",
+		gatewayPeer:  "peer0.org1.example.com",
+		mspID:        "Org1MSP",
+		channel:      "mychannel",
+		chaincode:    "adjudicator",
+	}
+	with := func(mod func(*options)) options {
+		opts := defaults
+		mod(&opts)
+		return opts
+	}
+
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		opts    options
+		cmd     string
+		cmdArgs []string
+		err     string
+	}{
+		{
+			name:    "defaults",
+			args:    []string{"balance"},
+			opts:    defaults,
+			cmd:     "balance",
+			cmdArgs: []string{},
+		},
+		{
+			name:    "command arguments",
+			args:    []string{"deposit", "-part", "ab", "cd", "10"},
+			opts:    defaults,
+			cmd:     "deposit",
+			cmdArgs: []string{"-part", "ab", "cd", "10"},
+		},
+		{
+			name: "connection flags",
+			args: []string{
+				"-peer", "localhost:9051", "-gateway", "peer0.org2.example.com", "-tlscert", "ca.crt",
+				"-msp", "Org2MSP", "-mspdir", "msp", "-channel", "ch", "-chaincode", "cc", "-json",
+				"mint", "5",
+			},
+			opts: options{
+				peerEndpoint: "localhost:9051",
+				gatewayPeer:  "peer0.org2.example.com",
+				tlsCert:      "ca.crt",
+				mspID:        "Org2MSP",
+				mspDir:       "msp",
+				channel:      "ch",
+				chaincode:    "cc",
+				json:         true,
+			},
+			cmd:     "mint",
+			cmdArgs: []string{"5"},
+		},
+		{
+			name:    "profile",
+			args:    []string{"-profile", "profile.yaml", "withdraw", "-backup", "backup.json"},
+			opts:    with(func(o *options) { o.profile = "profile.yaml" }),
+			cmd:     "withdraw",
+			cmdArgs: []string{"-backup", "backup.json"},
+		},
+		{
+			name: "missing command",
+			args: []string{"-json"},
+			err:  "missing command",
+		},
+		{
+			name: "unknown command",
+			args: []string{"close", "ab"},
+			err:  `unknown command "close"`,
+		},
+		{
+			name:    "flag after command",
+			args:    []string{"balance", "-json"},
+			opts:    defaults,
+			cmd:     "balance",
+			cmdArgs: []string{"-json"},
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-org", "1", "balance"},
+			err:  "flag provided but not defined: -org",
+		},
+		{
+			name: "missing flag value",
+			args: []string{"-chaincode"},
+			err:  "flag needs an argument: -chaincode",
+		},
+		{
+			name: "invalid bool flag",
+			args: []string{"-json=maybe", "balance"},
+			err:  `invalid boolean value "maybe" for -json: parse error`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opts, cmd, cmdArgs, err := parseArgs(tc.args, io.Discard)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.opts, opts)
+			require.Equal(t, tc.cmd, cmd)
+			require.Equal(t, tc.cmdArgs, cmdArgs)
+		})
+	}
+
+	t.Run("help", func(t *testing.T) {
+		var out strings.Builder
+		_, _, _, err := parseArgs([]string{"-h"}, &out)
+		require.ErrorIs(t, err, flag.ErrHelp)
+		require.Contains(t, out.String(), usage)
+		require.Contains(t, out.String(), "-chaincode")
+	})
+}
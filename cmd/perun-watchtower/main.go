@@ -0,0 +1,103 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command perun-watchtower runs a Watchtower for the channels of an adjudicator
+// chaincode. Clients hand their latest signed states to its HTTP API.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/channel/binding"
+	fabclient "github.com/perun-network/perun-fabric/client"
+	"github.com/perun-network/perun-fabric/watchtower"
+)
+
+func main() {
+	var (
+		peerEndpoint = flag.String("peer", "localhost:7051", "endpoint of the gateway peer")
+		gatewayPeer  = flag.String("gateway", "peer0.org1.example.com", "TLS server name of the gateway peer")
+		tlsCert      = flag.String("tlscert", "", "path to the TLS CA certificate of the gateway peer")
+		mspID        = flag.String("msp", "Org1MSP", "MSP id of the client identity")
+		cert         = flag.String("cert", "", "path to the certificate of the client identity")
+		key          = flag.String("key", "", "path to the private key of the client identity")
+		fabChannel   = flag.String("channel", "mychannel", "name of the Fabric channel")
+		chaincode    = flag.String("chaincode", "adjudicator", "name of the adjudicator chaincode")
+		storeDir     = flag.String("store", "watchtower", "directory in which the signed states are stored")
+		listen       = flag.String("listen", "localhost:8080", "address the HTTP API listens on")
+		polling      = flag.Duration("polling", time.Second, "interval in which registered states are checked")
+	)
+	flag.Parse()
+
+	conn, err := fabclient.NewGrpcConnection(*gatewayPeer, *peerEndpoint, *tlsCert)
+	if err != nil {
+		log.Fatalf("Creating gRPC connection: %v", err)
+	}
+	defer conn.Close()
+
+	id, _, _, err := fabclient.NewIdentity(*mspID, *cert)
+	if err != nil {
+		log.Fatalf("Creating identity: %v", err)
+	}
+	sign, _, err := fabclient.NewAccountWithSigner(*key)
+	if err != nil {
+		log.Fatalf("Creating signer: %v", err)
+	}
+	gw, err := client.Connect(id, client.WithSign(sign), client.WithClientConnection(conn))
+	if err != nil {
+		log.Fatalf("Connecting to gateway: %v", err)
+	}
+	defer gw.Close()
+
+	store, err := watchtower.NewFileStore(*storeDir)
+	if err != nil {
+		log.Fatalf("Opening store: %v", err)
+	}
+	w := watchtower.New(
+		binding.NewAdjudicatorBinding(gw.GetNetwork(*fabChannel), *chaincode),
+		adj.NewDomain(*fabChannel, *chaincode),
+		store,
+		watchtower.WithPollingInterval(*polling),
+		watchtower.WithErrorHandler(func(err error) { log.Printf("Checking channels: %v", err) }),
+	)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	srv := &http.Server{Addr: *listen, Handler: w.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Serving HTTP API: %v", err)
+			cancel()
+		}
+	}()
+
+	log.Printf("Watching channels of chaincode %s on channel %s", *chaincode, *fabChannel)
+	if err := w.Run(ctx); err != nil && ctx.Err() == nil {
+		log.Fatalf("Running watchtower: %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config loads Fabric connection profiles extended by a Perun section
+// and sets up the Perun Fabric backend from them.
+package config
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"gopkg.in/yaml.v3"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+const (
+	grpcsScheme = "grpcs"
+
+	grpcOptSSLTargetNameOverride = "ssl-target-name-override"
+	grpcOptHostnameOverride      = "hostnameOverride"
+)
+
+type (
+	// Config is a Fabric connection profile with an additional Perun section.
+	// Relative paths are resolved against the directory of the profile.
+	Config struct {
+		Name          string                  `yaml:"name"`
+		Client        Client                  `yaml:"client"`
+		Organizations map[string]Organization `yaml:"organizations"`
+		Peers         map[string]Peer         `yaml:"peers"`
+		Perun         Perun                   `yaml:"perun"`
+
+		dir string // dir is the directory relative paths are resolved against.
+	}
+
+	// Client describes the client of the connection profile.
+	Client struct {
+		Organization string   `yaml:"organization"`
+		TLSCerts     TLSCerts `yaml:"tlsCerts"`
+	}
+
+	// TLSCerts holds the TLS client certificate and key, which are presented
+	// to the peers for mutual TLS. Both are optional.
+	TLSCerts struct {
+		Client struct {
+			Key  PEM `yaml:"key"`
+			Cert PEM `yaml:"cert"`
+		} `yaml:"client"`
+	}
+
+	// Organization is an organization of the connection profile.
+	Organization struct {
+		MSPID string   `yaml:"mspid"`
+		Peers []string `yaml:"peers"`
+	}
+
+	// Peer is a peer of the connection profile.
+	Peer struct {
+		URL         string                 `yaml:"url"`
+		TLSCACerts  PEM                    `yaml:"tlsCACerts"`
+		GRPCOptions map[string]interface{} `yaml:"grpcOptions"`
+	}
+
+	// PEM holds PEM encoded data, either inline or in a file.
+	PEM struct {
+		PEM  string `yaml:"pem"`
+		Path string `yaml:"path"`
+	}
+
+	// Perun is the Perun section of the connection profile.
+	Perun struct {
+		// Channel is the name of the Fabric channel the chaincode is deployed on.
+		Channel string `yaml:"channel"`
+		// Chaincode is the name of the Adjudicator chaincode.
+		Chaincode string `yaml:"chaincode"`
+		// Identity locates the client identity.
+		Identity Identity `yaml:"identity"`
+		// Receiver receives withdrawn funds. Defaults to the client identity.
+		Receiver adj.AccountID `yaml:"receiver"`
+		// FunderPollingInterval is the polling interval of the Funder.
+		FunderPollingInterval time.Duration `yaml:"funderPollingInterval"`
+		// AdjudicatorPollingInterval is the polling interval of the
+		// Adjudicator's event subscriptions.
+		AdjudicatorPollingInterval time.Duration `yaml:"adjudicatorPollingInterval"`
+		// Timeouts are the Gateway timeouts.
+		Timeouts Timeouts `yaml:"timeouts"`
+	}
+
+	// Identity locates the client identity, either in an MSP directory or in
+	// a Fabric SDK wallet identity file.
+	Identity struct {
+		MSPPath    string `yaml:"mspPath"`
+		WalletFile string `yaml:"walletFile"`
+	}
+
+	// Timeouts are the timeouts of the Gateway calls. Zero values are
+	// replaced by defaults.
+	Timeouts struct {
+		Evaluate     time.Duration `yaml:"evaluate"`
+		Endorse      time.Duration `yaml:"endorse"`
+		Submit       time.Duration `yaml:"submit"`
+		CommitStatus time.Duration `yaml:"commitStatus"`
+	}
+)
+
+// Default timeouts of the Gateway calls.
+const (
+	DefaultEvaluateTimeout     = 5 * time.Second
+	DefaultEndorseTimeout      = 15 * time.Second
+	DefaultSubmitTimeout       = 5 * time.Second
+	DefaultCommitStatusTimeout = 1 * time.Minute
+)
+
+// Load loads the connection profile at the given path. Profiles can be
+// written in YAML or JSON.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading connection profile: %w", err)
+	}
+	return Parse(data, filepath.Dir(path))
+}
+
+// Parse parses a YAML or JSON connection profile. Relative paths are resolved
+// against dir.
+func Parse(data []byte, dir string) (*Config, error) {
+	var c Config
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing connection profile: %w", err)
+	}
+	c.dir = dir
+	c.Perun.Timeouts.setDefaults()
+	return &c, c.validate()
+}
+
+func (c *Config) validate() error {
+	org, err := c.Organization()
+	if err != nil {
+		return err
+	} else if org.MSPID == "" {
+		return fmt.Errorf("organization %s has no mspid", c.Client.Organization)
+	} else if len(org.Peers) == 0 {
+		return fmt.Errorf("organization %s has no peers", c.Client.Organization)
+	}
+	for _, name := range org.Peers {
+		if _, ok := c.Peers[name]; !ok {
+			return fmt.Errorf("unknown peer %s", name)
+		}
+	}
+	if tc := c.Client.TLSCerts.Client; tc.Key.empty() != tc.Cert.empty() {
+		return errors.New("client TLS certificate needs both key and cert")
+	}
+
+	p := c.Perun
+	switch {
+	case p.Channel == "":
+		return errors.New("missing perun channel")
+	case p.Chaincode == "":
+		return errors.New("missing perun chaincode")
+	case (p.Identity.MSPPath == "") == (p.Identity.WalletFile == ""):
+		return errors.New("perun identity needs exactly one of mspPath and walletFile")
+	case p.FunderPollingInterval < 0 || p.AdjudicatorPollingInterval < 0:
+		return errors.New("negative polling interval")
+	}
+	return nil
+}
+
+// Organization returns the organization of the client.
+func (c *Config) Organization() (Organization, error) {
+	org, ok := c.Organizations[c.Client.Organization]
+	if !ok {
+		return Organization{}, fmt.Errorf("unknown client organization %q", c.Client.Organization)
+	}
+	return org, nil
+}
+
+// Endpoint returns the host:port of the peer's URL.
+func (p Peer) Endpoint() (string, error) {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return "", fmt.Errorf("parsing peer url: %w", err)
+	} else if u.Scheme != grpcsScheme {
+		return "", fmt.Errorf("unsupported peer url scheme %q, expected %s", u.Scheme, grpcsScheme)
+	}
+	return u.Host, nil
+}
+
+// ServerName returns the name the peer's TLS certificate is verified
+// against. It is taken from the gRPC options and defaults to the host of the
+// peer's URL.
+func (p Peer) ServerName() (string, error) {
+	for _, opt := range []string{grpcOptSSLTargetNameOverride, grpcOptHostnameOverride} {
+		if name, ok := p.GRPCOptions[opt].(string); ok && name != "" {
+			return name, nil
+		}
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return "", fmt.Errorf("parsing peer url: %w", err)
+	}
+	return u.Hostname(), nil
+}
+
+// certificate returns the certificate of the PEM, reading it from its path
+// relative to dir if it is not inlined.
+func (p PEM) certificate(dir string) (*x509.Certificate, error) {
+	data, err := p.data(dir)
+	if err != nil {
+		return nil, err
+	}
+	return identity.CertificateFromPEM(data)
+}
+
+// data returns the PEM encoded data, reading it from its path relative to dir
+// if it is not inlined.
+func (p PEM) data(dir string) ([]byte, error) {
+	if p.PEM != "" {
+		return []byte(p.PEM), nil
+	}
+	if p.Path == "" {
+		return nil, errors.New("missing PEM")
+	}
+	data, err := os.ReadFile(resolve(dir, p.Path))
+	if err != nil {
+		return nil, fmt.Errorf("reading PEM: %w", err)
+	}
+	return data, nil
+}
+
+// empty returns whether neither PEM data nor a path is set.
+func (p PEM) empty() bool {
+	return p.PEM == "" && p.Path == ""
+}
+
+func (t *Timeouts) setDefaults() {
+	setDefault(&t.Evaluate, DefaultEvaluateTimeout)
+	setDefault(&t.Endorse, DefaultEndorseTimeout)
+	setDefault(&t.Submit, DefaultSubmitTimeout)
+	setDefault(&t.CommitStatus, DefaultCommitStatusTimeout)
+}
+
+func setDefault(d *time.Duration, def time.Duration) {
+	if *d == 0 {
+		*d = def
+	}
+}
+
+// resolve resolves path relative to dir, unless it is absolute.
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
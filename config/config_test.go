@@ -0,0 +1,200 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"polycry.pt/poly-go/test"
+
+	"github.com/perun-network/perun-fabric/config"
+	"github.com/perun-network/perun-fabric/wallet"
+	"github.com/perun-network/perun-fabric/wire"
+)
+
+const profileYAML = `
+name: test-network-org1
+client:
+  organization: Org1
+organizations:
+  Org1:
+    mspid: Org1MSP
+    peers:
+      - peer0.org1.example.com
+peers:
+  peer0.org1.example.com:
+    url: grpcs://localhost:7051
+    tlsCACerts:
+      path: tls/ca.crt
+    grpcOptions:
+      ssl-target-name-override: peer0.org1.example.com
+perun:
+  channel: mychannel
+  chaincode: adjudicator
+  identity:
+    mspPath: msp
+  funderPollingInterval: 500ms
+  adjudicatorPollingInterval: 2s
+  timeouts:
+    endorse: 30s
+`
+
+func TestLoad(t *testing.T) {
+	rng := test.Prng(t)
+	dir := t.TempDir()
+	tlsPEM := writeTestCredentials(t, rng, dir)
+	writeFile(t, filepath.Join(dir, "profile.yaml"), []byte(profileYAML))
+
+	c, err := config.Load(filepath.Join(dir, "profile.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, "mychannel", c.Perun.Channel)
+	require.Equal(t, "adjudicator", c.Perun.Chaincode)
+	require.Equal(t, 500*time.Millisecond, c.Perun.FunderPollingInterval)
+	require.Equal(t, 2*time.Second, c.Perun.AdjudicatorPollingInterval)
+	require.Equal(t, 30*time.Second, c.Perun.Timeouts.Endorse)
+	require.Equal(t, config.DefaultEvaluateTimeout, c.Perun.Timeouts.Evaluate)
+
+	peer := c.Peers["peer0.org1.example.com"]
+	endpoint, err := peer.Endpoint()
+	require.NoError(t, err)
+	require.Equal(t, "localhost:7051", endpoint)
+	serverName, err := peer.ServerName()
+	require.NoError(t, err)
+	require.Equal(t, "peer0.org1.example.com", serverName)
+
+	// The gRPC connection is established lazily, so no peer is needed.
+	s, err := c.Connect()
+	require.NoError(t, err)
+	defer s.Close()
+	require.NotNil(t, s.Funder)
+	require.NotNil(t, s.Adjudicator)
+	require.Equal(t, "mychannel", s.Network.Name())
+	require.Equal(t, "Org1MSP", s.Identity.X509.MspID())
+
+	t.Run("client TLS certificate", func(t *testing.T) {
+		acc := wallet.NewRandomAccount(rng)
+		cert, err := wire.SelfSignedCertificate(rng, acc, "User1@org1.example.com")
+		require.NoError(t, err)
+		key, err := x509.MarshalPKCS8PrivateKey(acc.PrivateKey())
+		require.NoError(t, err)
+		writeFile(t, filepath.Join(dir, "tls", "client.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+		writeFile(t, filepath.Join(dir, "tls", "client.key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}))
+
+		profile := replaceOnce(t, profileYAML, "organization: Org1",
+			"organization: Org1\n  tlsCerts:\n    client:\n      key:\n        path: tls/client.key\n      cert:\n        path: tls/client.crt")
+		c, err := config.Parse([]byte(profile), dir)
+		require.NoError(t, err)
+		s, err := c.Connect()
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+
+		profile = replaceOnce(t, profile, "path: tls/client.key", "path: tls/missing.key")
+		c, err = config.Parse([]byte(profile), dir)
+		require.NoError(t, err)
+		_, err = c.Connect()
+		require.Error(t, err)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		profile := map[string]interface{}{
+			"client":        map[string]string{"organization": "Org1"},
+			"organizations": map[string]interface{}{"Org1": map[string]interface{}{"mspid": "Org1MSP", "peers": []string{"peer0"}}},
+			"peers": map[string]interface{}{"peer0": map[string]interface{}{
+				"url":        "grpcs://peer0.org1.example.com:7051",
+				"tlsCACerts": map[string]string{"pem": string(tlsPEM)},
+			}},
+			"perun": map[string]interface{}{
+				"channel":   "mychannel",
+				"chaincode": "adjudicator",
+				"identity":  map[string]string{"mspPath": filepath.Join(dir, "msp")},
+				"receiver":  "receiver",
+			},
+		}
+		data, err := json.Marshal(profile)
+		require.NoError(t, err)
+		c, err := config.Parse(data, t.TempDir())
+		require.NoError(t, err)
+		serverName, err := c.Peers["peer0"].ServerName()
+		require.NoError(t, err)
+		require.Equal(t, "peer0.org1.example.com", serverName)
+
+		s, err := c.Connect()
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+	})
+}
+
+func TestParseInvalid(t *testing.T) {
+	for name, repl := range map[string][2]string{
+		"unknown organization": {"organization: Org1", "organization: Org2"},
+		"unknown peer":         {"      - peer0.org1.example.com", "      - peer1.org1.example.com"},
+		"missing chaincode":    {"chaincode: adjudicator", "chaincode: \"\""},
+		"two identities":       {"mspPath: msp", "mspPath: msp\n    walletFile: alice.id"},
+		"negative polling":     {"funderPollingInterval: 500ms", "funderPollingInterval: -1s"},
+		"client key only":      {"organization: Org1", "organization: Org1\n  tlsCerts:\n    client:\n      key:\n        path: tls/client.key"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			profile := replaceOnce(t, profileYAML, repl[0], repl[1])
+			_, err := config.Parse([]byte(profile), t.TempDir())
+			require.Error(t, err)
+		})
+	}
+
+	c, err := config.Parse([]byte(replaceOnce(t, profileYAML, "grpcs://", "grpc://")), t.TempDir())
+	require.NoError(t, err)
+	_, err = c.Peers["peer0.org1.example.com"].Endpoint()
+	require.Error(t, err, "insecure peer")
+}
+
+// writeTestCredentials writes an MSP directory and a TLS CA certificate to
+// dir and returns the PEM of the TLS CA certificate.
+func writeTestCredentials(t *testing.T, rng *rand.Rand, dir string) []byte {
+	t.Helper()
+	acc := wallet.NewRandomAccount(rng)
+	cert, err := wire.SelfSignedCertificate(rng, acc, "User1@org1.example.com")
+	require.NoError(t, err)
+	key, err := x509.MarshalPKCS8PrivateKey(acc.PrivateKey())
+	require.NoError(t, err)
+	writeFile(t, filepath.Join(dir, "msp", "signcerts", "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	writeFile(t, filepath.Join(dir, "msp", "keystore", "key_sk"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}))
+
+	tlsCA, err := wire.SelfSignedCertificate(rng, wallet.NewRandomAccount(rng), "tlsca.org1.example.com")
+	require.NoError(t, err)
+	tlsPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsCA.Raw})
+	writeFile(t, filepath.Join(dir, "tls", "ca.crt"), tlsPEM)
+	return tlsPEM
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func replaceOnce(t *testing.T, s, old, new string) string {
+	t.Helper()
+	res := strings.Replace(s, old, new, 1)
+	require.NotEqual(t, s, res, "replacing %q", old)
+	return res
+}
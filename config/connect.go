@@ -0,0 +1,173 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/channel"
+	pclient "github.com/perun-network/perun-fabric/client"
+)
+
+// Setup is the Perun Fabric backend set up from a Config.
+type Setup struct {
+	Identity    *pclient.Identity    // Identity is the client identity.
+	Gateway     *client.Gateway      // Gateway is the connection to the Fabric Gateway.
+	Network     *client.Network      // Network is the Fabric channel of the chaincode.
+	Funder      *channel.Funder      // Funder funds Perun channels.
+	Adjudicator *channel.Adjudicator // Adjudicator resolves disputes of Perun channels.
+
+	conn *pclient.PeerConnection
+}
+
+// Connect loads the client identity, connects to the Gateway of the peers of
+// the client organization and creates the Funder and Adjudicator. The
+// connection fails over to the next peer, in the configured order, if a peer
+// is unreachable. Connect also sets the channel backend domain to the domain
+// of the configured chaincode.
+func (c *Config) Connect() (*Setup, error) {
+	id, err := c.LoadIdentity()
+	if err != nil {
+		return nil, fmt.Errorf("loading identity: %w", err)
+	}
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	t := c.Perun.Timeouts
+	gw, err := client.Connect(
+		id.X509,
+		client.WithSign(id.Sign),
+		client.WithClientConnection(conn.ClientConn),
+		client.WithEvaluateTimeout(t.Evaluate),
+		client.WithEndorseTimeout(t.Endorse),
+		client.WithSubmitTimeout(t.Submit),
+		client.WithCommitStatusTimeout(t.CommitStatus),
+	)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to gateway: %w", err)
+	}
+
+	p := c.Perun
+	receiver := p.Receiver
+	if receiver == "" {
+		receiver = id.AccountID
+	}
+	var (
+		funderOpts []channel.FunderOpt
+		adjOpts    []channel.AdjudicatorOpt
+	)
+	if p.FunderPollingInterval != 0 {
+		funderOpts = append(funderOpts, channel.WithPollingInterval(p.FunderPollingInterval))
+	}
+	if p.AdjudicatorPollingInterval != 0 {
+		adjOpts = append(adjOpts, channel.WithSubPollingInterval(p.AdjudicatorPollingInterval))
+	}
+
+	network := gw.GetNetwork(p.Channel)
+	channel.SetDomain(adj.NewDomain(p.Channel, p.Chaincode))
+	return &Setup{
+		Identity:    id,
+		Gateway:     gw,
+		Network:     network,
+		Funder:      channel.NewFunder(network, p.Chaincode, funderOpts...),
+		Adjudicator: channel.NewAdjudicator(network, p.Chaincode, receiver, adjOpts...),
+		conn:        conn,
+	}, nil
+}
+
+// LoadIdentity loads the configured client identity.
+func (c *Config) LoadIdentity() (*pclient.Identity, error) {
+	id := c.Perun.Identity
+	if id.WalletFile != "" {
+		return pclient.LoadWalletIdentity(resolve(c.dir, id.WalletFile))
+	}
+	org, err := c.Organization()
+	if err != nil {
+		return nil, err
+	}
+	return pclient.LoadMSPIdentity(org.MSPID, resolve(c.dir, id.MSPPath))
+}
+
+// dial creates a gRPC connection to the peers of the client organization.
+// The TLS certificates of all peers are verified against the TLS CA
+// certificates of all peers. If configured, the client TLS certificate is
+// presented to the peers.
+func (c *Config) dial() (*pclient.PeerConnection, error) {
+	org, err := c.Organization()
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	peers := make([]pclient.GatewayPeer, 0, len(org.Peers))
+	for _, name := range org.Peers {
+		peer := c.Peers[name]
+		endpoint, err := peer.Endpoint()
+		if err != nil {
+			return nil, fmt.Errorf("peer %s: %w", name, err)
+		}
+		serverName, err := peer.ServerName()
+		if err != nil {
+			return nil, fmt.Errorf("peer %s: %w", name, err)
+		}
+		cert, err := peer.TLSCACerts.certificate(c.dir)
+		if err != nil {
+			return nil, fmt.Errorf("peer %s TLS CA certificate: %w", name, err)
+		}
+		pool.AddCert(cert)
+		peers = append(peers, pclient.GatewayPeer{Endpoint: endpoint, ServerName: serverName})
+	}
+
+	var opts []pclient.ConnOpt
+	if tc := c.Client.TLSCerts.Client; !tc.Cert.empty() {
+		cert, err := c.clientCertificate()
+		if err != nil {
+			return nil, fmt.Errorf("client TLS certificate: %w", err)
+		}
+		opts = append(opts, pclient.WithClientCertificate(cert))
+	}
+	return pclient.NewPeerConnection(peers, pool, opts...)
+}
+
+// clientCertificate loads the configured client TLS certificate and key.
+func (c *Config) clientCertificate() (tls.Certificate, error) {
+	tc := c.Client.TLSCerts.Client
+	certPEM, err := tc.Cert.data(c.dir)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := tc.Key.data(c.dir)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// Close closes the Gateway and its gRPC connection.
+func (s *Setup) Close() error {
+	err0 := s.Gateway.Close()
+	err1 := s.conn.Close()
+	if err0 != nil {
+		return err0
+	}
+	return err1
+}
@@ -17,4 +17,5 @@ package fabric
 import (
 	_ "github.com/perun-network/perun-fabric/channel" //nolint:nolintlint,revive
 	_ "github.com/perun-network/perun-fabric/wallet"
+	_ "github.com/perun-network/perun-fabric/wire"
 )
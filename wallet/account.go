@@ -15,7 +15,9 @@
 package wallet
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
@@ -30,12 +32,55 @@ import (
 var defaultCurve = elliptic.P256()
 
 // Account identifies a Fabric identity by its X509 certificate's private key.
-// The only supported type is *ecdsa.PrivateKey because that's the only type used
-// in Fabric currently.
-type Account ecdsa.PrivateKey
+// Supported are ECDSA keys, the default in Fabric, and Ed25519 keys.
+type Account struct {
+	ecdsa   *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+// NewECDSAAccount returns the Account of the given ECDSA private key.
+func NewECDSAAccount(sk *ecdsa.PrivateKey) *Account {
+	return &Account{ecdsa: sk}
+}
+
+// NewEd25519Account returns the Account of the given Ed25519 private key.
+func NewEd25519Account(sk ed25519.PrivateKey) *Account {
+	return &Account{ed25519: sk}
+}
+
+// AccountFromPrivateKey returns the Account of the given private key. It must
+// be an *ecdsa.PrivateKey or an ed25519.PrivateKey.
+func AccountFromPrivateKey(sk crypto.PrivateKey) (*Account, error) {
+	switch sk := sk.(type) {
+	case *ecdsa.PrivateKey:
+		return NewECDSAAccount(sk), nil
+	case ed25519.PrivateKey:
+		return NewEd25519Account(sk), nil
+	}
+	return nil, fmt.Errorf("private key of unexpected type %T", sk)
+}
+
+// Type returns the type of the Account's key.
+func (a *Account) Type() KeyType {
+	if a.ed25519 != nil {
+		return KeyTypeEd25519
+	}
+	return KeyTypeECDSA
+}
+
+// ECDSA returns the private key of an ECDSA Account and nil otherwise.
+func (a *Account) ECDSA() *ecdsa.PrivateKey { return a.ecdsa }
+
+// Ed25519 returns the private key of an Ed25519 Account and nil otherwise.
+func (a *Account) Ed25519() ed25519.PrivateKey { return a.ed25519 }
 
-// ECDSA returns the private key.
-func (a *Account) ECDSA() *ecdsa.PrivateKey { return (*ecdsa.PrivateKey)(a) }
+// PrivateKey returns the private key of the Account.
+func (a *Account) PrivateKey() crypto.PrivateKey {
+	if a.Type() == KeyTypeEd25519 {
+		return a.ed25519
+	}
+	return a.ecdsa
+}
 
 // NewRandomAccount creates a new Account using the randomness
 // provided by rng. The default curve (P-256) is used.
@@ -44,7 +89,17 @@ func NewRandomAccount(rng io.Reader) *Account {
 	if err != nil {
 		panic("error generating ECDSA secret key: " + err.Error())
 	}
-	return (*Account)(sk)
+	return NewECDSAAccount(sk)
+}
+
+// NewRandomEd25519Account creates a new Ed25519 Account using the randomness
+// provided by rng.
+func NewRandomEd25519Account(rng io.Reader) *Account {
+	_, sk, err := ed25519.GenerateKey(rng)
+	if err != nil {
+		panic("error generating Ed25519 secret key: " + err.Error())
+	}
+	return NewEd25519Account(sk)
 }
 
 // Address of this Account.
@@ -55,16 +110,24 @@ func (a *Account) Address() wallet.Address {
 // FabricAddress returns the public key of this account as this package's
 // Address type.
 func (a *Account) FabricAddress() *Address {
-	return (*Address)(&a.PublicKey)
+	if a.Type() == KeyTypeEd25519 {
+		return NewEd25519Address(a.ed25519.Public().(ed25519.PublicKey)) //nolint:forcetypeassert
+	}
+	return NewECDSAAddress(&a.ecdsa.PublicKey)
 }
 
-// SignData signs the data with this account. The data is hashed before signing.
+// SignData signs the data with this account. For ECDSA, the data is hashed
+// before signing and the signature is normalized to low-S, as required by
+// Fabric. Ed25519 signs the data itself.
 func (a *Account) SignData(data []byte) ([]byte, error) {
-	r, s, err := ecdsa.Sign(rand.Reader, a.ECDSA(), Hash(data))
+	if a.Type() == KeyTypeEd25519 {
+		return marshalEd25519Sig(ed25519.Sign(a.ed25519, data)), nil
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, a.ecdsa, Hash(data))
 	if err != nil {
 		return nil, fmt.Errorf("ecdsa.Sign: %w", err)
 	}
-	return marshalSig(a.Curve, r, s), nil
+	return marshalSig(a.ecdsa.Curve, r, toLowS(a.ecdsa.Curve, s)), nil
 }
 
 // Hash returns the SHA256 of msg.
@@ -82,6 +145,20 @@ func marshalSig(curve elliptic.Curve, r, s *big.Int) wallet.Sig {
 	return sig
 }
 
+// marshalEd25519Sig brings an Ed25519 signature into the format of ECDSA
+// signatures, its length byte followed by R and S, so that it can be decoded
+// by DecodeSig.
+func marshalEd25519Sig(sig []byte) wallet.Sig {
+	return append([]byte{ed25519.SignatureSize / 2}, sig...) //nolint:gomnd
+}
+
+func unmarshalEd25519Sig(sig []byte) ([]byte, error) {
+	if l := len(sig); l != ed25519.SignatureSize+1 || sig[0] != ed25519.SignatureSize/2 {
+		return nil, fmt.Errorf("UnmarshalSig: invalid Ed25519 signature of length %d", l)
+	}
+	return sig[1:], nil
+}
+
 func unmarshalSig(sig []byte) (*big.Int, *big.Int, error) {
 	if len(sig) == 0 {
 		return nil, nil, errors.New("UnmarshalSig: empty signature")
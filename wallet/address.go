@@ -15,12 +15,16 @@
 package wallet
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -28,21 +32,132 @@ import (
 	"perun.network/go-perun/wallet"
 )
 
+// KeyType is the type of the key of an Address or Account.
+type KeyType uint8
+
+const (
+	// KeyTypeECDSA indicates ECDSA keys on one of the NIST curves.
+	KeyTypeECDSA KeyType = iota + 1
+	// KeyTypeEd25519 indicates Ed25519 keys.
+	KeyTypeEd25519
+)
+
+const (
+	keyTypeNameECDSA   = "ecdsa"
+	keyTypeNameEd25519 = "ed25519"
+
+	// asn1SequenceTag is the first byte of the legacy, untagged ASN.1 encoding
+	// of ECDSA addresses.
+	asn1SequenceTag = 0x30
+	// addrEncodingCompactV1 is the first byte of the compact address encoding.
+	// It is distinct from the key type tags of the previous encoding.
+	addrEncodingCompactV1 = 0x03
+)
+
+// Curve tags of the compact address encoding.
+const (
+	curveTagP224 byte = iota + 1
+	curveTagP256
+	curveTagP384
+	curveTagP521
+	curveTagEd25519
+)
+
+var (
+	curvesByTag = map[byte]elliptic.Curve{
+		curveTagP224: elliptic.P224(),
+		curveTagP256: elliptic.P256(),
+		curveTagP384: elliptic.P384(),
+		curveTagP521: elliptic.P521(),
+	}
+	curveTagsByName = map[string]byte{
+		"P-224": curveTagP224,
+		"P-256": curveTagP256,
+		"P-384": curveTagP384,
+		"P-521": curveTagP521,
+	}
+	// curveTagsByKeyLen maps the lengths of compressed points and Ed25519 keys
+	// to their curve tags. The lengths are unique.
+	curveTagsByKeyLen = map[int]byte{
+		29:                    curveTagP224,
+		33:                    curveTagP256,
+		49:                    curveTagP384,
+		67:                    curveTagP521,
+		ed25519.PublicKeySize: curveTagEd25519,
+	}
+)
+
+func (t KeyType) String() string {
+	switch t {
+	case KeyTypeECDSA:
+		return keyTypeNameECDSA
+	case KeyTypeEd25519:
+		return keyTypeNameEd25519
+	}
+	return fmt.Sprintf("KeyType(%d)", uint8(t))
+}
+
 // Address identifies a Fabric identity by its X509 certificate's public key.
-// The only supported type is *ecdsa.PublicKey because that's the only type used
-// in Fabric currently.
-type Address ecdsa.PublicKey
+// Supported are ECDSA keys, the default in Fabric, and Ed25519 keys.
+type Address struct {
+	ecdsa   *ecdsa.PublicKey
+	ed25519 ed25519.PublicKey
+}
+
+// NewECDSAAddress returns the Address of the given ECDSA public key.
+func NewECDSAAddress(pk *ecdsa.PublicKey) *Address {
+	return &Address{ecdsa: pk}
+}
 
-// ECDSA returns the public key.
-func (a *Address) ECDSA() *ecdsa.PublicKey { return (*ecdsa.PublicKey)(a) }
+// NewEd25519Address returns the Address of the given Ed25519 public key.
+func NewEd25519Address(pk ed25519.PublicKey) *Address {
+	return &Address{ed25519: pk}
+}
+
+// AddressFromPublicKey returns the Address of the given public key. It must be
+// an *ecdsa.PublicKey or an ed25519.PublicKey.
+func AddressFromPublicKey(pk crypto.PublicKey) (*Address, error) {
+	switch pk := pk.(type) {
+	case *ecdsa.PublicKey:
+		return NewECDSAAddress(pk), nil
+	case ed25519.PublicKey:
+		return NewEd25519Address(pk), nil
+	}
+	return nil, fmt.Errorf("public key of unexpected type %T", pk)
+}
+
+// Type returns the type of the Address' key.
+func (a *Address) Type() KeyType {
+	if a.ed25519 != nil {
+		return KeyTypeEd25519
+	}
+	return KeyTypeECDSA
+}
+
+// ECDSA returns the public key of an ECDSA Address and nil otherwise.
+func (a *Address) ECDSA() *ecdsa.PublicKey { return a.ecdsa }
+
+// Ed25519 returns the public key of an Ed25519 Address and nil otherwise.
+func (a *Address) Ed25519() ed25519.PublicKey { return a.ed25519 }
+
+// PublicKey returns the public key of the Address.
+func (a *Address) PublicKey() crypto.PublicKey {
+	if a.Type() == KeyTypeEd25519 {
+		return a.ed25519
+	}
+	return a.ecdsa
+}
 
 // Clone duplicates the Address.
 func (a *Address) Clone() *Address {
-	return &Address{
-		X:     new(big.Int).Set(a.X),
-		Y:     new(big.Int).Set(a.Y),
-		Curve: a.Curve, // curves are global pointers
+	if a.Type() == KeyTypeEd25519 {
+		return NewEd25519Address(append(ed25519.PublicKey(nil), a.ed25519...))
 	}
+	return NewECDSAAddress(&ecdsa.PublicKey{
+		X:     new(big.Int).Set(a.ecdsa.X),
+		Y:     new(big.Int).Set(a.ecdsa.Y),
+		Curve: a.ecdsa.Curve, // curves are global pointers
+	})
 }
 
 type ecdsaPK struct {
@@ -51,51 +166,168 @@ type ecdsaPK struct {
 	Curve string `asn1:"printable"`
 }
 
-func (a Address) marshalable() ecdsaPK {
+func marshalableECDSA(pk *ecdsa.PublicKey) ecdsaPK {
 	return ecdsaPK{
-		X:     a.X,
-		Y:     a.Y,
-		Curve: a.Curve.Params().Name,
+		X:     pk.X,
+		Y:     pk.Y,
+		Curve: pk.Curve.Params().Name,
 	}
 }
 
-func (a *Address) unmarshal(data ecdsaPK) error {
-	a.X = data.X
-	a.Y = data.Y
+func unmarshalECDSA(data ecdsaPK) (*ecdsa.PublicKey, error) {
 	c, err := ecdsaCurveFromName(data.Curve)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	a.Curve = c
-	return nil
+	return &ecdsa.PublicKey{X: data.X, Y: data.Y, Curve: c}, nil
+}
+
+// addressJSON is the JSON encoding of an Address. ECDSA addresses without a
+// type are accepted for backwards compatibility.
+type addressJSON struct {
+	Type  string   `json:"type,omitempty"`
+	X     *big.Int `json:"X,omitempty"`
+	Y     *big.Int `json:"Y,omitempty"`
+	Curve string   `json:"Curve,omitempty"`
+	Key   []byte   `json:"key,omitempty"`
 }
 
 func (a *Address) MarshalJSON() ([]byte, error) { //nolint:revive
-	return json.Marshal(a.marshalable())
+	if a.Type() == KeyTypeEd25519 {
+		return json.Marshal(addressJSON{Type: keyTypeNameEd25519, Key: a.ed25519})
+	}
+	pk := marshalableECDSA(a.ecdsa)
+	return json.Marshal(addressJSON{Type: keyTypeNameECDSA, X: pk.X, Y: pk.Y, Curve: pk.Curve})
 }
 
 func (a *Address) UnmarshalJSON(data []byte) error { //nolint:revive
-	var pk ecdsaPK
-	if err := json.Unmarshal(data, &pk); err != nil {
+	var aj addressJSON
+	if err := json.Unmarshal(data, &aj); err != nil {
 		return err
 	}
-	return a.unmarshal(pk)
+	switch aj.Type {
+	case keyTypeNameECDSA, "":
+		pk, err := unmarshalECDSA(ecdsaPK{X: aj.X, Y: aj.Y, Curve: aj.Curve})
+		if err != nil {
+			return err
+		} else if pk.X == nil || pk.Y == nil {
+			return errors.New("missing ECDSA coordinates")
+		}
+		*a = Address{ecdsa: pk}
+	case keyTypeNameEd25519:
+		if l := len(aj.Key); l != ed25519.PublicKeySize {
+			return fmt.Errorf("Ed25519 public key has wrong length %d", l)
+		}
+		*a = Address{ed25519: aj.Key}
+	default:
+		return fmt.Errorf("unknown key type: %s", aj.Type)
+	}
+	return nil
 }
 
-// MarshalBinary allows to marshal Address in ASN1.
+// MarshalBinary marshals the Address in the compact encoding: the encoding
+// version, the curve tag and the compressed point, or the raw key for Ed25519.
 func (a *Address) MarshalBinary() ([]byte, error) {
-	return asn1.Marshal(a.marshalable())
+	tag, err := a.curveTag()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{addrEncodingCompactV1, tag}, a.key()...), nil
 }
 
-// UnmarshalBinary allows to unmarshal Address from ASN1.
+// UnmarshalBinary unmarshals an Address encoded by MarshalBinary. The older
+// encodings, plain ASN1 and key type tagged ASN1 or raw key, are accepted as
+// well.
 func (a *Address) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty address")
+	}
+	switch data[0] {
+	case addrEncodingCompactV1:
+		if len(data) < 2 { //nolint:gomnd
+			return errors.New("missing curve tag")
+		}
+		return a.unmarshalKey(data[1], data[2:])
+	case asn1SequenceTag:
+		return a.unmarshalASN1(data)
+	case byte(KeyTypeECDSA):
+		return a.unmarshalASN1(data[1:])
+	case byte(KeyTypeEd25519):
+		return a.unmarshalKey(curveTagEd25519, data[1:])
+	}
+	return fmt.Errorf("unknown address encoding: %d", data[0])
+}
+
+// ParseAddress parses an Address from its string representation, the hex
+// encoded compressed point for ECDSA or the hex encoded key for Ed25519.
+func ParseAddress(s string) (*Address, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding hex: %w", err)
+	}
+	tag, ok := curveTagsByKeyLen[len(data)]
+	if !ok {
+		return nil, fmt.Errorf("invalid address length %d", len(data))
+	}
+	a := new(Address)
+	return a, a.unmarshalKey(tag, data)
+}
+
+// key returns the compressed point of an ECDSA Address or the key of an
+// Ed25519 Address.
+func (a *Address) key() []byte {
+	if a.Type() == KeyTypeEd25519 {
+		return a.ed25519
+	}
+	return elliptic.MarshalCompressed(a.ecdsa.Curve, a.ecdsa.X, a.ecdsa.Y)
+}
+
+func (a *Address) curveTag() (byte, error) {
+	if a.Type() == KeyTypeEd25519 {
+		return curveTagEd25519, nil
+	}
+	tag, ok := curveTagsByName[a.ecdsa.Curve.Params().Name]
+	if !ok {
+		return 0, fmt.Errorf("unsupported curve: %s", a.ecdsa.Curve.Params().Name)
+	}
+	return tag, nil
+}
+
+// unmarshalKey unmarshals the key of the curve with the given tag.
+func (a *Address) unmarshalKey(tag byte, key []byte) error {
+	if tag == curveTagEd25519 {
+		if l := len(key); l != ed25519.PublicKeySize {
+			return fmt.Errorf("Ed25519 public key has wrong length %d", l)
+		}
+		*a = Address{ed25519: append(ed25519.PublicKey(nil), key...)}
+		return nil
+	}
+
+	curve, ok := curvesByTag[tag]
+	if !ok {
+		return fmt.Errorf("unknown curve tag: %d", tag)
+	}
+	x, y := elliptic.UnmarshalCompressed(curve, key)
+	if x == nil {
+		return fmt.Errorf("invalid compressed point on %s", curve.Params().Name)
+	}
+	*a = Address{ecdsa: &ecdsa.PublicKey{Curve: curve, X: x, Y: y}}
+	return nil
+}
+
+func (a *Address) unmarshalASN1(data []byte) error {
 	var pk ecdsaPK
 	if rest, err := asn1.Unmarshal(data, &pk); err != nil {
 		return err
 	} else if l := len(rest); l > 0 {
 		return fmt.Errorf("unexptected rest of length %d during asn1-unmarshaling", l)
 	}
-	return a.unmarshal(pk)
+	epk, err := unmarshalECDSA(pk)
+	if err != nil {
+		return err
+	}
+	*a = Address{ecdsa: epk}
+	return nil
 }
 
 func ecdsaCurveFromName(curve string) (elliptic.Curve, error) {
@@ -112,35 +344,55 @@ func ecdsaCurveFromName(curve string) (elliptic.Curve, error) {
 	return nil, fmt.Errorf("unknown curve: %s", curve)
 }
 
+// String returns the hex encoded compressed point of an ECDSA Address or the
+// hex encoded key of an Ed25519 Address. It is parsed by ParseAddress.
 func (a *Address) String() string {
-	return hex.EncodeToString(elliptic.MarshalCompressed(a.Curve, a.X, a.Y))
+	return hex.EncodeToString(a.key())
 }
 
 // Equal returns wether the two addresses are equal. The implementation
 // must be equivalent to checking `Address.Cmp(Address) == 0`.
 func (a *Address) Equal(other wallet.Address) bool {
-	return a.ECDSA().Equal(asECDSA(other))
+	b := asAddress(other)
+	if a.Type() != b.Type() {
+		return false
+	} else if a.Type() == KeyTypeEd25519 {
+		return a.ed25519.Equal(b.ed25519)
+	}
+	return a.ecdsa.Equal(b.ecdsa)
 }
 
 // Cmp checks the ordering of two Addresses according to following definition:
+// Addresses of different key types are ordered by their KeyType.
+// Ed25519 addresses are ordered by their byte representation.
+// ECDSA addresses are ordered as follows:
 // -1 if (a.X <  b.X) || ((a.X == b.X) && (a.Y < b.Y)).
 // 0 if (a.X == b.X) && (a.Y == b.Y).
 // +1 if (a.X >  b.X) || ((a.X == b.X) && (a.Y > b.Y)).
 // So the X coordinate takes precedence over the Y coordinate.
 // Pancis if the passed address is of the wrong type or the curves are not the same.
 func (a *Address) Cmp(b wallet.Address) int {
-	other := asECDSA(b)
-	if a.ECDSA().Curve != other.Curve {
+	other := asAddress(b)
+	if ta, tb := a.Type(), other.Type(); ta != tb {
+		if ta < tb {
+			return -1
+		}
+		return 1
+	} else if ta == KeyTypeEd25519 {
+		return bytes.Compare(a.ed25519, other.ed25519)
+	}
+
+	if a.ecdsa.Curve != other.ecdsa.Curve {
 		panic("different ECDSA curves")
 	}
-	if xCmp := a.X.Cmp(other.X); xCmp != 0 {
+	if xCmp := a.ecdsa.X.Cmp(other.ecdsa.X); xCmp != 0 {
 		return xCmp
 	}
-	return a.Y.Cmp(other.Y)
+	return a.ecdsa.Y.Cmp(other.ecdsa.Y)
 }
 
-func asECDSA(a wallet.Address) *ecdsa.PublicKey {
-	return ((a).(*Address)).ECDSA() //nolint:forcetypeassert
+func asAddress(a wallet.Address) *Address {
+	return (a).(*Address) //nolint:forcetypeassert
 }
 
 // NewRandomAddress creates a new Address using the randomness
@@ -151,9 +403,9 @@ func NewRandomAddress(rng io.Reader) *Address {
 
 // AddressFromX509Certificate extracts the public key from the given certificate.
 func AddressFromX509Certificate(cert *x509.Certificate) (*Address, error) {
-	pk, ok := cert.PublicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("certificate public key of unexpected type %T", cert.PublicKey)
+	addr, err := AddressFromPublicKey(cert.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("certificate: %w", err)
 	}
-	return (*Address)(pk), nil
+	return addr, nil
 }
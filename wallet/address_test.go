@@ -15,8 +15,13 @@
 package wallet_test
 
 import (
+	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -60,42 +65,42 @@ func TestAddressCmp(t *testing.T) {
 		},
 		{
 			name:   "X-1",
-			mod:    func(a *wallet.Address) { decOne(a.X) },
+			mod:    func(a *wallet.Address) { decOne(a.ECDSA().X) },
 			expCmp: -1,
 		},
 		{
 			name:   "X+1",
-			mod:    func(a *wallet.Address) { incOne(a.X) },
+			mod:    func(a *wallet.Address) { incOne(a.ECDSA().X) },
 			expCmp: 1,
 		},
 		{
 			name:   "Y-1",
-			mod:    func(a *wallet.Address) { decOne(a.Y) },
+			mod:    func(a *wallet.Address) { decOne(a.ECDSA().Y) },
 			expCmp: -1,
 		},
 		{
 			name:   "Y+1",
-			mod:    func(a *wallet.Address) { incOne(a.Y) },
+			mod:    func(a *wallet.Address) { incOne(a.ECDSA().Y) },
 			expCmp: 1,
 		},
 		{
 			name:   "X-1,Y-1",
-			mod:    func(a *wallet.Address) { decOne(a.X); decOne(a.Y) },
+			mod:    func(a *wallet.Address) { decOne(a.ECDSA().X); decOne(a.ECDSA().Y) },
 			expCmp: -1,
 		},
 		{
 			name:   "X+1,Y+1",
-			mod:    func(a *wallet.Address) { incOne(a.X); incOne(a.Y) },
+			mod:    func(a *wallet.Address) { incOne(a.ECDSA().X); incOne(a.ECDSA().Y) },
 			expCmp: 1,
 		},
 		{
 			name:   "X-1,Y+1",
-			mod:    func(a *wallet.Address) { decOne(a.X); incOne(a.Y) },
+			mod:    func(a *wallet.Address) { decOne(a.ECDSA().X); incOne(a.ECDSA().Y) },
 			expCmp: -1,
 		},
 		{
 			name:   "X+1,Y-1",
-			mod:    func(a *wallet.Address) { incOne(a.X); decOne(a.Y) },
+			mod:    func(a *wallet.Address) { incOne(a.ECDSA().X); decOne(a.ECDSA().Y) },
 			expCmp: 1,
 		},
 	}
@@ -113,9 +118,152 @@ func TestAddressCmp(t *testing.T) {
 	t.Run("panicOnDifferentCurve", func(t *testing.T) {
 		a := wallet.NewRandomAddress(rng)
 		b := a.Clone()
-		b.Curve = elliptic.P224() // not default P-256
+		b.ECDSA().Curve = elliptic.P224() // not default P-256
 		require.Panics(t, func() {
 			a.Cmp(b)
 		})
 	})
 }
+
+func TestEd25519AddressMarshaling(t *testing.T) {
+	rng := test.Prng(t)
+	a := wallet.NewRandomEd25519Account(rng).FabricAddress()
+	require.Equal(t, wallet.KeyTypeEd25519, a.Type())
+	wiretest.GenericMarshalerTest(t, a)
+
+	aj, err := a.MarshalJSON()
+	require.NoError(t, err)
+	a0 := new(wallet.Address)
+	require.NoError(t, a0.UnmarshalJSON(aj))
+	require.True(t, a.Equal(a0))
+
+	require.Error(t, a0.UnmarshalJSON([]byte(`{"type":"ed25519","key":"AAAA"}`)), "short key")
+	require.Error(t, a0.UnmarshalJSON([]byte(`{"type":"rsa"}`)), "unknown type")
+	require.Error(t, a0.UnmarshalBinary([]byte{byte(wallet.KeyTypeEd25519), 1, 2, 3}), "short key")
+	require.Error(t, a0.UnmarshalBinary([]byte{0xff}), "unknown type")
+}
+
+func TestAddressLegacyDecoding(t *testing.T) {
+	rng := test.Prng(t)
+	a := wallet.NewRandomAddress(rng)
+	pk := a.ECDSA()
+
+	// Untagged ASN1 encoding.
+	data, err := asn1.Marshal(struct {
+		X     *big.Int
+		Y     *big.Int
+		Curve string `asn1:"printable"`
+	}{pk.X, pk.Y, pk.Curve.Params().Name})
+	require.NoError(t, err)
+	a0 := new(wallet.Address)
+	require.NoError(t, a0.UnmarshalBinary(data))
+	require.True(t, a.Equal(a0))
+
+	// Untyped JSON encoding.
+	aj, err := json.Marshal(map[string]interface{}{"X": pk.X, "Y": pk.Y, "Curve": pk.Curve.Params().Name})
+	require.NoError(t, err)
+	a0 = new(wallet.Address)
+	require.NoError(t, a0.UnmarshalJSON(aj))
+	require.True(t, a.Equal(a0))
+}
+
+func TestAddressCmpKeyTypes(t *testing.T) {
+	rng := test.Prng(t)
+	ec := wallet.NewRandomAddress(rng)
+	ed := wallet.NewRandomEd25519Account(rng).FabricAddress()
+
+	require.Equal(t, -1, ec.Cmp(ed))
+	require.Equal(t, 1, ed.Cmp(ec))
+	require.False(t, ec.Equal(ed))
+	require.Equal(t, 0, ed.Cmp(ed.Clone()))
+	require.True(t, ed.Equal(ed.Clone()))
+}
+
+func TestAddressFromX509Certificate(t *testing.T) {
+	rng := test.Prng(t)
+	acc := wallet.NewRandomEd25519Account(rng)
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1)}
+	der, err := x509.CreateCertificate(rng, tmpl, tmpl, acc.Ed25519().Public(), acc.Ed25519())
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	addr, err := wallet.AddressFromX509Certificate(cert)
+	require.NoError(t, err)
+	require.True(t, addr.Equal(acc.Address()))
+}
+
+func TestAddressCompactEncoding(t *testing.T) {
+	rng := test.Prng(t)
+	addrs := []*wallet.Address{wallet.NewRandomEd25519Account(rng).FabricAddress()}
+	for _, curve := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		sk, err := ecdsa.GenerateKey(curve, rng)
+		require.NoError(t, err)
+		addrs = append(addrs, wallet.NewECDSAAddress(&sk.PublicKey))
+	}
+
+	for _, a := range addrs {
+		name := a.Type().String()
+		if a.Type() == wallet.KeyTypeECDSA {
+			name = a.ECDSA().Curve.Params().Name
+		}
+		t.Run(name, func(t *testing.T) {
+			wiretest.GenericMarshalerTest(t, a)
+			data, err := a.MarshalBinary()
+			require.NoError(t, err)
+			// Version, curve tag and the compressed point or raw key.
+			require.Len(t, data, 2+len(a.String())/2)
+
+			a0, err := wallet.ParseAddress(a.String())
+			require.NoError(t, err)
+			require.True(t, a.Equal(a0))
+		})
+	}
+
+	t.Run("P-256 size", func(t *testing.T) {
+		data, err := wallet.NewRandomAddress(rng).MarshalBinary()
+		require.NoError(t, err)
+		require.Len(t, data, 35)
+	})
+
+	t.Run("KeyType tagged", func(t *testing.T) {
+		a := wallet.NewRandomAddress(rng)
+		pk := a.ECDSA()
+		data, err := asn1.Marshal(struct {
+			X     *big.Int
+			Y     *big.Int
+			Curve string `asn1:"printable"`
+		}{pk.X, pk.Y, pk.Curve.Params().Name})
+		require.NoError(t, err)
+		a0 := new(wallet.Address)
+		require.NoError(t, a0.UnmarshalBinary(append([]byte{byte(wallet.KeyTypeECDSA)}, data...)))
+		require.True(t, a.Equal(a0))
+
+		ed := wallet.NewRandomEd25519Account(rng).FabricAddress()
+		require.NoError(t, a0.UnmarshalBinary(append([]byte{byte(wallet.KeyTypeEd25519)}, ed.Ed25519()...)))
+		require.True(t, ed.Equal(a0))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		a0 := new(wallet.Address)
+		require.Error(t, a0.UnmarshalBinary([]byte{3}), "missing curve tag")
+		require.Error(t, a0.UnmarshalBinary([]byte{3, 9, 1}), "unknown curve tag")
+		bad := make([]byte, 35)
+		bad[0], bad[1], bad[2] = 3, 2, 2
+		for i := range bad[3:] {
+			bad[3+i] = 0xff
+		}
+		require.Error(t, a0.UnmarshalBinary(bad), "invalid point")
+	})
+}
+
+func TestParseAddressInvalid(t *testing.T) {
+	for _, s := range []string{
+		"xyz",                           // no hex
+		"0203",                          // wrong length
+		"04" + strings.Repeat("ff", 32), // invalid compressed point prefix
+	} {
+		_, err := wallet.ParseAddress(s)
+		require.Error(t, err, s)
+	}
+}
@@ -16,6 +16,7 @@ package wallet
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"fmt"
 	"io"
 
@@ -39,10 +40,21 @@ func (b Backend) DecodeSig(r io.Reader) (wallet.Sig, error) {
 // VerifySignature verifies that signature sig is a valid signature by a on
 // message msg.
 // If the signature does not match the address, it returns false, nil.
+// Malformed ECDSA signatures, including high-S signatures and signatures on
+// another curve than the address', result in an error.
 func (b Backend) VerifySignature(msg []byte, sig wallet.Sig, a wallet.Address) (bool, error) {
-	r, s, err := unmarshalSig(sig)
+	addr := asAddress(a)
+	if addr.Type() == KeyTypeEd25519 {
+		esig, err := unmarshalEd25519Sig(sig)
+		if err != nil {
+			return false, fmt.Errorf("unmarshaling sig: %w", err)
+		}
+		return ed25519.Verify(addr.Ed25519(), msg, esig), nil
+	}
+
+	r, s, err := unmarshalECDSASig(addr.ECDSA().Curve, sig)
 	if err != nil {
 		return false, fmt.Errorf("unmarshaling sig: %w", err)
 	}
-	return ecdsa.Verify(asECDSA(a), Hash(msg), r, s), nil
+	return ecdsa.Verify(addr.ECDSA(), Hash(msg), r, s), nil
 }
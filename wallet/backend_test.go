@@ -15,6 +15,8 @@
 package wallet_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"math/big"
 	"testing"
 
@@ -37,12 +39,32 @@ func TestAccountWalletBackend(t *testing.T) {
 		Backend:         wallet.Backend{},
 		Wallet:          wallet.NewWallet(acc),
 		AddressInWallet: addr,
-		ZeroAddress: &wallet.Address{
-			Curve: addr.Curve,
+		ZeroAddress: wallet.NewECDSAAddress(&ecdsa.PublicKey{
+			Curve: addr.ECDSA().Curve,
 			X:     new(big.Int),
 			Y:     new(big.Int),
-		},
-		DataToSign:        addr.X.Bytes(),
+		}),
+		DataToSign:        addr.ECDSA().X.Bytes(),
+		AddressMarshalled: addrUnkBytes,
+	}
+
+	wtest.TestAccountWithWalletAndBackend(t, setup)
+}
+
+func TestEd25519AccountWalletBackend(t *testing.T) {
+	rng := test.Prng(t)
+	acc := wallet.NewRandomEd25519Account(rng)
+	addr := acc.FabricAddress()
+	addrUnk := wallet.NewRandomEd25519Account(rng).FabricAddress()
+	addrUnkBytes, err := addrUnk.MarshalBinary()
+	require.NoError(t, err)
+
+	setup := &wtest.Setup{
+		Backend:           wallet.Backend{},
+		Wallet:            wallet.NewWallet(acc),
+		AddressInWallet:   addr,
+		ZeroAddress:       wallet.NewEd25519Address(make(ed25519.PublicKey, ed25519.PublicKeySize)),
+		DataToSign:        addr.Ed25519(),
 		AddressMarshalled: addrUnkBytes,
 	}
 
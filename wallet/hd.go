@@ -0,0 +1,354 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed" // for the BIP-39 word list
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// HardenedKeyStart is the first index of hardened child keys.
+const HardenedKeyStart uint32 = 1 << 31
+
+const (
+	minSeedLen = 16
+	maxSeedLen = 64
+
+	mnemonicSaltPrefix = "mnemonic"
+	mnemonicIterations = 2048
+	mnemonicSeedLen    = 64
+
+	mnemonicBitsPerWord = 11
+	mnemonicMinWords    = 12
+	mnemonicMaxWords    = 24
+	mnemonicWordsStep   = 3
+
+	slip10SeedP224    = "Nist224p1 seed"
+	slip10SeedP256    = "Nist256p1 seed"
+	slip10SeedP384    = "Nist384p1 seed"
+	slip10SeedP521    = "Nist521p1 seed"
+	slip10SeedEd25519 = "ed25519 seed"
+
+	chainCodeLen = 32
+)
+
+var (
+	// bip39English is the English BIP-39 word list, see
+	// https://github.com/bitcoin/bips/blob/master/bip-0039/english.txt
+	//go:embed bip39_english.txt
+	bip39English string
+
+	// bip39Indices maps the words of the English BIP-39 word list to their
+	// indices.
+	bip39Indices = func() map[string]int {
+		words := strings.Fields(bip39English)
+		indices := make(map[string]int, len(words))
+		for i, w := range words {
+			indices[w] = i
+		}
+		return indices
+	}()
+
+	// slip10CurveSeeds maps the NIST curves to the keys of the HMAC that
+	// derives the master node. Only the key of P-256 is specified by SLIP-0010,
+	// the others follow its naming.
+	slip10CurveSeeds = map[elliptic.Curve]string{
+		elliptic.P224(): slip10SeedP224,
+		elliptic.P256(): slip10SeedP256,
+		elliptic.P384(): slip10SeedP384,
+		elliptic.P521(): slip10SeedP521,
+	}
+)
+
+// Deriver derives Accounts deterministically from a seed, following SLIP-0010.
+// The Account with index i is derived at the hardened child i' of the base
+// path of the Deriver. Derivation is supported for Ed25519 keys and for ECDSA
+// keys on all NIST curves, P-256 being the default curve.
+//
+// SLIP-0010 only specifies the derivation for P-256. The other NIST curves are
+// derived in the same way, with the following generalizations:
+//   - The master node is derived with the HMAC key "Nist224p1 seed",
+//     "Nist384p1 seed" or "Nist521p1 seed", respectively.
+//   - The HMAC-SHA512 output I is extended to the byte length l of the curve
+//     order plus 32 bytes by appending HMAC-SHA512(key, T || data), where T is
+//     the previous 64-byte block, until it is long enough. IL are the first l
+//     bytes of I and the chain code IR are the following 32 bytes.
+//   - IL is interpreted as the leftmost bits of its big-endian integer, as
+//     many as the curve order has, like bits2int of RFC 6979.
+//   - Invalid keys are retried as specified by SLIP-0010 for P-256.
+//
+// For P-256, this is exactly the derivation of SLIP-0010.
+type Deriver struct {
+	keyType KeyType
+	curve   elliptic.Curve // curve is the ECDSA curve, nil for Ed25519.
+	keyLen  int            // keyLen is the byte length of the keys of the nodes.
+	base    hdNode
+}
+
+// hdNode is a node of the derivation tree.
+type hdNode struct {
+	key       []byte // key is the private key, the scalar for ECDSA and the seed for Ed25519.
+	chainCode []byte // chainCode is the chain code of the node.
+}
+
+// SeedFromMnemonic returns the BIP-39 seed of the given mnemonic sentence and
+// passphrase. The mnemonic must consist of words of the English BIP-39 word
+// list and carry a valid checksum.
+func SeedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	if err := checkMnemonic(words); err != nil {
+		return nil, err
+	}
+	m := strings.Join(words, " ")
+	salt := norm.NFKD.String(mnemonicSaltPrefix + passphrase)
+	return pbkdf2.Key([]byte(m), []byte(salt), mnemonicIterations, mnemonicSeedLen, sha512.New), nil
+}
+
+// checkMnemonic checks the length, the words and the checksum of the given
+// mnemonic words.
+func checkMnemonic(words []string) error {
+	n := len(words)
+	if n < mnemonicMinWords || n > mnemonicMaxWords || n%mnemonicWordsStep != 0 {
+		return fmt.Errorf("mnemonic has invalid number of words %d", n)
+	}
+
+	// The words encode the entropy followed by a checksum of one bit per 32
+	// bits of entropy.
+	bits := new(big.Int)
+	for _, w := range words {
+		i, ok := bip39Indices[w]
+		if !ok {
+			return fmt.Errorf("mnemonic word %q not in BIP-39 word list", w)
+		}
+		bits.Lsh(bits, mnemonicBitsPerWord)
+		bits.Or(bits, big.NewInt(int64(i)))
+	}
+	csLen := uint(n * mnemonicBitsPerWord / 33) //nolint:gomnd
+	entropyLen := int(csLen) * 4                //nolint:gomnd
+	cs := new(big.Int).And(bits, big.NewInt(1<<csLen-1))
+	entropy := new(big.Int).Rsh(bits, csLen).FillBytes(make([]byte, entropyLen))
+
+	hash := sha256.Sum256(entropy)
+	if want := uint64(hash[0] >> (8 - csLen)); cs.Uint64() != want { //nolint:gomnd
+		return errors.New("mnemonic has invalid checksum")
+	}
+	return nil
+}
+
+// ParseDerivationPath parses a derivation path of the form m/44'/0'/1, where
+// hardened indices are marked by ' or h.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("derivation path must start with m: %s", path)
+	}
+	indices := make([]uint32, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		hardened := strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h")
+		if hardened {
+			p = p[:len(p)-1]
+		}
+		i, err := strconv.ParseUint(p, 10, 31) //nolint:gomnd
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path index %q: %w", p, err)
+		}
+		if hardened {
+			i += uint64(HardenedKeyStart)
+		}
+		indices = append(indices, uint32(i))
+	}
+	return indices, nil
+}
+
+// NewDeriver returns a Deriver for Accounts of the given key type, derived from
+// the given seed below the given base path. ECDSA Accounts are derived on the
+// default curve.
+func NewDeriver(seed []byte, keyType KeyType, basePath ...uint32) (*Deriver, error) {
+	switch keyType {
+	case KeyTypeECDSA:
+		return NewECDSADeriver(seed, defaultCurve, basePath...)
+	case KeyTypeEd25519:
+		return newDeriver(seed, keyType, nil, basePath)
+	}
+	return nil, fmt.Errorf("derivation not supported for key type %v", keyType)
+}
+
+// NewECDSADeriver returns a Deriver for ECDSA Accounts on the given NIST curve,
+// derived from the given seed below the given base path. See Deriver for the
+// derivation on curves other than P-256.
+func NewECDSADeriver(seed []byte, curve elliptic.Curve, basePath ...uint32) (*Deriver, error) {
+	return newDeriver(seed, KeyTypeECDSA, curve, basePath)
+}
+
+func newDeriver(seed []byte, keyType KeyType, curve elliptic.Curve, basePath []uint32) (*Deriver, error) {
+	if l := len(seed); l < minSeedLen || l > maxSeedLen {
+		return nil, fmt.Errorf("seed has invalid length %d", l)
+	}
+	d := &Deriver{keyType: keyType, curve: curve, keyLen: ed25519.SeedSize}
+	if curve != nil {
+		d.keyLen = (curve.Params().N.BitLen() + 7) / 8 //nolint:gomnd
+	}
+	node, err := d.masterNode(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range basePath {
+		if node, err = d.child(node, i); err != nil {
+			return nil, err
+		}
+	}
+	d.base = node
+	return d, nil
+}
+
+// DeriveAccount derives the Account with the given index.
+func (d *Deriver) DeriveAccount(index uint32) (*Account, error) {
+	if index >= HardenedKeyStart {
+		return nil, fmt.Errorf("account index %d out of range", index)
+	}
+	node, err := d.child(d.base, index+HardenedKeyStart)
+	if err != nil {
+		return nil, err
+	}
+	return d.account(node), nil
+}
+
+func (d *Deriver) masterNode(seed []byte) (hdNode, error) {
+	curveSeed, err := d.slip10CurveSeed()
+	if err != nil {
+		return hdNode{}, err
+	}
+	i := d.hmac([]byte(curveSeed), seed)
+	if d.keyType == KeyTypeEd25519 {
+		il, ir := d.split(i)
+		return hdNode{key: il, chainCode: ir}, nil
+	}
+	// Retry on invalid ECDSA keys by hashing the previous output.
+	for {
+		il, ir := d.split(i)
+		if k := d.scalar(il); k.Sign() != 0 && k.Cmp(d.curve.Params().N) < 0 {
+			return hdNode{key: k.FillBytes(make([]byte, d.keyLen)), chainCode: ir}, nil
+		}
+		i = d.hmac([]byte(curveSeed), i)
+	}
+}
+
+// child derives the child node of n with the given index.
+func (d *Deriver) child(n hdNode, index uint32) (hdNode, error) {
+	var data []byte
+	if index >= HardenedKeyStart {
+		data = append([]byte{0}, n.key...)
+	} else if d.keyType == KeyTypeEd25519 {
+		return hdNode{}, errors.New("Ed25519 supports only hardened derivation")
+	} else {
+		acc := d.account(n).ECDSA()
+		data = elliptic.MarshalCompressed(acc.Curve, acc.X, acc.Y)
+	}
+	data = appendUint32(data, index)
+
+	for {
+		il, ir := d.split(d.hmac(n.chainCode, data))
+		if d.keyType == KeyTypeEd25519 {
+			return hdNode{key: il, chainCode: ir}, nil
+		}
+
+		curveN := d.curve.Params().N
+		if k := d.scalar(il); k.Cmp(curveN) < 0 {
+			k.Add(k, new(big.Int).SetBytes(n.key))
+			k.Mod(k, curveN)
+			if k.Sign() != 0 {
+				return hdNode{key: k.FillBytes(make([]byte, d.keyLen)), chainCode: ir}, nil
+			}
+		}
+		// Invalid key, retry as specified by SLIP-0010.
+		data = appendUint32(append([]byte{1}, ir...), index)
+	}
+}
+
+func (d *Deriver) account(n hdNode) *Account {
+	if d.keyType == KeyTypeEd25519 {
+		return NewEd25519Account(ed25519.NewKeyFromSeed(n.key))
+	}
+	sk := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: d.curve},
+		D:         new(big.Int).SetBytes(n.key),
+	}
+	sk.X, sk.Y = d.curve.ScalarBaseMult(n.key)
+	return NewECDSAAccount(sk)
+}
+
+// slip10CurveSeed returns the SLIP-0010 curve seed of the key type and curve
+// of the Deriver.
+func (d *Deriver) slip10CurveSeed() (string, error) {
+	if d.keyType == KeyTypeEd25519 {
+		return slip10SeedEd25519, nil
+	}
+	if d.curve == nil {
+		return "", errors.New("no curve given")
+	}
+	seed, ok := slip10CurveSeeds[d.curve] // curves are global pointers
+	if !ok {
+		return "", fmt.Errorf("derivation not supported on curve %s", d.curve.Params().Name)
+	}
+	return seed, nil
+}
+
+// hmac returns the HMAC-SHA512 output of the given key and data, extended to
+// the key length plus the chain code length of the Deriver, see Deriver.
+func (d *Deriver) hmac(key, data []byte) []byte {
+	n := d.keyLen + chainCodeLen
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data) //nolint:errcheck
+	i := mac.Sum(nil)
+	for t := i; len(i) < n; i = append(i, t...) {
+		mac.Reset()
+		mac.Write(t)    //nolint:errcheck
+		mac.Write(data) //nolint:errcheck
+		t = mac.Sum(nil)
+	}
+	return i[:n]
+}
+
+// split splits the given HMAC output into the key IL and the chain code IR.
+func (d *Deriver) split(i []byte) ([]byte, []byte) {
+	return i[:d.keyLen], i[d.keyLen:]
+}
+
+// scalar returns the integer of the leftmost bits of il, as many as the order
+// of the curve of the Deriver has.
+func (d *Deriver) scalar(il []byte) *big.Int {
+	k := new(big.Int).SetBytes(il)
+	return k.Rsh(k, uint(len(il)*8-d.curve.Params().N.BitLen())) //nolint:gomnd
+}
+
+func appendUint32(b []byte, i uint32) []byte {
+	var ser [4]byte
+	binary.BigEndian.PutUint32(ser[:], i)
+	return append(b, ser[:]...)
+}
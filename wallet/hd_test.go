@@ -0,0 +1,176 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet_test
+
+import (
+	"crypto/elliptic"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/perun-network/perun-fabric/wallet"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// TestDeriverSLIP10 checks the derivation against test vector 1 of SLIP-0010.
+func TestDeriverSLIP10(t *testing.T) {
+	seed := mustDecodeHex(t, "000102030405060708090a0b0c0d0e0f")
+
+	t.Run("P-256", func(t *testing.T) {
+		d, err := wallet.NewDeriver(seed, wallet.KeyTypeECDSA)
+		require.NoError(t, err)
+		acc, err := d.DeriveAccount(0) // m/0'
+		require.NoError(t, err)
+		sk := acc.ECDSA()
+		require.Equal(t, "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c",
+			hex.EncodeToString(sk.D.Bytes()))
+		require.Equal(t, "0384610f5ecffe8fda089363a41f56a5c7ffc1d81b59a612d0d649b2d22355590c",
+			hex.EncodeToString(elliptic.MarshalCompressed(sk.Curve, sk.X, sk.Y)))
+	})
+
+	t.Run("Ed25519", func(t *testing.T) {
+		d, err := wallet.NewDeriver(seed, wallet.KeyTypeEd25519)
+		require.NoError(t, err)
+		acc, err := d.DeriveAccount(0) // m/0'
+		require.NoError(t, err)
+		require.Equal(t, "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3",
+			hex.EncodeToString(acc.Ed25519().Seed()))
+		require.Equal(t, "8c8a13df77a28f3445213a0f432fde644acaa215fc72dcdf300d5efaa85d350c",
+			hex.EncodeToString(acc.FabricAddress().Ed25519()))
+	})
+}
+
+func TestDeriver(t *testing.T) {
+	seed, err := wallet.SeedFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "TREZOR")
+	require.NoError(t, err)
+	// BIP-39 test vector.
+	require.Equal(t, "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		hex.EncodeToString(seed))
+
+	path, err := wallet.ParseDerivationPath("m/44'/1h/0")
+	require.NoError(t, err)
+	require.Equal(t, []uint32{wallet.HardenedKeyStart + 44, wallet.HardenedKeyStart + 1, 0}, path)
+	for _, p := range []string{"44'/0'", "m/x'", "m/2147483648"} {
+		_, err := wallet.ParseDerivationPath(p)
+		require.Error(t, err, p)
+	}
+
+	for _, kt := range []wallet.KeyType{wallet.KeyTypeECDSA, wallet.KeyTypeEd25519} {
+		t.Run(kt.String(), func(t *testing.T) {
+			require := require.New(t)
+			base := path[:2]
+			d, err := wallet.NewDeriver(seed, kt, base...)
+			require.NoError(err)
+
+			// A wallet is restored from the same seed.
+			w := wallet.NewWallet()
+			accs, err := w.RestoreAccounts(d, 3)
+			require.NoError(err)
+			require.Len(w, 3)
+			require.False(accs[0].Address().Equal(accs[1].Address()))
+
+			d2, err := wallet.NewDeriver(seed, kt, base...)
+			require.NoError(err)
+			w2 := wallet.NewWallet()
+			for i, acc := range accs {
+				acc2, err := w2.NewDerivedAccount(d2, uint32(i))
+				require.NoError(err)
+				require.True(acc.Address().Equal(acc2.Address()))
+				_, err = w.Unlock(acc2.Address())
+				require.NoError(err)
+			}
+
+			// Other base paths derive other accounts.
+			d3, err := wallet.NewDeriver(seed, kt, path[0])
+			require.NoError(err)
+			acc3, err := d3.DeriveAccount(0)
+			require.NoError(err)
+			require.False(accs[0].Address().Equal(acc3.Address()))
+
+			_, err = d.DeriveAccount(wallet.HardenedKeyStart)
+			require.Error(err)
+		})
+	}
+
+	_, err = wallet.NewDeriver(seed, wallet.KeyTypeEd25519, 0)
+	require.Error(t, err, "non-hardened Ed25519")
+	_, err = wallet.NewDeriver(seed[:8], wallet.KeyTypeECDSA)
+	require.Error(t, err, "short seed")
+
+	_, err = wallet.NewECDSADeriver(seed, nil)
+	require.Error(t, err, "no curve")
+}
+
+// TestDeriverCurves checks the derivation on the NIST curves for which
+// SLIP-0010 specifies no derivation against vectors of an independent
+// implementation of the derivation documented at Deriver.
+func TestDeriverCurves(t *testing.T) {
+	seed := mustDecodeHex(t, "000102030405060708090a0b0c0d0e0f")
+	for _, tc := range []struct {
+		curve elliptic.Curve
+		key   string // key is the private key at m/0'.
+	}{
+		{elliptic.P224(), "5528f68172d4b60b955bf0aec8f8d61856f35ea059bfdb184e10fd91"},
+		{elliptic.P384(), "9f8ad8b485546d819da756ae2512cbcd9ca53e7037c1e03080a702c47e233263" +
+			"c8508840992479047434c94e3534617c"},
+		{elliptic.P521(), "00c4280a38923f54a3d5e815af1c25b232ec4bf433ae5743b080932d1be557dbf699f31b3f" +
+			"8f6e63fb92d506a0b611f8e9464e50a3bb422b574ae7f72a7ddbec70c6"},
+	} {
+		tc := tc
+		t.Run(tc.curve.Params().Name, func(t *testing.T) {
+			require := require.New(t)
+			d, err := wallet.NewECDSADeriver(seed, tc.curve)
+			require.NoError(err)
+			acc, err := d.DeriveAccount(0) // m/0'
+			require.NoError(err)
+			sk := acc.ECDSA()
+			require.Equal(tc.curve, sk.Curve)
+			require.Equal(tc.key, hex.EncodeToString(sk.D.FillBytes(make([]byte, len(tc.key)/2))))
+			require.True(tc.curve.IsOnCurve(sk.X, sk.Y))
+
+			// Non-hardened derivation is supported, too.
+			d, err = wallet.NewECDSADeriver(seed, tc.curve, 0, 1)
+			require.NoError(err)
+			acc1, err := d.DeriveAccount(0) // m/0/1/0'
+			require.NoError(err)
+			require.False(acc.Address().Equal(acc1.Address()))
+		})
+	}
+}
+
+func TestSeedFromMnemonic(t *testing.T) {
+	// BIP-39 test vector with 24 words.
+	seed, err := wallet.SeedFromMnemonic("void come effort suffer camp survey warrior heavy shoot primary clutch crush open amazing screen patrol group space point ten exist slush involve unfold", "TREZOR")
+	require.NoError(t, err)
+	require.Equal(t, "01f5bced59dec48e362f2c45b5de68b9fd6c92c6634f44d6d40aab69056506f0e35524a518034ddc1192e1dacd32c1ed3eaa3c3b131c88ed8e7e54c49a5d0998",
+		hex.EncodeToString(seed))
+
+	for name, m := range map[string]string{
+		"checksum":  "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"word":      "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon perun",
+		"length":    "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"uppercase": "Abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+	} {
+		_, err := wallet.SeedFromMnemonic(m, "")
+		require.Error(t, err, name)
+	}
+}
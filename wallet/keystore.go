@@ -0,0 +1,498 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+	"perun.network/go-perun/wallet"
+)
+
+const (
+	// keyFileVersion is the version of the key file format. The ciphertext is
+	// bound to the address string as additional data.
+	keyFileVersion = 1
+	keyFileExt     = ".json"
+	keyFileCipher  = "aes-256-gcm"
+	keyFileKDF     = "scrypt"
+
+	// StandardScryptN is the default scrypt CPU/memory cost parameter.
+	StandardScryptN = 1 << 18
+	// StandardScryptR is the default scrypt block size parameter.
+	StandardScryptR = 8
+	// StandardScryptP is the default scrypt parallelization parameter.
+	StandardScryptP = 1
+
+	scryptKeyLen = 32
+	saltLen      = 32
+)
+
+var (
+	// ErrLocked is returned when unlocking a locked account without a passphrase.
+	ErrLocked = errors.New("account locked")
+	// ErrInUse is returned when locking an account that is in use.
+	ErrInUse = errors.New("account in use")
+)
+
+type (
+	// KeyStore is a wallet.Wallet that stores its accounts as passphrase
+	// encrypted key files in a directory. Accounts are only decrypted on
+	// Unlock and kept in memory as long as they are in use.
+	KeyStore struct {
+		dir        string         // dir is the directory of the key files.
+		passphrase PassphraseFunc // passphrase is asked for on Unlock of locked accounts.
+		scryptN    int            // scryptN is the scrypt cost of new key files.
+		scryptR    int            // scryptR is the scrypt block size of new key files.
+		scryptP    int            // scryptP is the scrypt parallelization of new key files.
+
+		mtx      sync.Mutex
+		unlocked map[wallet.AddrKey]*unlockedAccount
+	}
+
+	// PassphraseFunc returns the passphrase of the key file of the given Address.
+	PassphraseFunc func(addr *Address) (string, error)
+
+	// KeyStoreOpt extends the KeyStore constructor.
+	KeyStoreOpt func(*KeyStore)
+
+	unlockedAccount struct {
+		acc   *Account
+		usage int
+	}
+
+	keyFile struct {
+		Version int           `json:"version"`
+		Address *Address      `json:"address"`
+		Crypto  keyFileCrypto `json:"crypto"`
+	}
+
+	keyFileCrypto struct {
+		Cipher     string       `json:"cipher"`
+		CipherText []byte       `json:"ciphertext"`
+		Nonce      []byte       `json:"nonce"`
+		KDF        string       `json:"kdf"`
+		KDFParams  scryptParams `json:"kdfparams"`
+	}
+
+	scryptParams struct {
+		N    int    `json:"n"`
+		R    int    `json:"r"`
+		P    int    `json:"p"`
+		Salt []byte `json:"salt"`
+	}
+)
+
+// WithPassphrase sets the function that is asked for the passphrase when a
+// locked account is unlocked.
+func WithPassphrase(passphrase PassphraseFunc) KeyStoreOpt {
+	return func(ks *KeyStore) {
+		ks.passphrase = passphrase
+	}
+}
+
+// WithScryptParams overwrites the scrypt parameters used for encrypting new
+// key files. Existing key files are decrypted with their stored parameters.
+func WithScryptParams(n, r, p int) KeyStoreOpt {
+	return func(ks *KeyStore) {
+		ks.scryptN, ks.scryptR, ks.scryptP = n, r, p
+	}
+}
+
+// NewKeyStore returns a KeyStore with the key files in the given directory.
+// The directory is created if it does not exist.
+func NewKeyStore(dir string, opts ...KeyStoreOpt) (*KeyStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil { //nolint:gomnd
+		return nil, fmt.Errorf("creating keystore directory: %w", err)
+	}
+	ks := &KeyStore{
+		dir:      dir,
+		scryptN:  StandardScryptN,
+		scryptR:  StandardScryptR,
+		scryptP:  StandardScryptP,
+		unlocked: make(map[wallet.AddrKey]*unlockedAccount),
+	}
+	for _, opt := range opts {
+		opt(ks)
+	}
+	return ks, nil
+}
+
+// NewAccount generates a new Account using the randomness provided by rng and
+// stores it encrypted with the given passphrase. The account stays locked.
+func (ks *KeyStore) NewAccount(rng io.Reader, passphrase string) (*Address, error) {
+	acc := NewRandomAccount(rng)
+	defer acc.zeroize()
+	return ks.Import(acc, passphrase)
+}
+
+// Import stores the given Account encrypted with the given passphrase.
+// The account stays locked.
+func (ks *KeyStore) Import(acc *Account, passphrase string) (*Address, error) {
+	addr := acc.FabricAddress().Clone()
+	kf, err := ks.encrypt(acc, addr, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(kf)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling key file: %w", err)
+	}
+
+	path := ks.path(addr)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("account %v already exists", addr)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil { //nolint:gomnd
+		return nil, fmt.Errorf("writing key file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("renaming key file: %w", err)
+	}
+	return addr, nil
+}
+
+// Addresses returns the addresses of all accounts in the KeyStore.
+func (ks *KeyStore) Addresses() ([]*Address, error) {
+	entries, err := os.ReadDir(ks.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading keystore directory: %w", err)
+	}
+	var addrs []*Address
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), keyFileExt) {
+			continue
+		}
+		kf, err := ks.readKeyFile(filepath.Join(ks.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, kf.Address)
+	}
+	return addrs, nil
+}
+
+// Unlock returns the account for the provided address. If the account is
+// locked, its key file is decrypted with the passphrase returned by the
+// PassphraseFunc of the KeyStore. Without a PassphraseFunc, locked accounts
+// must be unlocked with UnlockWithPassphrase.
+func (ks *KeyStore) Unlock(a wallet.Address) (wallet.Account, error) {
+	if acc, ok := ks.unlockedAccount(a); ok {
+		return acc, nil
+	}
+	addr, ok := a.(*Address)
+	if !ok {
+		return nil, fmt.Errorf("address of unexpected type %T", a)
+	}
+	if ks.passphrase == nil {
+		return nil, fmt.Errorf("unlocking %v: %w", addr, ErrLocked)
+	}
+	passphrase, err := ks.passphrase(addr)
+	if err != nil {
+		return nil, fmt.Errorf("getting passphrase: %w", err)
+	}
+	return ks.unlock(addr, passphrase)
+}
+
+// UnlockWithPassphrase decrypts the key file of the given address with the
+// given passphrase and returns the account.
+func (ks *KeyStore) UnlockWithPassphrase(addr *Address, passphrase string) (*Account, error) {
+	if acc, ok := ks.unlockedAccount(addr); ok {
+		return acc, nil
+	}
+	return ks.unlock(addr, passphrase)
+}
+
+// Lock zeroizes the key of the given address and removes it from memory.
+// Accounts that are in use, i.e., whose usage count is not zero, are not
+// locked and ErrInUse is returned.
+func (ks *KeyStore) Lock(a wallet.Address) error {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	k := wallet.Key(a)
+	if ua, ok := ks.unlocked[k]; ok && ua.usage > 0 {
+		return fmt.Errorf("locking %v: %w", a, ErrInUse)
+	}
+	ks.lock(k)
+	return nil
+}
+
+// LockAll zeroizes the keys of all unlocked accounts that are not in use and
+// removes them from memory. Accounts in use are locked once their usage count
+// drops to zero.
+func (ks *KeyStore) LockAll() {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+	for k, ua := range ks.unlocked {
+		if ua.usage == 0 {
+			ks.lock(k)
+		}
+	}
+}
+
+// IncrementUsage increments the usage count of the given unlocked account.
+// It panics if the account is not unlocked.
+func (ks *KeyStore) IncrementUsage(a wallet.Address) {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	ua, ok := ks.unlocked[wallet.Key(a)]
+	if !ok {
+		panic(fmt.Sprintf("IncrementUsage: account %v not unlocked", a))
+	}
+	ua.usage++
+}
+
+// DecrementUsage decrements the usage count of the given unlocked account.
+// The account is locked once its usage count drops to zero.
+// It panics if the account is not unlocked or the usage count is already zero.
+func (ks *KeyStore) DecrementUsage(a wallet.Address) {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	k := wallet.Key(a)
+	ua, ok := ks.unlocked[k]
+	if !ok {
+		panic(fmt.Sprintf("DecrementUsage: account %v not unlocked", a))
+	} else if ua.usage == 0 {
+		panic(fmt.Sprintf("DecrementUsage: usage count of account %v below zero", a))
+	}
+	ua.usage--
+	if ua.usage == 0 {
+		ks.lock(k)
+	}
+}
+
+// Usage returns the usage count of the given account. Locked accounts have a
+// usage count of zero.
+func (ks *KeyStore) Usage(a wallet.Address) int {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	if ua, ok := ks.unlocked[wallet.Key(a)]; ok {
+		return ua.usage
+	}
+	return 0
+}
+
+// IsUnlocked returns whether the account of the given address is unlocked.
+func (ks *KeyStore) IsUnlocked(a wallet.Address) bool {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	_, ok := ks.unlocked[wallet.Key(a)]
+	return ok
+}
+
+// unlockedAccount returns the account of the given address if it is unlocked.
+func (ks *KeyStore) unlockedAccount(a wallet.Address) (*Account, bool) {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	if ua, ok := ks.unlocked[wallet.Key(a)]; ok {
+		return ua.acc, true
+	}
+	return nil, false
+}
+
+// unlock decrypts the key file of the given address. The expensive key
+// derivation runs without holding the mutex of the KeyStore. If the account
+// was unlocked concurrently, the already unlocked account is returned.
+func (ks *KeyStore) unlock(addr *Address, passphrase string) (*Account, error) {
+	kf, err := ks.readKeyFile(ks.path(addr))
+	if err != nil {
+		return nil, err
+	}
+	acc, err := kf.decrypt(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting key file of %v: %w", addr, err)
+	}
+
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+	k := wallet.Key(addr)
+	if ua, ok := ks.unlocked[k]; ok {
+		acc.zeroize()
+		return ua.acc, nil
+	}
+	ks.unlocked[k] = &unlockedAccount{acc: acc}
+	return acc, nil
+}
+
+func (ks *KeyStore) lock(k wallet.AddrKey) {
+	if ua, ok := ks.unlocked[k]; ok {
+		ua.acc.zeroize()
+		delete(ks.unlocked, k)
+	}
+}
+
+func (ks *KeyStore) path(addr *Address) string {
+	return filepath.Join(ks.dir, addr.String()+keyFileExt)
+}
+
+func (ks *KeyStore) readKeyFile(path string) (*keyFile, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unknown account: %s", strings.TrimSuffix(filepath.Base(path), keyFileExt))
+	} else if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("unmarshaling key file %s: %w", path, err)
+	}
+	if kf.Version != keyFileVersion {
+		return nil, fmt.Errorf("key file %s: unsupported version %d", path, kf.Version)
+	}
+	return &kf, nil
+}
+
+func (ks *KeyStore) encrypt(acc *Account, addr *Address, passphrase string) (*keyFile, error) {
+	params := scryptParams{
+		N:    ks.scryptN,
+		R:    ks.scryptR,
+		P:    ks.scryptP,
+		Salt: make([]byte, saltLen),
+	}
+	if _, err := io.ReadFull(rand.Reader, params.Salt); err != nil {
+		return nil, fmt.Errorf("reading salt: %w", err)
+	}
+	aead, err := params.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("reading nonce: %w", err)
+	}
+	plain := acc.secret()
+	defer zeroBytes(plain)
+	return &keyFile{
+		Version: keyFileVersion,
+		Address: addr,
+		Crypto: keyFileCrypto{
+			Cipher:     keyFileCipher,
+			CipherText: aead.Seal(nil, nonce, plain, []byte(addr.String())),
+			Nonce:      nonce,
+			KDF:        keyFileKDF,
+			KDFParams:  params,
+		},
+	}, nil
+}
+
+func (kf *keyFile) decrypt(passphrase string) (*Account, error) {
+	if kf.Crypto.Cipher != keyFileCipher {
+		return nil, fmt.Errorf("unsupported cipher: %s", kf.Crypto.Cipher)
+	} else if kf.Crypto.KDF != keyFileKDF {
+		return nil, fmt.Errorf("unsupported kdf: %s", kf.Crypto.KDF)
+	} else if kf.Address == nil {
+		return nil, errors.New("missing address")
+	}
+	aead, err := kf.Crypto.KDFParams.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, kf.Crypto.Nonce, kf.Crypto.CipherText, []byte(kf.Address.String()))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted key file")
+	}
+	defer zeroBytes(plain)
+
+	acc, err := accountFromSecret(kf.Address, plain)
+	if err != nil {
+		return nil, err
+	}
+	if !acc.FabricAddress().Equal(kf.Address) {
+		acc.zeroize()
+		return nil, errors.New("key does not match address")
+	}
+	return acc, nil
+}
+
+// secret returns the private key of the Account as stored in key files, the
+// scalar for ECDSA and the seed for Ed25519.
+func (a *Account) secret() []byte {
+	if a.Type() == KeyTypeEd25519 {
+		return append([]byte(nil), a.ed25519.Seed()...)
+	}
+	return a.ecdsa.D.FillBytes(make([]byte, pointByteSize(a.ecdsa.Curve)))
+}
+
+// accountFromSecret restores the Account of the given address type from its
+// secret.
+func accountFromSecret(addr *Address, secret []byte) (*Account, error) {
+	if addr.Type() == KeyTypeEd25519 {
+		if l := len(secret); l != ed25519.SeedSize {
+			return nil, fmt.Errorf("Ed25519 seed has wrong length %d", l)
+		}
+		return NewEd25519Account(ed25519.NewKeyFromSeed(secret)), nil
+	}
+	sk := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: addr.ECDSA().Curve},
+		D:         new(big.Int).SetBytes(secret),
+	}
+	sk.X, sk.Y = sk.Curve.ScalarBaseMult(secret)
+	return NewECDSAAccount(sk), nil
+}
+
+func (p scryptParams) aead(passphrase string) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), p.Salt, p.N, p.R, p.P, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	defer zeroBytes(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// zeroize overwrites the private key of the Account with zeros.
+func (a *Account) zeroize() {
+	if a.Type() == KeyTypeEd25519 {
+		zeroBytes(a.ed25519)
+		return
+	}
+	if a.ecdsa.D == nil {
+		return
+	}
+	d := a.ecdsa.D.Bits()
+	for i := range d {
+		d[i] = 0
+	}
+	a.ecdsa.D.SetInt64(0)
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	wtest "perun.network/go-perun/wallet/test"
+	"polycry.pt/poly-go/test"
+
+	"github.com/perun-network/perun-fabric/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+const passphrase = "correct horse battery staple"
+
+// Light scrypt parameters to keep the tests fast.
+var lightScrypt = wallet.WithScryptParams(1<<4, 8, 1)
+
+func staticPassphrase(p string) wallet.PassphraseFunc {
+	return func(*wallet.Address) (string, error) { return p, nil }
+}
+
+func TestKeyStoreWalletBackend(t *testing.T) {
+	rng := test.Prng(t)
+	ks, err := wallet.NewKeyStore(t.TempDir(), lightScrypt, wallet.WithPassphrase(staticPassphrase(passphrase)))
+	require.NoError(t, err)
+	addr, err := ks.NewAccount(rng, passphrase)
+	require.NoError(t, err)
+	addrUnk := wallet.NewRandomAddress(rng)
+	addrUnkBytes, err := addrUnk.MarshalBinary()
+	require.NoError(t, err)
+
+	setup := &wtest.Setup{
+		Backend:         wallet.Backend{},
+		Wallet:          ks,
+		AddressInWallet: addr,
+		ZeroAddress: wallet.NewECDSAAddress(&ecdsa.PublicKey{
+			Curve: addr.ECDSA().Curve,
+			X:     new(big.Int),
+			Y:     new(big.Int),
+		}),
+		DataToSign:        addr.ECDSA().X.Bytes(),
+		AddressMarshalled: addrUnkBytes,
+	}
+
+	wtest.TestAccountWithWalletAndBackend(t, setup)
+}
+
+func TestKeyStore(t *testing.T) {
+	t.Run("Unlock", func(t *testing.T) {
+		require := require.New(t)
+		rng := test.Prng(t)
+		dir := t.TempDir()
+		acc := wallet.NewRandomAccount(rng)
+
+		ks, err := wallet.NewKeyStore(dir, lightScrypt)
+		require.NoError(err)
+		addr, err := ks.Import(acc, passphrase)
+		require.NoError(err)
+		require.True(addr.Equal(acc.Address()))
+		_, err = ks.Import(acc, passphrase)
+		require.Error(err, "duplicate import")
+
+		// The key file does not contain the key in clear.
+		data, err := os.ReadFile(filepath.Join(dir, addr.String()+".json"))
+		require.NoError(err)
+		require.NotContains(string(data), acc.ECDSA().D.String())
+
+		// Without PassphraseFunc, Unlock needs an unlocked account.
+		_, err = ks.Unlock(addr)
+		require.True(errors.Is(err, wallet.ErrLocked))
+		_, err = ks.UnlockWithPassphrase(addr, "wrong")
+		require.Error(err)
+		require.False(ks.IsUnlocked(addr))
+
+		// A fresh KeyStore on the same directory decrypts the key.
+		ks, err = wallet.NewKeyStore(dir)
+		require.NoError(err)
+		addrs, err := ks.Addresses()
+		require.NoError(err)
+		require.Len(addrs, 1)
+		require.True(addrs[0].Equal(addr))
+		unlocked, err := ks.UnlockWithPassphrase(addr, passphrase)
+		require.NoError(err)
+		require.Equal(0, acc.ECDSA().D.Cmp(unlocked.ECDSA().D))
+		unlocked2, err := ks.Unlock(addr)
+		require.NoError(err)
+		require.Same(unlocked, unlocked2)
+	})
+
+	t.Run("Ed25519", func(t *testing.T) {
+		require := require.New(t)
+		rng := test.Prng(t)
+		dir := t.TempDir()
+		acc := wallet.NewRandomEd25519Account(rng)
+
+		ks, err := wallet.NewKeyStore(dir, lightScrypt)
+		require.NoError(err)
+		addr, err := ks.Import(acc, passphrase)
+		require.NoError(err)
+		require.Equal(wallet.KeyTypeEd25519, addr.Type())
+
+		unlocked, err := ks.UnlockWithPassphrase(addr, passphrase)
+		require.NoError(err)
+		require.True(acc.Ed25519().Equal(unlocked.Ed25519()))
+
+		ks.LockAll()
+		require.Equal(make([]byte, len(unlocked.Ed25519())), []byte(unlocked.Ed25519()), "key zeroized")
+	})
+
+	t.Run("LockAll", func(t *testing.T) {
+		require := require.New(t)
+		rng := test.Prng(t)
+		ks, err := wallet.NewKeyStore(t.TempDir(), lightScrypt, wallet.WithPassphrase(staticPassphrase(passphrase)))
+		require.NoError(err)
+
+		var accs []*wallet.Account
+		for i := 0; i < 2; i++ {
+			addr, err := ks.NewAccount(rng, passphrase)
+			require.NoError(err)
+			acc, err := ks.Unlock(addr)
+			require.NoError(err)
+			accs = append(accs, acc.(*wallet.Account))
+		}
+
+		ks.LockAll()
+		for _, acc := range accs {
+			require.Zero(acc.ECDSA().D.Sign(), "key zeroized")
+			require.False(ks.IsUnlocked(acc.Address()))
+		}
+
+		// Accounts can be unlocked again.
+		acc, err := ks.Unlock(accs[0].Address())
+		require.NoError(err)
+		require.NotZero(acc.(*wallet.Account).ECDSA().D.Sign())
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		require := require.New(t)
+		rng := test.Prng(t)
+		ks, err := wallet.NewKeyStore(t.TempDir(), lightScrypt, wallet.WithPassphrase(staticPassphrase(passphrase)))
+		require.NoError(err)
+		addr, err := ks.NewAccount(rng, passphrase)
+		require.NoError(err)
+
+		require.Panics(func() { ks.IncrementUsage(addr) }, "locked")
+		acc, err := ks.Unlock(addr)
+		require.NoError(err)
+		require.Panics(func() { ks.DecrementUsage(addr) }, "below zero")
+
+		ks.IncrementUsage(addr)
+		ks.IncrementUsage(addr)
+		require.Equal(2, ks.Usage(addr))
+		ks.DecrementUsage(addr)
+		require.Equal(1, ks.Usage(addr))
+		require.True(ks.IsUnlocked(addr))
+
+		// The account is locked once it is not used anymore.
+		ks.DecrementUsage(addr)
+		require.Equal(0, ks.Usage(addr))
+		require.False(ks.IsUnlocked(addr))
+		require.Zero(acc.(*wallet.Account).ECDSA().D.Sign())
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		require := require.New(t)
+		rng := test.Prng(t)
+		ks, err := wallet.NewKeyStore(t.TempDir(), lightScrypt, wallet.WithPassphrase(staticPassphrase(passphrase)))
+		require.NoError(err)
+		addr, err := ks.NewAccount(rng, passphrase)
+		require.NoError(err)
+
+		// Concurrent unlocks return the same account.
+		accs := make(chan *wallet.Account, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				acc, err := ks.UnlockWithPassphrase(addr, passphrase)
+				require.NoError(err)
+				accs <- acc
+			}()
+		}
+		acc := <-accs
+		require.Same(acc, <-accs)
+
+		// Accounts in use are not locked.
+		ks.IncrementUsage(addr)
+		require.True(errors.Is(ks.Lock(addr), wallet.ErrInUse))
+		ks.LockAll()
+		require.True(ks.IsUnlocked(addr))
+		require.NotZero(acc.ECDSA().D.Sign())
+
+		ks.DecrementUsage(addr)
+		require.False(ks.IsUnlocked(addr))
+		require.NoError(ks.Lock(addr))
+	})
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+// Package remote provides Accounts which sign with a separate signing daemon,
+// the Signer, and the Signer itself. Clients and Signer communicate over
+// net/rpc, usually on a local Unix socket.
+package remote
+
+import (
+	"bytes"
+	"fmt"
+	"net/rpc"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+type (
+	// Client is a connection to a Signer. It is a wallet.Wallet of the remote
+	// Accounts of the Signer.
+	Client struct {
+		rpc *rpc.Client
+	}
+
+	// Account is a wallet.Account whose signatures are created by a Signer.
+	Account struct {
+		client *Client
+		addr   wallet.Address
+	}
+)
+
+// Dial connects to the Signer at the given address, e.g., Dial("unix",
+// "/run/perun/signer.sock").
+func Dial(network, address string) (*Client, error) {
+	c, err := rpc.Dial(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("dialing signer: %w", err)
+	}
+	return &Client{rpc: c}, nil
+}
+
+// Close closes the connection to the Signer.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// Account returns the remote Account of the given address. It does not check
+// whether the Signer has the account, use Unlock for that.
+func (c *Client) Account(addr wallet.Address) *Account {
+	return &Account{client: c, addr: addr}
+}
+
+// Unlock returns the remote Account of the given address, if the Signer has
+// the account.
+func (c *Client) Unlock(addr wallet.Address) (wallet.Account, error) {
+	data, err := addr.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("encoding address: %w", err)
+	}
+	if err := c.rpc.Call(serviceName+".Unlock", data, new(struct{})); err != nil {
+		return nil, err
+	}
+	return c.Account(addr), nil
+}
+
+// LockAll - noop, the Signer manages its keys.
+func (c *Client) LockAll() {}
+
+// IncrementUsage - noop, the Signer manages its keys.
+func (c *Client) IncrementUsage(wallet.Address) {}
+
+// DecrementUsage - noop, the Signer manages its keys.
+func (c *Client) DecrementUsage(wallet.Address) {}
+
+// RegisterChannel registers the given channel params at the Signer. Policies
+// that relate states to the channel participants need the params of a
+// channel before its states can be signed. It returns the channel id
+// calculated by the Signer.
+func (c *Client) RegisterChannel(params *channel.Params) (channel.ID, error) {
+	var buf bytes.Buffer
+	if err := params.Encode(&buf); err != nil {
+		return channel.ID{}, fmt.Errorf("encoding params: %w", err)
+	}
+	var id channel.ID
+	return id, c.rpc.Call(serviceName+".RegisterChannel", buf.Bytes(), &id)
+}
+
+// Address returns the address of the Account.
+func (a *Account) Address() wallet.Address {
+	return a.addr
+}
+
+// SignData sends the data to the Signer and returns its signature.
+func (a *Account) SignData(data []byte) ([]byte, error) {
+	addr, err := a.addr.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("encoding address: %w", err)
+	}
+	var sig []byte
+	if err := a.client.rpc.Call(serviceName+".Sign", SignArgs{Address: addr, Data: data}, &sig); err != nil {
+		return nil, fmt.Errorf("remote signing: %w", err)
+	}
+	return sig, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+)
+
+const indexFileExt = ".json"
+
+type (
+	// signedIndex is the index of the states signed per channel and account.
+	// It is persisted in a directory with one file per channel and account, so
+	// that the policies keep their history across restarts of the Signer. It
+	// is not safe for concurrent use.
+	signedIndex struct {
+		dir     string
+		records map[signedKey]*signedRecord
+	}
+
+	signedKey struct {
+		id   channel.ID
+		addr wallet.AddrKey
+	}
+
+	// signedRecord holds the encoded first and last state signed by an account
+	// in a channel.
+	signedRecord struct {
+		First []byte `json:"first"`
+		Last  []byte `json:"last"`
+
+		first, last *channel.State // decoded First and Last
+	}
+)
+
+// newSignedIndex returns the signedIndex persisted in the given directory,
+// which is created if it does not exist.
+func newSignedIndex(dir string) (*signedIndex, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil { //nolint:gomnd
+		return nil, fmt.Errorf("creating index directory: %w", err)
+	}
+	return &signedIndex{dir: dir, records: make(map[signedKey]*signedRecord)}, nil
+}
+
+// get returns the record of the given key, nil if no state was signed yet.
+func (x *signedIndex) get(key signedKey) (*signedRecord, error) {
+	if rec, ok := x.records[key]; ok {
+		return rec, nil
+	}
+	data, err := os.ReadFile(x.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil //nolint:nilnil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading index file: %w", err)
+	}
+	rec := new(signedRecord)
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("decoding index file: %w", err)
+	}
+	if rec.first, err = decodeState(rec.First); err != nil {
+		return nil, err
+	}
+	if rec.last, err = decodeState(rec.Last); err != nil {
+		return nil, err
+	}
+	x.records[key] = rec
+	return rec, nil
+}
+
+// put records the given encoded state as the last signed state of the key, and
+// as the first one if no state was signed before. The record is written to
+// disk before it is updated in memory.
+func (x *signedIndex) put(key signedKey, data []byte, state *channel.State) error {
+	rec := &signedRecord{First: data, Last: data, first: state, last: state}
+	if prev, ok := x.records[key]; ok {
+		rec.First, rec.first = prev.First, prev.first
+	}
+	enc, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding index file: %w", err)
+	}
+	path := x.path(key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, enc, 0o600); err != nil { //nolint:gomnd
+		return fmt.Errorf("writing index file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing index file: %w", err)
+	}
+	x.records[key] = rec
+	return nil
+}
+
+func (x *signedIndex) path(key signedKey) string {
+	return filepath.Join(x.dir, fmt.Sprintf("%x-%x%s", key.id, []byte(key.addr), indexFileExt))
+}
+
+func decodeState(data []byte) (*channel.State, error) {
+	p, err := DecodePayload(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding indexed state: %w", err)
+	} else if p.State == nil {
+		return nil, errors.New("indexed payload is no state")
+	}
+	return p.State, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"perun.network/go-perun/channel"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+// Payload is the decoded data of a sign request. Exactly one of its fields is
+// set.
+type Payload struct {
+	State       *channel.State   // State is set if a channel state is signed.
+	WithdrawReq *adj.WithdrawReq // WithdrawReq is set if a withdraw request is signed.
+}
+
+// DecodePayload decodes the given data, which is either an encoded
+// channel.State or an encoded adj.WithdrawReq. The channel and wallet backends
+// must be set for decoding.
+func DecodePayload(data []byte) (*Payload, error) {
+	var (
+		state channel.State
+		req   adj.WithdrawReq
+	)
+	isState := decodeAll(data, state.Decode)
+	isReq := decodeAll(data, req.Decode)
+
+	switch {
+	case isState && isReq:
+		return nil, errors.New("ambiguous payload")
+	case isState:
+		return &Payload{State: &state}, nil
+	case isReq:
+		return &Payload{WithdrawReq: &req}, nil
+	}
+	return nil, errors.New("payload is neither a channel state nor a withdraw request")
+}
+
+// decodeAll returns whether decode consumes exactly the given data without
+// error.
+func decodeAll(data []byte, decode func(io.Reader) error) bool {
+	r := bytes.NewReader(data)
+	return decode(r) == nil && r.Len() == 0
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+type (
+	// Request is a sign request as seen by a Policy.
+	Request struct {
+		Address wallet.Address  // Address is the address of the signing account.
+		Payload *Payload        // Payload is the decoded data to sign.
+		Params  *channel.Params // Params are the registered params of the channel, nil if unknown.
+		First   *channel.State  // First is the first state signed by Address in the channel, nil if none.
+		Prev    *channel.State  // Prev is the last state signed by Address in the channel, nil if none.
+	}
+
+	// Policy decides whether the Signer may sign a Request.
+	Policy interface {
+		// Check returns an error if the Request must not be signed.
+		Check(req *Request) error
+	}
+
+	// PolicyFunc is a Policy implemented by a function.
+	PolicyFunc func(req *Request) error
+)
+
+// Check calls f(req).
+func (f PolicyFunc) Check(req *Request) error { return f(req) }
+
+// PartIdx returns the index of the signing account in the channel params.
+func (req *Request) PartIdx() (channel.Index, error) {
+	if req.Params == nil {
+		return 0, errors.New("unknown channel params")
+	}
+	for i, p := range req.Params.Parts {
+		if p.Equal(req.Address) {
+			return channel.Index(i), nil
+		}
+	}
+	return 0, errors.New("signer is no channel participant")
+}
+
+// MaxBalanceDecrease returns a Policy that rejects states that lower the
+// balance of the signer by more than max in any asset, compared to the first
+// state the signer signed in the channel. The decrease is cumulative, so a
+// series of states cannot lower the balance below that floor. The first state
+// of a channel is not restricted. It requires the channel params to be
+// registered at the Signer.
+func MaxBalanceDecrease(max *big.Int) Policy {
+	return PolicyFunc(func(req *Request) error {
+		state := req.Payload.State
+		if state == nil || req.First == nil {
+			return nil
+		}
+		idx, err := req.PartIdx()
+		if err != nil {
+			return err
+		}
+		if len(state.Balances) != len(req.First.Balances) {
+			return errors.New("number of assets changed")
+		}
+		for a := range state.Balances {
+			decrease := new(big.Int).Sub(req.First.Balances[a][idx], state.Balances[a][idx])
+			if decrease.Cmp(max) > 0 {
+				return fmt.Errorf("balance of asset %d decreases by %v, at most %v allowed", a, decrease, max)
+			}
+		}
+		return nil
+	})
+}
+
+// WithdrawReceivers returns a Policy that only allows withdrawals to the given
+// receivers.
+func WithdrawReceivers(receivers ...adj.AccountID) Policy {
+	return PolicyFunc(func(req *Request) error {
+		wr := req.Payload.WithdrawReq
+		if wr == nil {
+			return nil
+		}
+		for _, r := range receivers {
+			if wr.Receiver == r {
+				return nil
+			}
+		}
+		return fmt.Errorf("withdrawal to receiver %s not allowed", wr.Receiver)
+	})
+}
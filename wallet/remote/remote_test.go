@@ -0,0 +1,199 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package remote_test
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pwallet "perun.network/go-perun/wallet"
+	ptest "polycry.pt/poly-go/test"
+
+	_ "github.com/perun-network/perun-fabric" // init backend
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/wallet"
+	"github.com/perun-network/perun-fabric/wallet/remote"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	rng := ptest.Prng(t)
+	s := adjtest.NewSetup(rng)
+	receiver := adj.AccountID("receiver")
+	indexDir := t.TempDir()
+
+	// startSigner starts a signer with the account of participant 0 and
+	// returns a client connected to it.
+	startSigner := func(t *testing.T) *remote.Client {
+		t.Helper()
+		signer, err := remote.NewSigner(
+			wallet.NewWallet(s.Accs[0].(*wallet.Account)),
+			s.Domain,
+			indexDir,
+			remote.MaxBalanceDecrease(big.NewInt(10)),
+			remote.WithdrawReceivers(receiver),
+		)
+		require.NoError(t, err)
+		l, err := net.Listen("unix", filepath.Join(t.TempDir(), "signer.sock"))
+		require.NoError(t, err)
+		t.Cleanup(func() { l.Close() })
+		go signer.Serve(l)
+
+		client, err := remote.Dial("unix", l.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+	client := startSigner(t)
+
+	_, err := client.Unlock(s.Parts[1])
+	require.Error(t, err, "unknown account")
+	acc, err := client.Unlock(s.Parts[0])
+	require.NoError(t, err)
+	require.True(t, acc.Address().Equal(s.Parts[0]))
+
+	sign := func() error {
+		sig, err := s.State.Sign(acc)
+		if err != nil {
+			return err
+		}
+		if ok, err := adj.VerifySig(s.Parts[0], *s.State, sig); err != nil {
+			return err
+		} else if !ok {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	bal := func() *big.Int { return s.State.Balances[0] }
+	transfer := func(amount int64) {
+		bal().Sub(bal(), big.NewInt(amount))
+		s.State.Balances[1].Add(s.State.Balances[1], big.NewInt(amount))
+	}
+
+	t.Run("State", func(t *testing.T) {
+		require := require.New(t)
+		// Without registered params, only the first state can be signed.
+		require.NoError(sign())
+		s.State.Version = 1
+		require.Error(sign(), "unknown params")
+
+		id, err := client.RegisterChannel(s.Params.CoreParams())
+		require.NoError(err)
+		require.Equal(s.State.ID, id)
+		require.NoError(sign())
+
+		// Decrease within the limit.
+		s.State.Version = 2
+		transfer(10)
+		require.NoError(sign())
+
+		// The same state may be signed again, another one of the same version
+		// not.
+		require.NoError(sign())
+		s.State.Balances[1].Add(s.State.Balances[1], big.NewInt(1))
+		require.Error(sign(), "other state of same version")
+		s.State.Balances[1].Sub(s.State.Balances[1], big.NewInt(1))
+
+		// The decrease is cumulative, a small decrease beyond the limit is
+		// rejected.
+		s.State.Version = 3
+		transfer(1)
+		require.Error(sign())
+		transfer(-6)
+		require.NoError(sign())
+
+		// Lower versions are rejected.
+		s.State.Version = 1
+		require.Error(sign())
+		s.State.Version = 3
+	})
+
+	t.Run("Restart", func(t *testing.T) {
+		require := require.New(t)
+		client := startSigner(t)
+		acc := client.Account(s.Parts[0])
+		_, err := client.RegisterChannel(s.Params.CoreParams())
+		require.NoError(err)
+
+		// The signed states are restored from the index.
+		state := s.State.Clone()
+		state.Version = 2
+		_, err = state.Sign(acc)
+		require.Error(err, "lower version")
+		state.Version = 4
+		state.Balances[0].Sub(state.Balances[0], big.NewInt(6))
+		state.Balances[1].Add(state.Balances[1], big.NewInt(6))
+		_, err = state.Sign(acc)
+		require.Error(err, "cumulative decrease")
+		_, err = s.State.Sign(acc)
+		require.NoError(err)
+	})
+
+	t.Run("WithdrawReq", func(t *testing.T) {
+		require := require.New(t)
+		_, err := adj.SignWithdrawRequest(acc, s.Domain, s.State.ID, receiver)
+		require.NoError(err)
+		_, err = adj.SignWithdrawRequest(acc, s.Domain, s.State.ID, "other")
+		require.Error(err, "receiver not allowed")
+		_, err = adj.SignWithdrawRequest(acc, adj.NewDomain("other", "adjudicator"), s.State.ID, receiver)
+		require.Error(err, "other domain")
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		_, err := acc.SignData([]byte("arbitrary data"))
+		require.Error(t, err)
+	})
+}
+
+func TestDecodePayload(t *testing.T) {
+	rng := ptest.Prng(t)
+	s := adjtest.NewSetup(rng)
+	var rec recorder
+	_, err := s.State.Sign(&rec)
+	require.NoError(t, err)
+	p, err := remote.DecodePayload(rec.data)
+	require.NoError(t, err)
+	require.NotNil(t, p.State)
+	require.Nil(t, p.WithdrawReq)
+	require.NoError(t, p.State.Equal(s.State.CoreState()))
+
+	rec.addr = s.Parts[0]
+	_, err = adj.SignWithdrawRequest(&rec, s.Domain, s.State.ID, "receiver")
+	require.NoError(t, err)
+	p, err = remote.DecodePayload(rec.data)
+	require.NoError(t, err)
+	require.Nil(t, p.State)
+	require.NotNil(t, p.WithdrawReq)
+	require.Equal(t, adj.AccountID("receiver"), p.WithdrawReq.Receiver)
+
+	_, err = remote.DecodePayload(append(rec.data, 0))
+	require.Error(t, err)
+}
+
+// recorder is an account that records the data it signs.
+type recorder struct {
+	addr pwallet.Address
+	data []byte
+}
+
+func (r *recorder) Address() pwallet.Address { return r.addr }
+
+func (r *recorder) SignData(data []byte) ([]byte, error) {
+	r.data = data
+	return []byte{}, nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package remote
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+// serviceName is the name of the Signer's RPC service.
+const serviceName = "Signer"
+
+type (
+	// Signer signs data on behalf of remote Accounts with the accounts of its
+	// wallet. It only signs channel states and withdraw requests that pass all
+	// of its policies.
+	Signer struct {
+		wallet   wallet.Wallet // wallet holds the signing accounts.
+		domain   adj.Domain    // domain is the domain of the adjudicator the channels are on.
+		policies []Policy      // policies are checked before signing.
+
+		mtx    sync.Mutex
+		params map[channel.ID]*channel.Params // params are the registered channel params.
+		signed *signedIndex                   // signed indexes the signed states.
+	}
+
+	// service exposes the Signer over net/rpc.
+	service struct {
+		signer *Signer
+	}
+
+	// SignArgs are the arguments of a sign call.
+	SignArgs struct {
+		Address []byte // Address is the binary encoded address of the signing account.
+		Data    []byte // Data is the encoded payload to sign.
+	}
+)
+
+// NewSigner returns a Signer for the channels on the adjudicator of the given
+// domain, which signs with the accounts of the given wallet after checking the
+// given policies. The signed states are indexed in the given directory, which
+// must be kept across restarts of the Signer.
+func NewSigner(w wallet.Wallet, domain adj.Domain, dir string, policies ...Policy) (*Signer, error) {
+	signed, err := newSignedIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{
+		wallet:   w,
+		domain:   domain,
+		policies: policies,
+		params:   make(map[channel.ID]*channel.Params),
+		signed:   signed,
+	}, nil
+}
+
+// Serve serves the Signer to the clients connecting on the given listener,
+// usually a Unix socket. It blocks until the listener is closed.
+func (s *Signer) Serve(l net.Listener) {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(serviceName, &service{signer: s}); err != nil {
+		panic("registering signer service: " + err.Error())
+	}
+	srv.Accept(l)
+}
+
+// RegisterChannel registers the given channel params, so that policies can
+// relate signed states to the channel participants. It returns the channel id.
+func (s *Signer) RegisterChannel(params *channel.Params) channel.ID {
+	id := s.domain.CalcID(params)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.params[id] = params.Clone()
+	return id
+}
+
+// Sign signs the given data with the account of the given address, if the data
+// is a channel state or a withdraw request which passes all policies. A state
+// is only signed if its version is higher than that of the last state signed
+// by the account in the channel, or if it is that state.
+func (s *Signer) Sign(addr wallet.Address, data []byte) (wallet.Sig, error) {
+	payload, err := DecodePayload(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	req := &Request{Address: addr, Payload: payload}
+	var key signedKey
+	if payload.State != nil {
+		key = signedKey{id: payload.State.ID, addr: wallet.Key(addr)}
+		req.Params = s.params[payload.State.ID]
+		rec, err := s.signed.get(key)
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			req.First, req.Prev = rec.first, rec.last
+			if v, prev := payload.State.Version, rec.last.Version; v < prev {
+				return nil, fmt.Errorf("version %d lower than signed version %d", v, prev)
+			} else if v == prev && !bytes.Equal(data, rec.Last) {
+				return nil, fmt.Errorf("other state with signed version %d", v)
+			}
+		}
+	} else {
+		wr := payload.WithdrawReq
+		if !wr.Part.Equal(addr) {
+			return nil, errors.New("withdraw request of other participant")
+		} else if wr.Domain != s.domain {
+			return nil, errors.New("withdraw request of other domain")
+		}
+		req.Params = s.params[wr.ID]
+	}
+
+	for _, p := range s.policies {
+		if err := p.Check(req); err != nil {
+			return nil, fmt.Errorf("policy: %w", err)
+		}
+	}
+
+	acc, err := s.wallet.Unlock(addr)
+	if err != nil {
+		return nil, fmt.Errorf("unlocking account: %w", err)
+	}
+	sig, err := acc.SignData(data)
+	if err != nil {
+		return nil, err
+	}
+	if payload.State != nil {
+		if err := s.signed.put(key, data, payload.State); err != nil {
+			return nil, err
+		}
+	}
+	return sig, nil
+}
+
+// Sign is the RPC method for Signer.Sign.
+func (s *service) Sign(args SignArgs, sig *[]byte) error {
+	addr, err := decodeAddress(args.Address)
+	if err != nil {
+		return err
+	}
+	*sig, err = s.signer.Sign(addr, args.Data)
+	return err
+}
+
+// RegisterChannel is the RPC method for Signer.RegisterChannel. It takes the
+// encoded params.
+func (s *service) RegisterChannel(params []byte, id *channel.ID) error {
+	var p channel.Params
+	if err := p.Decode(bytes.NewReader(params)); err != nil {
+		return fmt.Errorf("decoding params: %w", err)
+	}
+	*id = s.signer.RegisterChannel(&p)
+	return nil
+}
+
+// Unlock is the RPC method checking whether the Signer has the account of the
+// given encoded address.
+func (s *service) Unlock(addrData []byte, _ *struct{}) error {
+	addr, err := decodeAddress(addrData)
+	if err != nil {
+		return err
+	}
+	_, err = s.signer.wallet.Unlock(addr)
+	return err
+}
+
+func decodeAddress(data []byte) (wallet.Address, error) {
+	addr := wallet.NewAddress()
+	if err := addr.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("decoding address: %w", err)
+	}
+	return addr, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"perun.network/go-perun/wallet"
+)
+
+// ecdsaSigDER is the DER structure of ECDSA signatures as used by Fabric.
+type ecdsaSigDER struct {
+	R, S *big.Int
+}
+
+// SigToDER converts the given ECDSA signature of the given signer into its DER
+// encoding, as used by Fabric tooling. As signatures do not carry their key
+// type, the signer's address is needed to reject Ed25519 signatures, which
+// have no DER encoding, and signatures that are malformed for the signer's
+// curve.
+func SigToDER(sig wallet.Sig, signer *Address) ([]byte, error) {
+	if signer.Type() != KeyTypeECDSA {
+		return nil, fmt.Errorf("no DER encoding for signatures of key type %v", signer.Type())
+	}
+	r, s, err := unmarshalECDSASig(signer.ECDSA().Curve, sig)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ecdsaSigDER{R: r, S: s})
+}
+
+// SigFromDER converts the given DER encoded ECDSA signature on the given curve
+// into a wallet.Sig. A high-S signature is normalized to its low-S
+// counterpart, which is valid for the same message and key.
+func SigFromDER(der []byte, curve elliptic.Curve) (wallet.Sig, error) {
+	var sig ecdsaSigDER
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("unmarshaling DER signature: %w", err)
+	} else if l := len(rest); l > 0 {
+		return nil, fmt.Errorf("unexpected rest of length %d after DER signature", l)
+	}
+	if err := checkSigRange(curve, sig.R, sig.S); err != nil {
+		return nil, err
+	}
+	return marshalSig(curve, sig.R, toLowS(curve, sig.S)), nil
+}
+
+// unmarshalECDSASig unmarshals the given signature and checks that it is a
+// low-S signature on the given curve.
+func unmarshalECDSASig(curve elliptic.Curve, sig []byte) (*big.Int, *big.Int, error) {
+	r, s, err := unmarshalSig(sig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ps := pointByteSize(curve); sig[0] != ps {
+		return nil, nil, fmt.Errorf("signature size %d does not match curve %s", sig[0], curve.Params().Name)
+	}
+	if err := checkSigRange(curve, r, s); err != nil {
+		return nil, nil, err
+	}
+	if !isLowS(curve, s) {
+		return nil, nil, errors.New("signature has high S value")
+	}
+	return r, s, nil
+}
+
+// checkSigRange checks that r and s are in [1, N-1], where N is the order of
+// the curve.
+func checkSigRange(curve elliptic.Curve, r, s *big.Int) error {
+	n := curve.Params().N
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 {
+		return errors.New("signature R value out of range")
+	} else if s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return errors.New("signature S value out of range")
+	}
+	return nil
+}
+
+// isLowS returns whether s is at most half the order of the curve.
+func isLowS(curve elliptic.Curve, s *big.Int) bool {
+	return s.Cmp(halfOrder(curve)) <= 0
+}
+
+// toLowS returns s if it is low or its low counterpart N - s otherwise.
+func toLowS(curve elliptic.Curve, s *big.Int) *big.Int {
+	if isLowS(curve, s) {
+		return s
+	}
+	return new(big.Int).Sub(curve.Params().N, s)
+}
+
+func halfOrder(curve elliptic.Curve) *big.Int {
+	return new(big.Int).Rsh(curve.Params().N, 1)
+}
@@ -0,0 +1,139 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pwallet "perun.network/go-perun/wallet"
+	"polycry.pt/poly-go/test"
+
+	"github.com/perun-network/perun-fabric/wallet"
+)
+
+// rawSig encodes r and s in the wallet signature format without any checks.
+func rawSig(curve elliptic.Curve, r, s *big.Int) pwallet.Sig {
+	ps := (curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*ps+1)
+	sig[0] = byte(ps)
+	r.FillBytes(sig[1 : ps+1])
+	s.FillBytes(sig[ps+1:])
+	return sig
+}
+
+func sigRS(sig pwallet.Sig) (*big.Int, *big.Int) {
+	ps := int(sig[0])
+	return new(big.Int).SetBytes(sig[1 : ps+1]), new(big.Int).SetBytes(sig[ps+1:])
+}
+
+func TestSignLowS(t *testing.T) {
+	rng := test.Prng(t)
+	acc := wallet.NewRandomAccount(rng)
+	half := new(big.Int).Rsh(acc.ECDSA().Curve.Params().N, 1)
+	for i := 0; i < 0x40; i++ {
+		sig, err := acc.SignData([]byte{byte(i)})
+		require.NoError(t, err)
+		_, s := sigRS(sig)
+		require.LessOrEqual(t, s.Cmp(half), 0)
+	}
+}
+
+func TestVerifySignatureStrict(t *testing.T) {
+	rng := test.Prng(t)
+	acc := wallet.NewRandomAccount(rng)
+	addr := acc.Address()
+	curve := acc.ECDSA().Curve
+	n := curve.Params().N
+	msg := []byte("strict")
+	b := wallet.Backend{}
+
+	sig, err := acc.SignData(msg)
+	require.NoError(t, err)
+	valid, err := b.VerifySignature(msg, sig, addr)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	r, s := sigRS(sig)
+	invalid := map[string]pwallet.Sig{
+		"high-S":   rawSig(curve, r, new(big.Int).Sub(n, s)),
+		"zero-R":   rawSig(curve, new(big.Int), s),
+		"zero-S":   rawSig(curve, r, new(big.Int)),
+		"R=N":      rawSig(curve, n, s),
+		"S=N":      rawSig(curve, r, n),
+		"P-384":    rawSig(elliptic.P384(), r, s),
+		"too-long": append(append(pwallet.Sig{}, sig...), 0),
+		"empty":    {},
+	}
+	for name, sig := range invalid {
+		t.Run(name, func(t *testing.T) {
+			valid, err := b.VerifySignature(msg, sig, addr)
+			require.Error(t, err)
+			require.False(t, valid)
+		})
+	}
+}
+
+func TestSigDER(t *testing.T) {
+	rng := test.Prng(t)
+	acc := wallet.NewRandomAccount(rng)
+	curve := acc.ECDSA().Curve
+	msg := []byte("der")
+	b := wallet.Backend{}
+
+	sig, err := acc.SignData(msg)
+	require.NoError(t, err)
+	der, err := wallet.SigToDER(sig, acc.FabricAddress())
+	require.NoError(t, err)
+	sig0, err := wallet.SigFromDER(der, curve)
+	require.NoError(t, err)
+	require.Equal(t, sig, sig0)
+
+	// DER signatures of the standard library verify after import, also
+	// high-S ones.
+	digest := sha256.Sum256(msg)
+	for i := 0; i < 0x10; i++ {
+		der, err := ecdsa.SignASN1(rng, acc.ECDSA(), digest[:])
+		require.NoError(t, err)
+		sig, err := wallet.SigFromDER(der, curve)
+		require.NoError(t, err)
+		valid, err := b.VerifySignature(msg, sig, acc.Address())
+		require.NoError(t, err)
+		require.True(t, valid)
+	}
+
+	_, err = wallet.SigFromDER(append(der, 0), curve)
+	require.Error(t, err, "trailing data")
+	_, err = wallet.SigFromDER([]byte{0x30, 0}, curve)
+	require.Error(t, err, "missing values")
+
+	// Ed25519 signatures have the length prefix of P-256 signatures, but no
+	// DER encoding.
+	edAcc := wallet.NewRandomEd25519Account(rng)
+	edSig, err := edAcc.SignData(msg)
+	require.NoError(t, err)
+	_, err = wallet.SigToDER(edSig, edAcc.FabricAddress())
+	require.Error(t, err, "Ed25519 signer")
+
+	// Signatures must match the signer's curve.
+	sk, err := ecdsa.GenerateKey(elliptic.P384(), rng)
+	require.NoError(t, err)
+	_, err = wallet.SigToDER(sig, wallet.NewECDSAAddress(&sk.PublicKey))
+	require.Error(t, err, "curve mismatch")
+}
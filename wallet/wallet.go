@@ -54,6 +54,31 @@ func (w Wallet) NewRandomAccount(rng *rand.Rand) wallet.Account {
 	return acc
 }
 
+// NewDerivedAccount derives the Account with the given index from the Deriver
+// and adds it to the Wallet.
+func (w Wallet) NewDerivedAccount(d *Deriver, index uint32) (*Account, error) {
+	acc, err := d.DeriveAccount(index)
+	if err != nil {
+		return nil, err
+	}
+	w.Add(acc)
+	return acc, nil
+}
+
+// RestoreAccounts derives the Accounts with the indices 0 to n-1 from the
+// Deriver and adds them to the Wallet.
+func (w Wallet) RestoreAccounts(d *Deriver, n uint32) ([]*Account, error) {
+	accs := make([]*Account, 0, n)
+	for i := uint32(0); i < n; i++ {
+		acc, err := w.NewDerivedAccount(d, i)
+		if err != nil {
+			return nil, fmt.Errorf("deriving account %d: %w", i, err)
+		}
+		accs = append(accs, acc)
+	}
+	return accs, nil
+}
+
 // LockAll - noop.
 func (w Wallet) LockAll() {}
 
@@ -0,0 +1,93 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"perun.network/go-perun/channel"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+// Client hands signed channel states to a Watchtower over its HTTP API.
+type Client struct {
+	url  string       // url is the base url of the Watchtower.
+	http *http.Client // http is used for sending the requests.
+}
+
+// NewClient returns a Client for the Watchtower served at the given base url.
+func NewClient(url string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		url:  strings.TrimSuffix(url, "/"),
+		http: httpClient,
+	}
+}
+
+// Update hands the given signed channel state to the Watchtower.
+// It should be called after every update of the channel.
+func (c *Client) Update(ctx context.Context, ch *adj.SignedChannel) error {
+	data, err := json.Marshal(ch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.url+ChannelsPath, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	_, err = c.do(req, http.StatusNoContent)
+	return err
+}
+
+// Channel returns the latest signed state the Watchtower stores for the given channel.
+func (c *Client) Channel(ctx context.Context, id channel.ID) (*adj.SignedChannel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s%x", c.url, ChannelsPath, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var ch adj.SignedChannel
+	return &ch, json.Unmarshal(data, &ch)
+}
+
+func (c *Client) do(req *http.Request, expected int) ([]byte, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRequestSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != expected {
+		return nil, fmt.Errorf("watchtower responded %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"perun.network/go-perun/channel"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+// ChannelsPath is the path of the Watchtower's HTTP API.
+//
+// A PUT request on ChannelsPath with a JSON-encoded adj.SignedChannel as body
+// hands the state to the Watchtower. A GET request on ChannelsPath followed by
+// the hex-encoded channel id returns the stored state of the channel.
+const ChannelsPath = "/channels/"
+
+// maxRequestSize limits the size of request bodies.
+const maxRequestSize = 1 << 20
+
+// Handler returns the http.Handler serving the Watchtower's HTTP API.
+func (w *Watchtower) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ChannelsPath, w.serveChannels)
+	return mux
+}
+
+func (w *Watchtower) serveChannels(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPut:
+		w.servePut(rw, r)
+	case http.MethodGet:
+		w.serveGet(rw, r)
+	default:
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (w *Watchtower) servePut(rw http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var ch adj.SignedChannel
+	if err := json.Unmarshal(data, &ch); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = w.Update(&ch)
+	switch {
+	case err == nil:
+		rw.WriteHeader(http.StatusNoContent)
+	case errors.As(err, new(adj.VersionError)):
+		http.Error(rw, err.Error(), http.StatusConflict)
+	case adj.IsAdjudicatorError(err):
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (w *Watchtower) serveGet(rw http.ResponseWriter, r *http.Request) {
+	id, err := parseChannelID(strings.TrimPrefix(r.URL.Path, ChannelsPath))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ch, err := w.Channel(id)
+	if adj.IsNotFoundError(err) {
+		http.Error(rw, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(ch)
+}
+
+func parseChannelID(s string) (channel.ID, error) {
+	var id channel.ID
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return id, err
+	} else if len(b) != len(id) {
+		return id, errors.New("invalid channel id length")
+	}
+	copy(id[:], b)
+	return id, nil
+}
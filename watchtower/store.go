@@ -0,0 +1,195 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"perun.network/go-perun/channel"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+)
+
+type (
+	// Store persists the latest signed channel states handed to the Watchtower.
+	Store interface {
+		// Put stores the given channel, replacing any channel with the same id.
+		Put(ch *adj.SignedChannel) error
+		// Get returns the channel with the given id. If there is no such
+		// channel, an adj.NotFoundError is returned.
+		Get(id channel.ID) (*adj.SignedChannel, error) //nolint:forbidigo
+		// Delete removes the channel with the given id.
+		Delete(id channel.ID) error
+		// All returns all stored channels.
+		All() ([]*adj.SignedChannel, error)
+	}
+
+	// MemStore is an in-memory Store for testing.
+	MemStore struct {
+		mtx      sync.Mutex
+		channels map[channel.ID]*adj.SignedChannel
+	}
+
+	// FileStore is a Store that keeps every channel as a JSON file in a
+	// directory.
+	FileStore struct {
+		mtx sync.Mutex
+		dir string
+	}
+)
+
+const (
+	fileStoreExt  = ".json"
+	fileStorePerm = 0o600
+	fileStoreDir  = 0o700
+)
+
+// NewMemStore returns a new, empty in-memory Store.
+func NewMemStore() *MemStore {
+	return &MemStore{channels: make(map[channel.ID]*adj.SignedChannel)}
+}
+
+// Put stores a copy of the given channel.
+func (m *MemStore) Put(ch *adj.SignedChannel) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.channels[ch.State.ID] = ch.Clone()
+	return nil
+}
+
+// Get returns a copy of the channel with the given id.
+func (m *MemStore) Get(id channel.ID) (*adj.SignedChannel, error) { //nolint:forbidigo
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	ch, ok := m.channels[id]
+	if !ok {
+		return nil, &adj.NotFoundError{Key: adj.IDKey(id), Type: "SignedChannel"}
+	}
+	return ch.Clone(), nil
+}
+
+// Delete removes the channel with the given id.
+func (m *MemStore) Delete(id channel.ID) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	delete(m.channels, id)
+	return nil
+}
+
+// All returns copies of all stored channels.
+func (m *MemStore) All() ([]*adj.SignedChannel, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	chs := make([]*adj.SignedChannel, 0, len(m.channels))
+	for _, ch := range m.channels {
+		chs = append(chs, ch.Clone())
+	}
+	return chs, nil
+}
+
+// NewFileStore returns a Store that keeps its channels in the given directory.
+// The directory is created if it does not exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, fileStoreDir); err != nil {
+		return nil, fmt.Errorf("creating store directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(id channel.ID) string {
+	return filepath.Join(f.dir, adj.IDKey(id)+fileStoreExt)
+}
+
+// Put writes the given channel to its file. The file is replaced atomically,
+// so that a crash never leaves a partially written channel behind.
+func (f *FileStore) Put(ch *adj.SignedChannel) error {
+	data, err := json.Marshal(ch)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	path := f.path(ch.State.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, fileStorePerm); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("renaming file: %w", err)
+	}
+	return nil
+}
+
+// Get reads the channel with the given id from its file.
+func (f *FileStore) Get(id channel.ID) (*adj.SignedChannel, error) { //nolint:forbidigo
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	ch, err := readChannel(f.path(id))
+	if os.IsNotExist(err) {
+		return nil, &adj.NotFoundError{Key: adj.IDKey(id), Type: "SignedChannel"}
+	}
+	return ch, err
+}
+
+// Delete removes the file of the channel with the given id.
+func (f *FileStore) Delete(id channel.ID) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if err := os.Remove(f.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing file: %w", err)
+	}
+	return nil
+}
+
+// All reads all channels from the store directory.
+func (f *FileStore) All() ([]*adj.SignedChannel, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading store directory: %w", err)
+	}
+
+	var chs []*adj.SignedChannel //nolint:prealloc
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), fileStoreExt) {
+			continue
+		}
+		ch, err := readChannel(filepath.Join(f.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		chs = append(chs, ch)
+	}
+	return chs, nil
+}
+
+func readChannel(path string) (*adj.SignedChannel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ch adj.SignedChannel
+	if err := json.Unmarshal(data, &ch); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s: %w", path, err)
+	}
+	return &ch, nil
+}
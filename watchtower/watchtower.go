@@ -0,0 +1,221 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package watchtower implements a standalone service that watches channels on
+// behalf of offline clients and refutes registrations of outdated states.
+package watchtower
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"perun.network/go-perun/channel"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	"github.com/perun-network/perun-fabric/channel/binding"
+)
+
+const defaultPollingInterval = 1 * time.Second
+
+type (
+	// Adjudicator is the on-chain adjudicator the Watchtower watches and
+	// refutes on. It is implemented by the chaincode binding as well as by the
+	// in-memory adjudicator.
+	Adjudicator interface {
+		Register(ch *adj.SignedChannel) error
+		StateReg(id channel.ID) (*adj.StateReg, error)
+	}
+
+	// Watchtower keeps the latest signed states of channels and registers them
+	// whenever an older state is registered on the Adjudicator.
+	Watchtower struct {
+		adjudicator Adjudicator          // adjudicator gives access to the registered states.
+		domain      adj.Domain           // domain is the domain of the adjudicator.
+		store       Store                // store persists the latest signed states.
+		polling     time.Duration        // polling is the interval in which registered states are checked.
+		now         func() adj.Timestamp // now returns the current time, compared with registration timeouts.
+		onError     func(error)          // onError handles the errors of the checks of Run.
+		mtx         sync.Mutex           // mtx serializes the accesses to the store.
+	}
+
+	// Opt extends the Watchtower constructor.
+	Opt func(*Watchtower)
+)
+
+// WithPollingInterval overwrites the interval in which the Watchtower checks
+// the registered states.
+func WithPollingInterval(d time.Duration) Opt {
+	return func(w *Watchtower) {
+		w.polling = d
+	}
+}
+
+// WithNow overwrites the time source the Watchtower compares registration
+// timeouts with. It defaults to adj.StdNow.
+func WithNow(now func() adj.Timestamp) Opt {
+	return func(w *Watchtower) {
+		w.now = now
+	}
+}
+
+// WithErrorHandler sets the handler of the errors that occur while Run checks
+// the channels, e.g., to log them. By default, these errors are discarded.
+func WithErrorHandler(h func(error)) Opt {
+	return func(w *Watchtower) {
+		w.onError = h
+	}
+}
+
+// New returns a Watchtower that watches the channels in store on the given
+// Adjudicator of the given domain.
+func New(a Adjudicator, domain adj.Domain, store Store, opts ...Opt) *Watchtower {
+	w := &Watchtower{
+		adjudicator: a,
+		domain:      domain,
+		store:       store,
+		polling:     defaultPollingInterval,
+		now:         adj.StdNow,
+		onError:     func(error) {},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Update hands the given signed channel state to the Watchtower. The channel is
+// validated and stored if its version is not lower than that of the currently
+// stored state of the channel. Otherwise, an adj.VersionError is returned.
+func (w *Watchtower) Update(ch *adj.SignedChannel) error {
+	if err := adj.ValidateChannel(w.domain, ch); err != nil {
+		return err
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	stored, err := w.store.Get(ch.State.ID)
+	if err == nil && stored.State.Version > ch.State.Version {
+		return adj.VersionError{
+			Registered: stored.State.Version,
+			Tried:      ch.State.Version,
+		}
+	} else if err != nil && !adj.IsNotFoundError(err) {
+		return fmt.Errorf("querying store: %w", err)
+	}
+	return w.store.Put(ch)
+}
+
+// Channel returns the latest signed state stored for the given channel.
+func (w *Watchtower) Channel(id channel.ID) (*adj.SignedChannel, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.store.Get(id)
+}
+
+// Run checks all channels in the polling interval until the context is
+// canceled. Errors of the checks are passed to the error handler, see
+// WithErrorHandler, and do not stop the Watchtower.
+func (w *Watchtower) Run(ctx context.Context) error {
+	for {
+		if err := w.Check(); err != nil {
+			w.onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.polling):
+		}
+	}
+}
+
+// Check checks all stored channels once. If an older state than the stored one
+// is registered, the stored state is registered to refute it. Channels that
+// are finalized on the Adjudicator with the stored or a newer state are removed
+// from the store.
+// All channels are checked, even if some fail. The first error is returned.
+//
+// The store is only locked while the channels are read and removed, so that
+// updates are not blocked by the queries and registrations on the
+// Adjudicator.
+func (w *Watchtower) Check() error {
+	w.mtx.Lock()
+	chs, err := w.store.All()
+	w.mtx.Unlock()
+	if err != nil {
+		return fmt.Errorf("querying store: %w", err)
+	}
+
+	var firstErr error
+	for _, ch := range chs {
+		if err := w.check(ch); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("checking channel %x: %w", ch.State.ID, err)
+		}
+	}
+	return firstErr
+}
+
+func (w *Watchtower) check(ch *adj.SignedChannel) error {
+	id := ch.State.ID
+	reg, err := w.adjudicator.StateReg(id)
+	if isUnknownChannel(err) {
+		return nil // Nothing registered yet, nothing to refute.
+	} else if err != nil {
+		return fmt.Errorf("querying state reg: %w", err)
+	}
+
+	if reg.Version >= ch.State.Version {
+		if reg.IsFinalizedAt(w.now()) {
+			return w.forget(ch)
+		}
+		return nil
+	}
+
+	// An outdated state is registered: refute it.
+	if err := w.adjudicator.Register(ch); err != nil {
+		if reg.IsFinalizedAt(w.now()) {
+			// Too late to refute, stop watching the channel.
+			if derr := w.forget(ch); derr != nil {
+				return fmt.Errorf("deleting channel: %w", derr)
+			}
+		}
+		return fmt.Errorf("refuting version %d with version %d: %w", reg.Version, ch.State.Version, err)
+	}
+	return nil
+}
+
+// forget removes the given checked channel from the store, unless a newer
+// state of the channel was stored in the meantime.
+func (w *Watchtower) forget(ch *adj.SignedChannel) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	stored, err := w.store.Get(ch.State.ID)
+	if adj.IsNotFoundError(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("querying store: %w", err)
+	} else if stored.State.Version > ch.State.Version {
+		return nil
+	}
+	return w.store.Delete(ch.State.ID)
+}
+
+// isUnknownChannel checks if err indicates that no state is registered for a
+// channel, either returned by the in-memory adjudicator or the chaincode.
+func isUnknownChannel(err error) bool {
+	return err != nil && (errors.Is(err, adj.ErrUnknownChannel) || binding.IsChannelUnknownErr(err))
+}
@@ -0,0 +1,219 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchtower_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	ptest "polycry.pt/poly-go/test"
+
+	adj "github.com/perun-network/perun-fabric/adjudicator"
+	adjtest "github.com/perun-network/perun-fabric/adjudicator/test"
+	"github.com/perun-network/perun-fabric/watchtower"
+)
+
+func TestWatchtower(t *testing.T) {
+	t.Run("Refute", func(t *testing.T) {
+		require := require.New(t)
+		rng := ptest.Prng(t)
+		s := adjtest.NewSetup(rng, adjtest.Funded)
+		w := watchtower.New(s.Adj, s.Domain, watchtower.NewMemStore(), watchtower.WithNow(s.Ledger.Now))
+
+		// Hand version 1 to the watchtower, then register version 0.
+		old := s.SignedChannel()
+		s.State.Version = 1
+		require.NoError(w.Update(s.SignedChannel()))
+		require.NoError(s.Adj.Register(old))
+
+		require.NoError(w.Check())
+		reg, err := s.Adj.StateReg(s.State.ID)
+		require.NoError(err)
+		require.Equal(uint64(1), reg.Version)
+
+		// The channel is forgotten once the registration is final.
+		s.Ledger.AdvanceNow(s.Params.ChallengeDuration + 1)
+		require.NoError(w.Check())
+		_, err = w.Channel(s.State.ID)
+		require.True(adj.IsNotFoundError(err))
+	})
+
+	t.Run("Unregistered", func(t *testing.T) {
+		rng := ptest.Prng(t)
+		s := adjtest.NewSetup(rng, adjtest.Funded)
+		w := watchtower.New(s.Adj, s.Domain, watchtower.NewMemStore())
+
+		require.NoError(t, w.Update(s.SignedChannel()))
+		require.NoError(t, w.Check())
+		_, err := s.Adj.StateReg(s.State.ID)
+		require.True(t, errors.Is(err, adj.ErrUnknownChannel))
+	})
+
+	t.Run("Update-invalid", func(t *testing.T) {
+		require := require.New(t)
+		rng := ptest.Prng(t)
+		s := adjtest.NewSetup(rng)
+		w := watchtower.New(s.Adj, s.Domain, watchtower.NewMemStore())
+
+		s.State.Version = 1
+		require.NoError(w.Update(s.SignedChannel()))
+
+		// Older versions are rejected.
+		s.State.Version = 0
+		err := w.Update(s.SignedChannel())
+		require.True(errors.As(err, new(adj.VersionError)))
+
+		// Invalid signatures are rejected.
+		s.State.Version = 2
+		ch := s.SignedChannel()
+		ch.Sigs[0], ch.Sigs[1] = ch.Sigs[1], ch.Sigs[0]
+		err = w.Update(ch)
+		require.True(errors.As(err, new(adj.ValidationError)))
+
+		// Other domains are rejected.
+		w = watchtower.New(s.Adj, adj.NewDomain("other", "adjudicator"), watchtower.NewMemStore())
+		err = w.Update(s.SignedChannel())
+		require.True(errors.As(err, new(adj.ValidationError)))
+	})
+
+	t.Run("Update-during-Check", func(t *testing.T) {
+		require := require.New(t)
+		rng := ptest.Prng(t)
+		s := adjtest.NewSetup(rng, adjtest.Funded)
+		a := &blockingAdjudicator{Adjudicator: s.Adj, registering: make(chan struct{}), release: make(chan struct{})}
+		w := watchtower.New(a, s.Domain, watchtower.NewMemStore(), watchtower.WithNow(s.Ledger.Now))
+
+		old := s.SignedChannel()
+		s.State.Version = 1
+		require.NoError(w.Update(s.SignedChannel()))
+		require.NoError(s.Adj.Register(old))
+
+		checked := make(chan error, 1)
+		go func() { checked <- w.Check() }()
+		<-a.registering
+
+		// Updates are not blocked by the refutation.
+		s.State.Version = 2
+		require.NoError(w.Update(s.SignedChannel()))
+		close(a.release)
+		require.NoError(<-checked)
+		ch, err := w.Channel(s.State.ID)
+		require.NoError(err)
+		require.Equal(uint64(2), ch.State.Version)
+	})
+
+	t.Run("Run-errors", func(t *testing.T) {
+		rng := ptest.Prng(t)
+		s := adjtest.NewSetup(rng, adjtest.Funded)
+		errs := make(chan error, 1)
+		a := &failingAdjudicator{Adjudicator: s.Adj}
+		w := watchtower.New(a, s.Domain, watchtower.NewMemStore(), watchtower.WithPollingInterval(time.Millisecond),
+			watchtower.WithErrorHandler(func(err error) {
+				select {
+				case errs <- err:
+				default:
+				}
+			}))
+		require.NoError(t, w.Update(s.SignedChannel()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() { _ = w.Run(ctx) }()
+		select {
+		case err := <-errs:
+			require.ErrorIs(t, err, errQuery)
+		case <-time.After(time.Second):
+			t.Fatal("no error handled")
+		}
+	})
+
+	t.Run("FileStore", func(t *testing.T) {
+		require := require.New(t)
+		rng := ptest.Prng(t)
+		s := adjtest.NewSetup(rng, adjtest.Funded)
+		dir := t.TempDir()
+
+		store, err := watchtower.NewFileStore(dir)
+		require.NoError(err)
+		old := s.SignedChannel()
+		s.State.Version = 1
+		require.NoError(watchtower.New(s.Adj, s.Domain, store).Update(s.SignedChannel()))
+
+		// A restarted watchtower refutes with the persisted state.
+		store, err = watchtower.NewFileStore(dir)
+		require.NoError(err)
+		w := watchtower.New(s.Adj, s.Domain, store)
+		require.NoError(s.Adj.Register(old))
+		require.NoError(w.Check())
+		reg, err := s.Adj.StateReg(s.State.ID)
+		require.NoError(err)
+		require.Equal(uint64(1), reg.Version)
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		require := require.New(t)
+		rng := ptest.Prng(t)
+		s := adjtest.NewSetup(rng)
+		w := watchtower.New(s.Adj, s.Domain, watchtower.NewMemStore())
+		srv := httptest.NewServer(w.Handler())
+		defer srv.Close()
+		ctx := context.Background()
+		c := watchtower.NewClient(srv.URL, srv.Client())
+
+		_, err := c.Channel(ctx, s.State.ID)
+		require.Error(err)
+
+		s.State.Version = 1
+		ch := s.SignedChannel()
+		require.NoError(c.Update(ctx, ch))
+		got, err := c.Channel(ctx, s.State.ID)
+		require.NoError(err)
+		require.Equal(ch.State.Version, got.State.Version)
+		require.Equal(ch.State.ID, got.State.ID)
+
+		s.State.Version = 0
+		require.Error(c.Update(ctx, s.SignedChannel()))
+	})
+}
+
+// blockingAdjudicator blocks registrations until release is closed. It
+// signals on registering when a registration starts.
+type blockingAdjudicator struct {
+	watchtower.Adjudicator
+	registering chan struct{}
+	release     chan struct{}
+}
+
+func (a *blockingAdjudicator) Register(ch *adj.SignedChannel) error {
+	a.registering <- struct{}{}
+	<-a.release
+	return a.Adjudicator.Register(ch)
+}
+
+var errQuery = errors.New("query failed")
+
+// failingAdjudicator fails all state queries.
+type failingAdjudicator struct {
+	watchtower.Adjudicator
+}
+
+func (failingAdjudicator) StateReg(channel.ID) (*adj.StateReg, error) {
+	return nil, errQuery
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"perun.network/go-perun/wire"
+
+	"github.com/perun-network/perun-fabric/wallet"
+)
+
+// Account is a Perun wire account backed by the private key of a Fabric
+// identity. Besides its address, it holds the X.509 certificate of the
+// identity so that counterparties can authenticate it against their trusted
+// MSP certificates.
+type Account struct {
+	acc  *wallet.Account
+	cert *x509.Certificate
+}
+
+var _ wire.Account = (*Account)(nil)
+
+// ErrCertificateMismatch is returned if a certificate does not belong to a
+// wire address or private key.
+var ErrCertificateMismatch = errors.New("certificate does not match address")
+
+// NewAccount returns the wire Account of the given wallet Account and its
+// certificate. The certificate must contain the public key of the Account.
+func NewAccount(acc *wallet.Account, cert *x509.Certificate) (*Account, error) {
+	addr, err := wallet.AddressFromX509Certificate(cert)
+	if err != nil {
+		return nil, err
+	} else if !addr.Equal(acc.FabricAddress()) {
+		return nil, ErrCertificateMismatch
+	}
+	return &Account{acc: acc, cert: cert}, nil
+}
+
+// Address returns the wire Address of the Account.
+func (a *Account) Address() wire.Address {
+	return NewAddress(a.acc.FabricAddress())
+}
+
+// WalletAccount returns the wallet Account backing the wire Account.
+func (a *Account) WalletAccount() *wallet.Account {
+	return a.acc
+}
+
+// Certificate returns the X.509 certificate of the Account.
+func (a *Account) Certificate() *x509.Certificate {
+	return a.cert
+}
+
+// SignData signs the given data with the Account's private key.
+func (a *Account) SignData(data []byte) ([]byte, error) {
+	return a.acc.SignData(data)
+}
+
+// VerifySignature verifies that sig is a signature on data by the key of the
+// given wire Address.
+func VerifySignature(data, sig []byte, addr wire.Address) (bool, error) {
+	a, ok := addr.(*Address)
+	if !ok || a.addr == nil {
+		return false, fmt.Errorf("invalid address: %v", addr)
+	}
+	return wallet.Backend{}.VerifySignature(data, sig, a.addr)
+}
+
+// VerifyCertificate checks that the given certificate is issued by one of the
+// trusted roots and contains the public key of the given wire Address. The
+// roots are typically the CA certificates of the MSPs that are allowed to
+// communicate with us.
+func VerifyCertificate(cert *x509.Certificate, roots *x509.CertPool, addr wire.Address) error {
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("verifying certificate chain: %w", err)
+	}
+	certAddr, err := AddressFromX509Certificate(cert)
+	if err != nil {
+		return err
+	} else if !certAddr.Equal(addr) {
+		return ErrCertificateMismatch
+	}
+	return nil
+}
+
+// NewRandomAccount creates a new Account with a random ECDSA key and a
+// self-signed certificate using the randomness provided by rng.
+func NewRandomAccount(rng io.Reader) *Account {
+	acc := wallet.NewRandomAccount(rng)
+	cert, err := SelfSignedCertificate(rng, acc, "perun")
+	if err != nil {
+		panic(err)
+	}
+	return &Account{acc: acc, cert: cert}
+}
+
+// SelfSignedCertificate creates a self-signed CA certificate for the given
+// Account with the given common name.
+func SelfSignedCertificate(rng io.Reader, acc *wallet.Account, commonName string) (*x509.Certificate, error) {
+	serial := make([]byte, 16) //nolint:gomnd
+	if _, err := io.ReadFull(rng, serial); err != nil {
+		return nil, fmt.Errorf("reading serial number: %w", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          new(big.Int).SetBytes(serial),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(certValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rng, tmpl, tmpl, acc.FabricAddress().PublicKey(), acc.PrivateKey())
+	if err != nil {
+		return nil, fmt.Errorf("creating certificate: %w", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+const certValidity = 10 * 365 * 24 * time.Hour
@@ -0,0 +1,132 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wire provides a go-perun wire backend based on Fabric identities.
+// Wire addresses and accounts use the same keys as the Fabric wallet, so peers
+// on the Perun network can be authenticated by their Fabric MSP certificates.
+package wire
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io"
+	"strings"
+
+	"perun.network/go-perun/wire"
+
+	"github.com/perun-network/perun-fabric/wallet"
+)
+
+// Address is a Perun wire address backed by the public key of a Fabric
+// identity. It uses the same keys as the Fabric wallet Address, so the address
+// of a peer can be derived from its X.509 certificate. The zero Address holds
+// no key and is encoded as an empty byte slice.
+type Address struct {
+	addr *wallet.Address
+}
+
+var _ wire.Address = (*Address)(nil)
+
+// NewAddress returns the wire Address of the given wallet Address.
+func NewAddress(addr *wallet.Address) *Address {
+	return &Address{addr: addr.Clone()}
+}
+
+// AddressFromX509Certificate returns the wire Address of the public key
+// contained in the given certificate.
+func AddressFromX509Certificate(cert *x509.Certificate) (*Address, error) {
+	addr, err := wallet.AddressFromX509Certificate(cert)
+	if err != nil {
+		return nil, err
+	}
+	return &Address{addr: addr}, nil
+}
+
+// WalletAddress returns the wallet Address of the wire Address or nil for
+// the zero Address.
+func (a *Address) WalletAddress() *wallet.Address {
+	return a.addr
+}
+
+// MarshalBinary marshals the Address in the binary encoding of the wallet
+// Address.
+func (a *Address) MarshalBinary() ([]byte, error) {
+	if a.addr == nil {
+		return []byte{}, nil
+	}
+	return a.addr.MarshalBinary()
+}
+
+// UnmarshalBinary unmarshals an Address encoded by MarshalBinary.
+func (a *Address) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		a.addr = nil
+		return nil
+	}
+	addr := new(wallet.Address)
+	if err := addr.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("unmarshaling wallet address: %w", err)
+	}
+	a.addr = addr
+	return nil
+}
+
+// Equal returns whether the two addresses are equal.
+func (a *Address) Equal(b wire.Address) bool {
+	other, ok := b.(*Address)
+	if !ok {
+		return false
+	}
+	if a.addr == nil || other.addr == nil {
+		return a.addr == other.addr
+	}
+	return a.addr.Equal(other.addr)
+}
+
+// Cmp compares the two addresses. The zero Address is smaller than all other
+// addresses, which are ordered like their wallet addresses. Addresses on
+// different ECDSA curves are ordered by their string representation. Panics if the
+// passed address is of the wrong type.
+func (a *Address) Cmp(b wire.Address) int {
+	other, ok := b.(*Address)
+	if !ok {
+		panic(fmt.Sprintf("wrong address type: %T", b))
+	}
+	switch {
+	case a.addr == nil && other.addr == nil:
+		return 0
+	case a.addr == nil:
+		return -1
+	case other.addr == nil:
+		return 1
+	}
+	if a.addr.Type() == wallet.KeyTypeECDSA && other.addr.Type() == wallet.KeyTypeECDSA &&
+		a.addr.ECDSA().Curve != other.addr.ECDSA().Curve {
+		return strings.Compare(a.addr.String(), other.addr.String())
+	}
+	return a.addr.Cmp(other.addr)
+}
+
+// String returns the string representation of the wallet Address.
+func (a *Address) String() string {
+	if a.addr == nil {
+		return "<zero>"
+	}
+	return a.addr.String()
+}
+
+// NewRandomAddress creates a new Address using the randomness provided by rng.
+func NewRandomAddress(rng io.Reader) *Address {
+	return &Address{addr: wallet.NewRandomAddress(rng)}
+}
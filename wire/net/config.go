@@ -0,0 +1,88 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package net provides a mutual-TLS TCP transport for go-perun wire messages.
+// Peers are authenticated against the CA certificates of the trusted Fabric
+// organizations and the TLS identity of a peer is bound to its Perun wire
+// address, so that only members of the configured MSPs can talk to us.
+package net
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/perun-network/perun-fabric/wire"
+)
+
+// newTLSConfig returns the TLS configuration of the given Account. The peer
+// certificates are verified against the given roots only. Host names are not
+// checked because Fabric client certificates do not name hosts. Instead, the
+// peer's wire address is derived from its certificate.
+func newTLSConfig(acc *wire.Account, roots *x509.CertPool) *tls.Config {
+	cert := acc.Certificate()
+	verify := func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return verifyPeerCertificate(rawCerts, roots)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{cert.Raw},
+			PrivateKey:  acc.WalletAccount().PrivateKey(),
+			Leaf:        cert,
+		}},
+		MinVersion:            tls.VersionTLS12,
+		ClientAuth:            tls.RequireAnyClientCert,
+		InsecureSkipVerify:    true, //nolint:gosec // The chain is verified in VerifyPeerCertificate.
+		VerifyPeerCertificate: verify,
+	}
+}
+
+// verifyPeerCertificate verifies that the first certificate is issued by one
+// of the roots, using the remaining certificates as intermediates.
+func verifyPeerCertificate(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("no peer certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("parsing peer certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("verifying peer certificate: %w", err)
+	}
+	return nil
+}
+
+// peerAddress returns the wire address of the peer's certificate.
+func peerAddress(conn *tls.Conn) (*wire.Address, error) {
+	state := conn.ConnectionState()
+	if !state.HandshakeComplete || len(state.PeerCertificates) == 0 {
+		return nil, errors.New("TLS handshake not complete")
+	}
+	return wire.AddressFromX509Certificate(state.PeerCertificates[0])
+}
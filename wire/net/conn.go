@@ -0,0 +1,68 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	pwire "perun.network/go-perun/wire"
+	wirenet "perun.network/go-perun/wire/net"
+)
+
+// Conn is a wire connection over mutual TLS. It only accepts envelopes sent
+// by the wire address of the peer's TLS certificate, which binds the address
+// exchange of the go-perun handshake to the TLS identity of the peer.
+type Conn struct {
+	wirenet.Conn
+	tls *tls.Conn
+	own pwire.Address
+}
+
+var _ wirenet.Conn = (*Conn)(nil)
+
+func newConn(conn *tls.Conn, own pwire.Address, ser pwire.EnvelopeSerializer) *Conn {
+	return &Conn{
+		Conn: wirenet.NewIoConn(conn, ser),
+		tls:  conn,
+		own:  own,
+	}
+}
+
+// PeerAddress returns the wire address of the peer's TLS certificate. It
+// fails if the TLS handshake is not complete.
+func (c *Conn) PeerAddress() (pwire.Address, error) {
+	return peerAddress(c.tls)
+}
+
+// Recv receives an envelope from the peer. The connection is closed if the
+// envelope's sender is not the peer's TLS identity.
+func (c *Conn) Recv() (*pwire.Envelope, error) {
+	e, err := c.Conn.Recv()
+	if err != nil {
+		return nil, err
+	}
+	peer, err := c.PeerAddress()
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("getting peer address: %w", err)
+	}
+	if !e.Sender.Equal(peer) {
+		c.Close()
+		return nil, wirenet.NewAuthenticationError(e.Sender, e.Recipient, c.own,
+			"sender does not match TLS identity")
+	}
+	return e, nil
+}
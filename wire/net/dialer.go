@@ -0,0 +1,112 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	stdnet "net"
+	"sync"
+	"time"
+
+	pwire "perun.network/go-perun/wire"
+	wirenet "perun.network/go-perun/wire/net"
+	pkgsync "polycry.pt/poly-go/sync"
+
+	"github.com/perun-network/perun-fabric/wire"
+)
+
+// Dialer dials mutual-TLS connections to registered peers. A dialed
+// connection is only returned if the TLS identity of the peer is the wire
+// address that was dialed.
+type Dialer struct {
+	mutex  sync.RWMutex             // Protects peers.
+	peers  map[pwire.AddrKey]string // Known peer hosts.
+	dialer stdnet.Dialer
+	config *tls.Config
+	own    pwire.Address
+
+	pkgsync.Closer
+}
+
+var _ wirenet.Dialer = (*Dialer)(nil)
+
+// NewTCPDialer returns a Dialer for the given Account. Peers are
+// authenticated against the given roots, usually the CA certificates of the
+// trusted Fabric organizations. The timeout limits connection establishment.
+func NewTCPDialer(acc *wire.Account, roots *x509.CertPool, timeout time.Duration) *Dialer {
+	return &Dialer{
+		peers:  make(map[pwire.AddrKey]string),
+		dialer: stdnet.Dialer{Timeout: timeout},
+		config: newTLSConfig(acc, roots),
+		own:    acc.Address(),
+	}
+}
+
+// Register registers the host, in the form host:port, of the peer with the
+// given address.
+func (d *Dialer) Register(addr pwire.Address, host string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.peers[pwire.Key(addr)] = host
+}
+
+func (d *Dialer) host(addr pwire.Address) (string, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	host, ok := d.peers[pwire.Key(addr)]
+	return host, ok
+}
+
+// Dial dials the peer with the given address and performs the TLS handshake.
+func (d *Dialer) Dial(ctx context.Context, addr pwire.Address, ser pwire.EnvelopeSerializer) (wirenet.Conn, error) {
+	host, ok := d.host(addr)
+	if !ok {
+		return nil, errors.New("peer not found")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-d.Closed():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	raw, err := d.dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("dialing peer: %w", err)
+	}
+	conn := tls.Client(raw, d.config)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("TLS handshake: %w", err)
+	}
+
+	peer, err := peerAddress(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	} else if !peer.Equal(addr) {
+		conn.Close()
+		return nil, wirenet.NewAuthenticationError(peer, addr, d.own, "peer TLS identity does not match dialed address")
+	}
+	return newConn(conn, d.own, ser), nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	stdnet "net"
+
+	pwire "perun.network/go-perun/wire"
+	wirenet "perun.network/go-perun/wire/net"
+
+	"github.com/perun-network/perun-fabric/wire"
+)
+
+// Listener accepts mutual-TLS connections from peers. The TLS handshake is
+// performed on the first use of an accepted connection, so that a slow peer
+// does not block Accept.
+type Listener struct {
+	stdnet.Listener
+	config *tls.Config
+	own    pwire.Address
+}
+
+var _ wirenet.Listener = (*Listener)(nil)
+
+// NewTCPListener listens on the given address for connections of peers that
+// are authenticated against the given roots.
+func NewTCPListener(acc *wire.Account, roots *x509.CertPool, address string) (*Listener, error) {
+	l, err := stdnet.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s: %w", address, err)
+	}
+	return &Listener{
+		Listener: l,
+		config:   newTLSConfig(acc, roots),
+		own:      acc.Address(),
+	}, nil
+}
+
+// Accept accepts the next incoming connection.
+func (l *Listener) Accept(ser pwire.EnvelopeSerializer) (wirenet.Conn, error) {
+	raw, err := l.Listener.Accept()
+	if err != nil {
+		return nil, fmt.Errorf("accepting connection: %w", err)
+	}
+	return newConn(tls.Server(raw, l.config), l.own, ser), nil
+}
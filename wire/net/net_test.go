@@ -0,0 +1,200 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package net_test
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pwire "perun.network/go-perun/wire"
+	wirenet "perun.network/go-perun/wire/net"
+	"perun.network/go-perun/wire/perunio/serializer"
+	"polycry.pt/poly-go/test"
+
+	"github.com/perun-network/perun-fabric/wallet"
+	"github.com/perun-network/perun-fabric/wire"
+	"github.com/perun-network/perun-fabric/wire/net"
+)
+
+const (
+	timeout  = 5 * time.Second
+	loopback = "127.0.0.1:0"
+)
+
+var ser = serializer.Serializer()
+
+func TestBus(t *testing.T) {
+	rng := test.Prng(t)
+	ca := wire.NewRandomAccount(rng)
+	roots := rootsOf(ca)
+	alice, bob := issueAccount(t, rng, ca, "alice"), issueAccount(t, rng, ca, "bob")
+
+	bobListener, err := net.NewTCPListener(bob, roots, loopback)
+	require.NoError(t, err)
+	bobBus := wirenet.NewBus(bob, net.NewTCPDialer(bob, roots, timeout), ser)
+	defer bobBus.Close()
+	go bobBus.Listen(bobListener)
+	bobRecv := pwire.NewReceiver()
+	require.NoError(t, bobBus.SubscribeClient(bobRecv, bob.Address()))
+
+	aliceDialer := net.NewTCPDialer(alice, roots, timeout)
+	aliceDialer.Register(bob.Address(), bobListener.Addr().String())
+	aliceBus := wirenet.NewBus(alice, aliceDialer, ser)
+	defer aliceBus.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	require.NoError(t, aliceBus.Publish(ctx, &pwire.Envelope{
+		Sender:    alice.Address(),
+		Recipient: bob.Address(),
+		Msg:       pwire.NewPingMsg(),
+	}))
+	e, err := bobRecv.Next(ctx)
+	require.NoError(t, err)
+	require.True(t, e.Sender.Equal(alice.Address()))
+	require.IsType(t, &pwire.PingMsg{}, e.Msg)
+}
+
+func TestAuthentication(t *testing.T) {
+	rng := test.Prng(t)
+	ca := wire.NewRandomAccount(rng)
+	roots := rootsOf(ca)
+	alice, bob, carol := issueAccount(t, rng, ca, "alice"), issueAccount(t, rng, ca, "bob"), issueAccount(t, rng, ca, "carol")
+
+	listen := func(acc *wire.Account) *net.Listener {
+		l, err := net.NewTCPListener(acc, roots, loopback)
+		require.NoError(t, err)
+		t.Cleanup(func() { l.Close() })
+		return l
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	t.Run("untrusted server", func(t *testing.T) {
+		mallory := wire.NewRandomAccount(rng)
+		l, err := net.NewTCPListener(mallory, rootsOf(mallory), loopback)
+		require.NoError(t, err)
+		defer l.Close()
+		go acceptAndRecv(l) //nolint:errcheck
+
+		d := net.NewTCPDialer(alice, roots, timeout)
+		d.Register(mallory.Address(), l.Addr().String())
+		_, err = d.Dial(ctx, mallory.Address(), ser)
+		require.Error(t, err)
+	})
+
+	t.Run("untrusted client", func(t *testing.T) {
+		l := listen(bob)
+		recvErr := make(chan error, 1)
+		go func() { recvErr <- acceptAndRecv(l) }()
+
+		mallory := wire.NewRandomAccount(rng)
+		d := net.NewTCPDialer(mallory, rootsOf(mallory, ca), timeout)
+		d.Register(bob.Address(), l.Addr().String())
+		if conn, err := d.Dial(ctx, bob.Address(), ser); err == nil {
+			conn.Send(ping(mallory, bob)) //nolint:errcheck
+			defer conn.Close()
+		}
+		require.Error(t, <-recvErr)
+	})
+
+	t.Run("wrong address", func(t *testing.T) {
+		l := listen(bob)
+		go acceptAndRecv(l) //nolint:errcheck
+
+		d := net.NewTCPDialer(alice, roots, timeout)
+		d.Register(carol.Address(), l.Addr().String())
+		_, err := d.Dial(ctx, carol.Address(), ser)
+		require.True(t, wirenet.IsAuthenticationError(err))
+	})
+
+	t.Run("spoofed sender", func(t *testing.T) {
+		l := listen(bob)
+		recvErr := make(chan error, 1)
+		go func() { recvErr <- acceptAndRecv(l) }()
+
+		d := net.NewTCPDialer(alice, roots, timeout)
+		d.Register(bob.Address(), l.Addr().String())
+		conn, err := d.Dial(ctx, bob.Address(), ser)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Send(ping(carol, bob)))
+		require.True(t, wirenet.IsAuthenticationError(<-recvErr))
+	})
+
+	t.Run("authentic sender", func(t *testing.T) {
+		l := listen(bob)
+		recvErr := make(chan error, 1)
+		go func() { recvErr <- acceptAndRecv(l) }()
+
+		d := net.NewTCPDialer(alice, roots, timeout)
+		d.Register(bob.Address(), l.Addr().String())
+		conn, err := d.Dial(ctx, bob.Address(), ser)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Send(ping(alice, bob)))
+		require.NoError(t, <-recvErr)
+	})
+}
+
+// acceptAndRecv accepts a single connection and receives one envelope on it.
+func acceptAndRecv(l *net.Listener) error {
+	conn, err := l.Accept(ser)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Recv()
+	return err
+}
+
+func ping(from, to *wire.Account) *pwire.Envelope {
+	return &pwire.Envelope{Sender: from.Address(), Recipient: to.Address(), Msg: pwire.NewPingMsg()}
+}
+
+func rootsOf(cas ...*wire.Account) *x509.CertPool {
+	roots := x509.NewCertPool()
+	for _, ca := range cas {
+		roots.AddCert(ca.Certificate())
+	}
+	return roots
+}
+
+// issueAccount creates a new Account with a certificate issued by ca.
+func issueAccount(t *testing.T, rng io.Reader, ca *wire.Account, name string) *wire.Account {
+	t.Helper()
+	acc := wallet.NewRandomAccount(rng)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rng, tmpl, ca.Certificate(),
+		acc.FabricAddress().PublicKey(), ca.WalletAccount().PrivateKey())
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	wacc, err := wire.NewAccount(acc, cert)
+	require.NoError(t, err)
+	return wacc
+}
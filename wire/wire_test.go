@@ -0,0 +1,97 @@
+// Copyright 2022 - See NOTICE file for copyright holders.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire_test
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pwire "perun.network/go-perun/wire"
+	wiretest "perun.network/go-perun/wire/test"
+	"polycry.pt/poly-go/test"
+
+	"github.com/perun-network/perun-fabric/wallet"
+	"github.com/perun-network/perun-fabric/wire"
+)
+
+func TestAddress(t *testing.T) {
+	wiretest.TestAddressImplementation(t,
+		func() pwire.Address { return new(wire.Address) },
+		func(rng *rand.Rand) pwire.Address { return wire.NewRandomAddress(rng) })
+
+	rng := test.Prng(t)
+	wiretest.GenericMarshalerTest(t, wire.NewRandomAddress(rng))
+	wiretest.GenericMarshalerTest(t, wire.NewAddress(wallet.NewRandomEd25519Account(rng).FabricAddress()))
+
+	a, b := wire.NewRandomAddress(rng), wire.NewRandomAddress(rng)
+	require.Equal(t, -b.Cmp(a), a.Cmp(b))
+	require.Equal(t, a.WalletAddress().Cmp(b.WalletAddress()), a.Cmp(b))
+}
+
+func TestAccount(t *testing.T) {
+	rng := test.Prng(t)
+	acc := wire.NewRandomAccount(rng)
+	addr := acc.Address()
+	require.True(t, addr.Equal(wire.NewAddress(acc.WalletAccount().FabricAddress())))
+
+	_, err := wire.NewAccount(wallet.NewRandomAccount(rng), acc.Certificate())
+	require.ErrorIs(t, err, wire.ErrCertificateMismatch)
+	acc0, err := wire.NewAccount(acc.WalletAccount(), acc.Certificate())
+	require.NoError(t, err)
+	require.True(t, addr.Equal(acc0.Address()))
+
+	data := []byte("handshake")
+	sig, err := acc.SignData(data)
+	require.NoError(t, err)
+	ok, err := wire.VerifySignature(data, sig, addr)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = wire.VerifySignature(data, sig, wire.NewRandomAddress(rng))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	rng := test.Prng(t)
+	ca := wire.NewRandomAccount(rng)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+
+	peer := wallet.NewRandomAccount(rng)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "peer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rng, tmpl, ca.Certificate(),
+		peer.FabricAddress().PublicKey(), ca.WalletAccount().PrivateKey())
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	peerAddr := wire.NewAddress(peer.FabricAddress())
+	require.NoError(t, wire.VerifyCertificate(cert, roots, peerAddr))
+	require.ErrorIs(t, wire.VerifyCertificate(cert, roots, wire.NewRandomAddress(rng)), wire.ErrCertificateMismatch)
+
+	untrusted := wire.NewRandomAccount(rng)
+	require.Error(t, wire.VerifyCertificate(untrusted.Certificate(), roots, untrusted.Address()))
+}